@@ -0,0 +1,208 @@
+package adlbuild
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+const fooAdl = `/// Built in code
+module a.b {
+
+import common.db.DbTable;
+import common.http.*;
+
+union Either<A, B> {
+  A left;
+  B right;
+};
+
+/// A foo
+@DbTable { "indexes" : [["x"]], "withIdPrimaryKey" : true }
+struct Foo<T> {
+  Int32 x = 1;
+  /// Tags of the foo
+  Vector<String> tags = [];
+  @SerializedName "v"
+  Nullable<T> value;
+};
+
+newtype Id = String = "none";
+
+type Pair = Either<Id, Foo<Double>>;
+
+annotation Path "/foo";
+
+};
+`
+
+// decls are emitted in name order
+func TestBuilder(t *testing.T) {
+	b := NewModule("a.b").Import("common.http").ImportDecl("common.db", "DbTable").Annotate("common.http", "Path", "/foo")
+	b.Annotate(SysAnnotations, "Doc", "Built in code\n")
+	b.Struct("Foo", "T").Doc("A foo").
+		Annotate("common.db", "DbTable", map[string]interface{}{"withIdPrimaryKey": true, "indexes": [][]string{{"x"}}}).
+		Field("x", Int32).Default(1).
+		Field("tags", Vector(String)).Doc("Tags of the foo").Default([]interface{}{}).
+		Field("value", Nullable(Param("T"))).SerializedName("v")
+	b.Newtype("Id", String).Default("none")
+	b.Union("Either", "A", "B").Field("left", Param("A")).Field("right", Param("B"))
+	b.Type("Pair", Ref("", "Either", Ref("", "Id"), Ref("", "Foo", Double)))
+	src, err := b.Source()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != fooAdl {
+		t.Errorf("expected\n%s\ngot\n%s", fooAdl, src)
+	}
+	mod, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref := mod.Decls["Pair"].Type.Type.TypeExpr.Parameters[0].TypeRef.Reference; ref == nil || ref.ModuleName != "a.b" {
+		t.Errorf("expected a qualified reference, got %v", ref)
+	}
+	if f := mod.Decls["Foo"].Fields()[2]; f.SerializedName != "v" {
+		t.Errorf("expected serialized name v got %s", f.SerializedName)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	allmod := adltest.Modules()
+	want := allmod["helix.protoapp.requests"]
+	got, err := Parse(adltest.OneOfEachAdl, allmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// adlc doesn't keep the order of module annotations
+	if js(normalize(got)) != js(normalize(want)) {
+		t.Errorf("parsed module differs from the adlc ast\n%s", js(got))
+	}
+	src, err := Source(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// imports and prefix annotations are sorted by the emitter
+	again, err := Parse(src, allmod)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, src)
+	}
+	if js(normalize(again)) != js(normalize(want)) {
+		t.Errorf("emitted source doesn't round trip\n%s", src)
+	}
+	if src2, err := Source(again); err != nil || src2 != src {
+		t.Errorf("expected the same source on the second trip %v\n%s", err, src2)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  func() error
+		msg  string
+	}{
+		{"duplicate decl", func() error {
+			b := NewModule("a")
+			b.Struct("A")
+			b.Union("A")
+			_, err := b.Build()
+			return err
+		}, "duplicate decl 'A'"},
+		{"duplicate field", func() error {
+			_, err := NewModule("a").Struct("A").Field("x", Int32).Field("x", String).Build()
+			return err
+		}, "duplicate field 'x'"},
+		{"field of a type", func() error {
+			_, err := NewModule("a").Type("A", String).Field("x", Int32).Build()
+			return err
+		}, "only structs and unions have fields"},
+		{"name clash", func() error {
+			_, err := NewModule("a").Struct("A").Field("x", Ref("b", "X")).Field("y", Ref("c", "X")).Source()
+			return err
+		}, "'b.X' and 'c.X' can't both be referred to by name"},
+		{"negative", func() error {
+			_, err := NewModule("a").Struct("A").Field("x", Int32).Default(-1).Source()
+			return err
+		}, "negative number"},
+		{"unknown", func() error {
+			_, err := Parse("module a { struct A { B b; }; };", nil)
+			return err
+		}, "unknown name 'B'"},
+		{"syntax", func() error {
+			_, err := Parse("module a { struct A { Int32 a } };", nil)
+			return err
+		}, "1:"},
+	} {
+		err := tc.err()
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: expected error containing %q got %v", tc.name, tc.msg, err)
+		}
+	}
+}
+
+func TestParsePartial(t *testing.T) {
+	mod, errs := ParsePartial("module a { struct A { B b; }; struct C { Int32 c; }; annotation C D 1; };", nil)
+	msgs := []string{}
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	if want := "A: b: unknown name 'B'|unknown name 'D'"; strings.Join(msgs, "|") != want {
+		t.Errorf("got %q want %q", strings.Join(msgs, "|"), want)
+	}
+	if got := strings.Join(mod.DeclNames(), ","); got != "C" {
+		t.Errorf("got decls %s", got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	for _, tc := range []struct {
+		val  interface{}
+		want string
+	}{
+		{nil, "null"},
+		{map[string]interface{}{"b": 1.5, "a": []interface{}{true, "x"}}, `{ "a" : [true, "x"], "b" : 1.5 }`},
+		{uint8(7), "7"},
+		{struct {
+			A int `json:"a"`
+		}{3}, `{ "a" : 3 }`},
+		{1e21, "1000000000000000000000"},
+	} {
+		got, err := Value(tc.val)
+		if err != nil || got != tc.want {
+			t.Errorf("%v: expected %s got %s %v", tc.val, tc.want, got, err)
+		}
+	}
+}
+
+// normalize sorts the imports and annotations
+func normalize(mod adl.Module) adl.Module {
+	sort.SliceStable(mod.Imports, func(i, j int) bool { return importName(mod.Imports[i]) < importName(mod.Imports[j]) })
+	sortAnnos(mod.Annotations)
+	for _, decl := range mod.Decls {
+		sortAnnos(decl.Annotations)
+		for _, f := range decl.Fields() {
+			sortAnnos(f.Annotations)
+		}
+	}
+	return mod
+}
+
+func importName(im adl.Import) string {
+	if im.ModuleName != nil {
+		return *im.ModuleName
+	}
+	return im.ScopedName.String()
+}
+
+func sortAnnos(ans adl.Annotations) {
+	sort.SliceStable(ans, func(i, j int) bool { return ans[i].Key.String() < ans[j].Key.String() })
+}
+
+func js(v interface{}) string {
+	by, _ := json.MarshalIndent(v, "", " ")
+	return string(by)
+}
@@ -0,0 +1,315 @@
+// Package adlbuild builds ADL modules in code and converts between modules and ADL source.
+//
+// The builder produces modules in the same form as the adlc ast, with fully qualified references,
+// "nothing" or {"just": v} defaults and docs as sys.annotations.Doc annotations, so generated modules can be
+// fed to the generators or emitted as source with Source.
+//
+//	mod, err := adlbuild.NewModule("a.b").
+//		Struct("Foo").Doc("A foo").
+//		Field("x", adlbuild.Int32).Default(1).
+//		Field("tags", adlbuild.Vector(adlbuild.String)).
+//		Build()
+package adlbuild
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Primitive type expressions
+var (
+	Void   = Prim("Void")
+	Bool   = Prim("Bool")
+	Int8   = Prim("Int8")
+	Int16  = Prim("Int16")
+	Int32  = Prim("Int32")
+	Int64  = Prim("Int64")
+	Word8  = Prim("Word8")
+	Word16 = Prim("Word16")
+	Word32 = Prim("Word32")
+	Word64 = Prim("Word64")
+	Float  = Prim("Float")
+	Double = Prim("Double")
+	String = Prim("String")
+	Bytes  = Prim("Bytes")
+	Json   = Prim("Json")
+)
+
+// Prim is a primitive type, with its parameters for Vector, StringMap, Nullable and TypeToken
+func Prim(name string, params ...adl.TypeExpr) adl.TypeExpr {
+	return adl.TypeExpr{TypeRef: adl.TypeRef{Primitive: &name}, Parameters: append([]adl.TypeExpr{}, params...)}
+}
+
+func Vector(te adl.TypeExpr) adl.TypeExpr    { return Prim("Vector", te) }
+func StringMap(te adl.TypeExpr) adl.TypeExpr { return Prim("StringMap", te) }
+func Nullable(te adl.TypeExpr) adl.TypeExpr  { return Prim("Nullable", te) }
+
+// Param is a reference to a type parameter of the decl
+func Param(name string) adl.TypeExpr {
+	return adl.TypeExpr{TypeRef: adl.TypeRef{TypeParam: &name}, Parameters: []adl.TypeExpr{}}
+}
+
+// Ref is a reference to a decl, an empty module is the module being built
+func Ref(module, name string, params ...adl.TypeExpr) adl.TypeExpr {
+	return adl.TypeExpr{TypeRef: adl.TypeRef{Reference: &adl.ScopedName{ModuleName: module, Name: name}}, Parameters: append([]adl.TypeExpr{}, params...)}
+}
+
+type ModuleBuilder struct {
+	mod   adl.Module
+	decls []*adl.Decl
+	err   error
+}
+
+type DeclBuilder struct {
+	*ModuleBuilder
+	decl *adl.Decl
+}
+
+type FieldBuilder struct {
+	*DeclBuilder
+	idx int
+}
+
+func NewModule(name string) *ModuleBuilder {
+	return &ModuleBuilder{mod: adl.Module{
+		Name:        name,
+		Imports:     []adl.Import{},
+		Decls:       map[string]adl.Decl{},
+		Annotations: adl.Annotations{},
+	}}
+}
+
+func (b *ModuleBuilder) fail(format string, args ...interface{}) {
+	if b.err == nil {
+		b.err = fmt.Errorf("%s: "+format, append([]interface{}{b.mod.Name}, args...)...)
+	}
+}
+
+// Import imports all the decls of a module, ie a.b.*
+func (b *ModuleBuilder) Import(module string) *ModuleBuilder {
+	b.mod.Imports = append(b.mod.Imports, adl.Import{ModuleName: &module})
+	return b
+}
+
+// ImportDecl imports a single decl, ie a.b.Foo
+func (b *ModuleBuilder) ImportDecl(module, name string) *ModuleBuilder {
+	b.mod.Imports = append(b.mod.Imports, adl.Import{ScopedName: &adl.ScopedName{ModuleName: module, Name: name}})
+	return b
+}
+
+// Annotate adds a module annotation
+func (b *ModuleBuilder) Annotate(module, name string, val interface{}) *ModuleBuilder {
+	b.mod.Annotations = append(b.mod.Annotations, adl.Annotation{Key: adl.ScopedName{ModuleName: module, Name: name}, Val: val})
+	return b
+}
+
+func (b *ModuleBuilder) add(name string, dt adl.DeclType) *DeclBuilder {
+	for _, d := range b.decls {
+		if d.Name == name {
+			b.fail("duplicate decl '%s'", name)
+		}
+	}
+	nothing := "nothing"
+	d := &adl.Decl{Name: name, Version: &nothing, Type: dt, Annotations: adl.Annotations{}}
+	b.decls = append(b.decls, d)
+	return &DeclBuilder{ModuleBuilder: b, decl: d}
+}
+
+func (b *ModuleBuilder) Struct(name string, typeParams ...string) *DeclBuilder {
+	return b.add(name, adl.DeclType{Struct: &adl.Name{TypeParams: params(typeParams), Field: []adl.Field{}}})
+}
+
+func (b *ModuleBuilder) Union(name string, typeParams ...string) *DeclBuilder {
+	return b.add(name, adl.DeclType{Union: &adl.Name{TypeParams: params(typeParams), Field: []adl.Field{}}})
+}
+
+func (b *ModuleBuilder) Type(name string, te adl.TypeExpr, typeParams ...string) *DeclBuilder {
+	return b.add(name, adl.DeclType{Type: &adl.TypeDef{TypeParams: params(typeParams), TypeExpr: te}})
+}
+
+func (b *ModuleBuilder) Newtype(name string, te adl.TypeExpr, typeParams ...string) *DeclBuilder {
+	return b.add(name, adl.DeclType{Newtype: &adl.NewType{TypeParams: params(typeParams), TypeExpr: te, Default: "nothing"}})
+}
+
+func params(ps []string) []string {
+	return append([]string{}, ps...)
+}
+
+// Build returns the module, references without a module name are qualified with the module's name
+func (b *ModuleBuilder) Build() (adl.Module, error) {
+	if b.err != nil {
+		return adl.Module{}, b.err
+	}
+	mod := b.mod
+	mod.Decls = map[string]adl.Decl{}
+	mod.Annotations = qualifyAnnos(b.mod.Annotations, b.mod.Name)
+	for _, d := range b.decls {
+		decl := *d
+		decl.Annotations = qualifyAnnos(d.Annotations, b.mod.Name)
+		switch {
+		case d.Type.Struct != nil || d.Type.Union != nil:
+			n := d.Type.Struct
+			if n == nil {
+				n = d.Type.Union
+			}
+			nn := &adl.Name{TypeParams: n.TypeParams, Field: []adl.Field{}}
+			for _, f := range n.Field {
+				f.TypeExpr = qualify(f.TypeExpr, b.mod.Name)
+				f.Annotations = qualifyAnnos(f.Annotations, b.mod.Name)
+				nn.Field = append(nn.Field, f)
+			}
+			if d.Type.Struct != nil {
+				decl.Type = adl.DeclType{Struct: nn}
+			} else {
+				decl.Type = adl.DeclType{Union: nn}
+			}
+		case d.Type.Type != nil:
+			td := *d.Type.Type
+			td.TypeExpr = qualify(td.TypeExpr, b.mod.Name)
+			decl.Type = adl.DeclType{Type: &td}
+		case d.Type.Newtype != nil:
+			nt := *d.Type.Newtype
+			nt.TypeExpr = qualify(nt.TypeExpr, b.mod.Name)
+			decl.Type = adl.DeclType{Newtype: &nt}
+		}
+		mod.Decls[d.Name] = decl
+	}
+	return mod, nil
+}
+
+// Source builds the module and emits it as formatted ADL source
+func (b *ModuleBuilder) Source() (string, error) {
+	mod, err := b.Build()
+	if err != nil {
+		return "", err
+	}
+	return Source(mod)
+}
+
+func qualify(te adl.TypeExpr, module string) adl.TypeExpr {
+	ret := adl.TypeExpr{TypeRef: te.TypeRef, Parameters: []adl.TypeExpr{}}
+	if te.TypeRef.Reference != nil && te.TypeRef.Reference.ModuleName == "" {
+		ret.TypeRef.Reference = &adl.ScopedName{ModuleName: module, Name: te.TypeRef.Reference.Name}
+	}
+	for _, p := range te.Parameters {
+		ret.Parameters = append(ret.Parameters, qualify(p, module))
+	}
+	return ret
+}
+
+func qualifyAnnos(ans adl.Annotations, module string) adl.Annotations {
+	ret := adl.Annotations{}
+	for _, an := range ans {
+		if an.Key.ModuleName == "" {
+			an.Key.ModuleName = module
+		}
+		ret = append(ret, an)
+	}
+	return ret
+}
+
+// docText is the adlc form of a doc comment, each line ends with a newline
+func docText(text string) string {
+	if text == "" || strings.HasSuffix(text, "\n") {
+		return text
+	}
+	return text + "\n"
+}
+
+// Doc sets the doc comment of the decl
+func (d *DeclBuilder) Doc(text string) *DeclBuilder {
+	d.decl.Annotations = setAnno(d.decl.Annotations, adl.DocAnno, docText(text))
+	return d
+}
+
+// Annotate adds an annotation to the decl, an empty module is the module being built
+func (d *DeclBuilder) Annotate(module, name string, val interface{}) *DeclBuilder {
+	d.decl.Annotations = append(d.decl.Annotations, adl.Annotation{Key: adl.ScopedName{ModuleName: module, Name: name}, Val: val})
+	return d
+}
+
+// Default sets the default value of a newtype
+func (d *DeclBuilder) Default(val interface{}) *DeclBuilder {
+	if d.decl.Type.Newtype == nil {
+		d.fail("%s: only newtypes and fields have defaults", d.decl.Name)
+		return d
+	}
+	d.decl.Type.Newtype.Default = map[string]interface{}{"just": val}
+	return d
+}
+
+// Field adds a field to a struct or union
+func (d *DeclBuilder) Field(name string, te adl.TypeExpr) *FieldBuilder {
+	n := d.decl.Type.Struct
+	if n == nil {
+		n = d.decl.Type.Union
+	}
+	if n == nil {
+		d.fail("%s: only structs and unions have fields", d.decl.Name)
+		return &FieldBuilder{DeclBuilder: d, idx: -1}
+	}
+	for _, f := range n.Field {
+		if f.Name == name {
+			d.fail("%s: duplicate field '%s'", d.decl.Name, name)
+		}
+	}
+	n.Field = append(n.Field, adl.Field{
+		Name:           name,
+		SerializedName: name,
+		TypeExpr:       te,
+		Default:        "nothing",
+		Annotations:    adl.Annotations{},
+	})
+	return &FieldBuilder{DeclBuilder: d, idx: len(n.Field) - 1}
+}
+
+// field is the field being built, the fields are reallocated as fields are added
+func (f *FieldBuilder) field() *adl.Field {
+	if f.idx < 0 {
+		return &adl.Field{}
+	}
+	if f.decl.Type.Struct != nil {
+		return &f.decl.Type.Struct.Field[f.idx]
+	}
+	return &f.decl.Type.Union.Field[f.idx]
+}
+
+// Doc sets the doc comment of the field
+func (f *FieldBuilder) Doc(text string) *FieldBuilder {
+	fd := f.field()
+	fd.Annotations = setAnno(fd.Annotations, adl.DocAnno, docText(text))
+	return f
+}
+
+// Annotate adds an annotation to the field, an empty module is the module being built
+func (f *FieldBuilder) Annotate(module, name string, val interface{}) *FieldBuilder {
+	fd := f.field()
+	fd.Annotations = append(fd.Annotations, adl.Annotation{Key: adl.ScopedName{ModuleName: module, Name: name}, Val: val})
+	return f
+}
+
+// Default sets the default value of the field
+func (f *FieldBuilder) Default(val interface{}) *FieldBuilder {
+	f.field().Default = map[string]interface{}{"just": val}
+	return f
+}
+
+// SerializedName sets the name of the field in json
+func (f *FieldBuilder) SerializedName(name string) *FieldBuilder {
+	fd := f.field()
+	fd.SerializedName = name
+	fd.Annotations = setAnno(fd.Annotations, adl.SerializedNameAnno, name)
+	return f
+}
+
+func setAnno(ans adl.Annotations, key adl.ScopedName, val interface{}) adl.Annotations {
+	for i := range ans {
+		if ans[i].Key == key {
+			ans[i].Val = val
+			return ans
+		}
+	}
+	return append(ans, adl.Annotation{Key: key, Val: val})
+}
@@ -0,0 +1,331 @@
+package adlbuild
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlfmt"
+)
+
+// SysAnnotations is imported implicitly by adlc
+const SysAnnotations = "sys.annotations"
+
+// Source emits the module as formatted ADL source.
+// ADL source only refers to decls by name, imports are added for the decls of other modules that aren't
+// already imported, it is an error for two referenced decls to have the same name.
+func Source(mod adl.Module) (string, error) {
+	e := &emitter{mod: mod, names: map[string]adl.ScopedName{}}
+	for name := range mod.Decls {
+		e.names[name] = adl.ScopedName{ModuleName: mod.Name, Name: name}
+	}
+	body, err := e.body()
+	if err != nil {
+		return "", fmt.Errorf("%s: %v", mod.Name, err)
+	}
+	var buf strings.Builder
+	// the other module annotations are annotation statements
+	doc(&buf, mod.Annotations, "")
+	fmt.Fprintf(&buf, "module %s {\n\n", mod.Name)
+	imports := e.imports()
+	for _, im := range imports {
+		fmt.Fprintf(&buf, "import %s;\n", im)
+	}
+	if len(imports) != 0 {
+		buf.WriteString("\n")
+	}
+	buf.WriteString(body)
+	buf.WriteString("};\n")
+	out, err := adlfmt.Format(buf.String())
+	if err != nil {
+		return "", fmt.Errorf("%s: emitted invalid source %v\n%s", mod.Name, err, buf.String())
+	}
+	return out, nil
+}
+
+type emitter struct {
+	mod adl.Module
+	// names are the decls referred to by name
+	names map[string]adl.ScopedName
+}
+
+// ref is the name used for a decl in source
+func (e *emitter) ref(sn adl.ScopedName) (string, error) {
+	if sn.ModuleName == "" || sn.ModuleName == SysAnnotations && (sn.Name == adl.DocAnno.Name || sn.Name == adl.SerializedNameAnno.Name) {
+		return sn.Name, nil
+	}
+	if prev, ex := e.names[sn.Name]; ex && prev != sn {
+		return "", fmt.Errorf("'%s' and '%s' can't both be referred to by name", prev, sn)
+	}
+	e.names[sn.Name] = sn
+	return sn.Name, nil
+}
+
+// imports are the module's imports and those needed for the referenced decls, sorted
+func (e *emitter) imports() []string {
+	seen := map[string]bool{}
+	ims := []string{}
+	add := func(im string) {
+		if !seen[im] {
+			seen[im] = true
+			ims = append(ims, im)
+		}
+	}
+	all := map[string]bool{}
+	for _, im := range e.mod.Imports {
+		switch {
+		case im.ModuleName != nil && *im.ModuleName != SysAnnotations:
+			all[*im.ModuleName] = true
+			add(*im.ModuleName + ".*")
+		case im.ScopedName != nil:
+			add(im.ScopedName.String())
+		}
+	}
+	for _, sn := range e.names {
+		if sn.ModuleName != e.mod.Name && !all[sn.ModuleName] {
+			add(sn.String())
+		}
+	}
+	sort.Strings(ims)
+	return ims
+}
+
+func (e *emitter) body() (string, error) {
+	var buf strings.Builder
+	for _, name := range e.mod.DeclNames() {
+		if err := e.decl(&buf, e.mod.Decls[name]); err != nil {
+			return "", fmt.Errorf("%s: %v", name, err)
+		}
+		buf.WriteString("\n")
+	}
+	if len(e.mod.Annotations) != 0 {
+		for _, an := range e.mod.Annotations {
+			if an.Key == adl.DocAnno {
+				continue
+			}
+			name, err := e.ref(an.Key)
+			if err != nil {
+				return "", err
+			}
+			val, err := Value(an.Val)
+			if err != nil {
+				return "", fmt.Errorf("annotation %s: %v", name, err)
+			}
+			fmt.Fprintf(&buf, "annotation %s %s;\n", name, val)
+		}
+		buf.WriteString("\n")
+	}
+	return buf.String(), nil
+}
+
+func doc(buf *strings.Builder, ans adl.Annotations, indent string) {
+	if doc := ans.Doc(); doc != "" {
+		for _, l := range strings.Split(strings.TrimSuffix(doc, "\n"), "\n") {
+			buf.WriteString(strings.TrimRight(indent+"/// "+l, " ") + "\n")
+		}
+	}
+}
+
+// annos writes the doc comment and the prefix annotations
+func (e *emitter) annos(buf *strings.Builder, ans adl.Annotations, indent string) error {
+	doc(buf, ans, indent)
+	for _, an := range ans {
+		if an.Key == adl.DocAnno {
+			continue
+		}
+		name, err := e.ref(an.Key)
+		if err != nil {
+			return err
+		}
+		if an.Val == nil {
+			fmt.Fprintf(buf, "%s@%s\n", indent, name)
+			continue
+		}
+		val, err := Value(an.Val)
+		if err != nil {
+			return fmt.Errorf("annotation %s: %v", name, err)
+		}
+		fmt.Fprintf(buf, "%s@%s %s\n", indent, name, val)
+	}
+	return nil
+}
+
+func (e *emitter) decl(buf *strings.Builder, decl adl.Decl) error {
+	if err := e.annos(buf, decl.Annotations, ""); err != nil {
+		return err
+	}
+	name := decl.Name
+	if tps := decl.TypeParams(); len(tps) != 0 {
+		name += "<" + strings.Join(tps, ", ") + ">"
+	}
+	switch {
+	case decl.Type.Struct != nil || decl.Type.Union != nil:
+		fmt.Fprintf(buf, "%s %s {\n", decl.Type.Kind(), name)
+		for _, f := range decl.Fields() {
+			if err := e.field(buf, f); err != nil {
+				return fmt.Errorf("%s: %v", f.Name, err)
+			}
+		}
+		buf.WriteString("};\n")
+	case decl.Type.Type != nil:
+		te, err := e.typeExpr(decl.Type.Type.TypeExpr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "type %s = %s;\n", name, te)
+	case decl.Type.Newtype != nil:
+		te, err := e.typeExpr(decl.Type.Newtype.TypeExpr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(buf, "newtype %s = %s", name, te)
+		if def, ok := adl.Just(decl.Type.Newtype.Default); ok {
+			val, err := Value(def)
+			if err != nil {
+				return fmt.Errorf("default: %v", err)
+			}
+			buf.WriteString(" = " + val)
+		}
+		buf.WriteString(";\n")
+	default:
+		return fmt.Errorf("no decl type")
+	}
+	return nil
+}
+
+func (e *emitter) field(buf *strings.Builder, f adl.Field) error {
+	ans := f.Annotations
+	if _, ok := ans.Find(adl.SerializedNameAnno); !ok && f.SerializedName != "" && f.SerializedName != f.Name {
+		ans = append(append(adl.Annotations{}, ans...), adl.Annotation{Key: adl.SerializedNameAnno, Val: f.SerializedName})
+	}
+	if err := e.annos(buf, ans, "  "); err != nil {
+		return err
+	}
+	te, err := e.typeExpr(f.TypeExpr)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "  %s %s", te, f.Name)
+	if def, ok := adl.Just(f.Default); ok {
+		val, err := Value(def)
+		if err != nil {
+			return fmt.Errorf("default: %v", err)
+		}
+		buf.WriteString(" = " + val)
+	}
+	buf.WriteString(";\n")
+	return nil
+}
+
+func (e *emitter) typeExpr(te adl.TypeExpr) (string, error) {
+	var name string
+	switch {
+	case te.TypeRef.Primitive != nil:
+		name = *te.TypeRef.Primitive
+	case te.TypeRef.TypeParam != nil:
+		name = *te.TypeRef.TypeParam
+	case te.TypeRef.Reference != nil:
+		sn := *te.TypeRef.Reference
+		if sn.ModuleName == "" {
+			sn.ModuleName = e.mod.Name
+		}
+		var err error
+		if name, err = e.ref(sn); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("empty type reference")
+	}
+	if len(te.Parameters) == 0 {
+		return name, nil
+	}
+	ps := make([]string, len(te.Parameters))
+	for i, p := range te.Parameters {
+		var err error
+		if ps[i], err = e.typeExpr(p); err != nil {
+			return "", err
+		}
+	}
+	return name + "<" + strings.Join(ps, ", ") + ">", nil
+}
+
+// Value returns the ADL source of a JSON value, object keys are sorted.
+// Values other than the encoding/json generic types are converted with encoding/json.
+func Value(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case string:
+		return strconv.Quote(v), nil
+	case float64:
+		return number(v)
+	case float32:
+		return number(float64(v))
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return "", err
+		}
+		return number(f)
+	case []interface{}:
+		es := make([]string, len(v))
+		for i, el := range v {
+			var err error
+			if es[i], err = Value(el); err != nil {
+				return "", err
+			}
+		}
+		return "[" + strings.Join(es, ", ") + "]", nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		es := make([]string, len(keys))
+		for i, k := range keys {
+			val, err := Value(v[k])
+			if err != nil {
+				return "", err
+			}
+			es[i] = strconv.Quote(k) + " : " + val
+		}
+		if len(es) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(es, ", ") + " }", nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number(float64(rv.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(rv.Uint(), 10), nil
+	}
+	by, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var gv interface{}
+	if err := json.Unmarshal(by, &gv); err != nil {
+		return "", err
+	}
+	return Value(gv)
+}
+
+// number formats without an exponent, the ADL lexer has no negative numbers
+func number(f float64) (string, error) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("%v is not a JSON number", f)
+	}
+	if f < 0 {
+		return "", fmt.Errorf("negative number %v, ADL has no literal for negative numbers", f)
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
@@ -0,0 +1,438 @@
+package adlbuild
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+)
+
+// Parse reads ADL source into a module in the adlc ast form.
+// Names are resolved against the module's decls and imports, names imported with a .* import are looked up in
+// allmod, which may be nil when there are none. sys.annotations is imported implicitly.
+func Parse(src string, allmod map[string]adl.Module) (adl.Module, error) {
+	mod, errs := ParsePartial(src, allmod)
+	if len(errs) != 0 {
+		return adl.Module{}, errs[0]
+	}
+	return mod, nil
+}
+
+// ParsePartial reads the source as Parse does, leaving out the decls, annotation statements and module annotations
+// that fail to read, the errors are theirs. Source with syntax errors isn't read.
+func ParsePartial(src string, allmod map[string]adl.Module) (adl.Module, []error) {
+	_, _, _, _, errs := adl.BuildAdlAST(src)
+	if errs.Error() != nil {
+		for _, ms := range [][]adl.DiagMessage{errs.LexErr, errs.ParseErr, errs.SyntaxErr} {
+			if len(ms) != 0 {
+				return adl.Module{}, []error{fmt.Errorf("%d:%d: %s", ms[0].Line()+1, ms[0].Column()+1, ms[0].Message())}
+			}
+		}
+		return adl.Module{}, []error{errs.Error()}
+	}
+	var ms *cst.Node
+	for _, c := range cst.Parse(src).Root.Children {
+		if c.Rule == "ModuleStatement" {
+			ms = c
+		}
+	}
+	if ms == nil {
+		return adl.Module{}, []error{fmt.Errorf("no module")}
+	}
+	sysAnnos := SysAnnotations
+	r := &reader{
+		allmod: allmod,
+		local:  map[string]bool{},
+		scoped: map[string]adl.ScopedName{},
+		mod: adl.Module{
+			Imports:     []adl.Import{{ModuleName: &sysAnnos}},
+			Decls:       map[string]adl.Decl{},
+			Annotations: adl.Annotations{},
+		},
+	}
+	return r.module(ms)
+}
+
+type reader struct {
+	allmod map[string]adl.Module
+	mod    adl.Module
+	// local decl names, decls imported by name and modules imported with .*
+	local  map[string]bool
+	scoped map[string]adl.ScopedName
+	wild   []string
+}
+
+// tokens are the texts of the node's direct leaves
+func tokens(n *cst.Node) []string {
+	ts := []string{}
+	for _, c := range n.Children {
+		if c.Token != nil {
+			ts = append(ts, c.Token.Text)
+		}
+	}
+	return ts
+}
+
+// rules are the node's direct rule children
+func rules(n *cst.Node) []*cst.Node {
+	rs := []*cst.Node{}
+	for _, c := range n.Children {
+		if c.Token == nil {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
+
+func isAnnon(n *cst.Node) bool {
+	return n.Rule == "DocAnno" || n.Rule == "LocalAnno"
+}
+
+func (r *reader) module(ms *cst.Node) (adl.Module, []error) {
+	ts := tokens(ms)
+	for _, t := range ts[1:] {
+		if t == "{" {
+			break
+		}
+		r.mod.Name += t
+	}
+	// decl names first, decls may be used before they are declared
+	for _, n := range rules(ms) {
+		if n.Rule == "StructOrUnion" || n.Rule == "TypeOrNewtype" {
+			r.local[tokens(n)[1]] = true
+		}
+	}
+	docs := []*cst.Node{}
+	for _, n := range rules(ms) {
+		switch n.Rule {
+		case "DocAnno", "LocalAnno":
+			docs = append(docs, n)
+		case "ImportModuleName":
+			ts := tokens(n)
+			name := strings.Join(ts[1:len(ts)-3], "")
+			if !contains(r.wild, name) {
+				r.wild = append(r.wild, name)
+			}
+			r.mod.Imports = append(r.mod.Imports, adl.Import{ModuleName: &name})
+		case "ImportScopedName":
+			ts := tokens(n)
+			sn := adl.ScopedName{ModuleName: strings.Join(ts[1:len(ts)-3], ""), Name: ts[len(ts)-2]}
+			r.scoped[sn.Name] = sn
+			r.mod.Imports = append(r.mod.Imports, adl.Import{ScopedName: &sn})
+		}
+	}
+	errs := []error{}
+	ans, err := r.annos(docs)
+	if err != nil {
+		errs = append(errs, err)
+		ans = adl.Annotations{}
+	}
+	r.mod.Annotations = ans
+	annoStmts := []*cst.Node{}
+	for _, n := range rules(ms) {
+		switch n.Rule {
+		case "StructOrUnion", "TypeOrNewtype":
+			decl, err := r.decl(n)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %v", decl.Name, err))
+				continue
+			}
+			r.mod.Decls[decl.Name] = decl
+		case "ModuleAnnotation", "DeclAnnotation", "FieldAnnotation":
+			annoStmts = append(annoStmts, n)
+		}
+	}
+	for _, n := range annoStmts {
+		if err := r.annoStmt(n); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return r.mod, errs
+}
+
+// resolve a decl name used in the module
+func (r *reader) resolve(name string) (adl.ScopedName, error) {
+	if r.local[name] {
+		return adl.ScopedName{ModuleName: r.mod.Name, Name: name}, nil
+	}
+	if sn, ex := r.scoped[name]; ex {
+		return sn, nil
+	}
+	found := []adl.ScopedName{}
+	for _, mn := range append([]string{SysAnnotations}, r.wild...) {
+		mod, ex := r.allmod[mn]
+		_, decl := mod.Decls[name]
+		if decl || !ex && mn == SysAnnotations && (name == adl.DocAnno.Name || name == adl.SerializedNameAnno.Name) {
+			found = append(found, adl.ScopedName{ModuleName: mn, Name: name})
+		}
+	}
+	switch len(found) {
+	case 0:
+		return adl.ScopedName{}, fmt.Errorf("unknown name '%s'", name)
+	case 1:
+		return found[0], nil
+	}
+	return adl.ScopedName{}, fmt.Errorf("'%s' is ambiguous, it is declared by %v", name, found)
+}
+
+// annos reads prefix annotations in source order, the doc comment comes last as in the adlc ast
+func (r *reader) annos(ns []*cst.Node) (adl.Annotations, error) {
+	ans := adl.Annotations{}
+	doc := ""
+	for _, n := range ns {
+		if n.Rule == "DocAnno" {
+			line := strings.TrimPrefix(strings.TrimRight(tokens(n)[0], " \t\r"), "///")
+			doc += strings.TrimPrefix(line, " ") + "\n"
+			continue
+		}
+		key, err := r.resolve(tokens(n)[1])
+		if err != nil {
+			return nil, err
+		}
+		an := adl.Annotation{Key: key}
+		if vs := rules(n); len(vs) != 0 {
+			if an.Val, err = jsonValue(vs[0]); err != nil {
+				return nil, err
+			}
+		}
+		ans = append(ans, an)
+	}
+	if doc != "" {
+		ans = append(ans, adl.Annotation{Key: adl.DocAnno, Val: doc})
+	}
+	return ans, nil
+}
+
+func (r *reader) decl(n *cst.Node) (adl.Decl, error) {
+	ts := tokens(n)
+	nothing := "nothing"
+	decl := adl.Decl{Name: ts[1], Version: &nothing}
+	annons := []*cst.Node{}
+	typeParams := []string{}
+	var te *cst.Node
+	var def *cst.Node
+	fields := []*cst.Node{}
+	for _, c := range rules(n) {
+		switch {
+		case isAnnon(c):
+			annons = append(annons, c)
+		case c.Rule == "TypeParameter":
+			for _, t := range tokens(c) {
+				if t != "<" && t != ">" && t != "," {
+					typeParams = append(typeParams, t)
+				}
+			}
+		case c.Rule == "FieldStatement":
+			fields = append(fields, c)
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			te = c
+		default:
+			def = c
+		}
+	}
+	var err error
+	if decl.Annotations, err = r.annos(annons); err != nil {
+		return decl, err
+	}
+	switch ts[0] {
+	case "struct", "union":
+		nm := &adl.Name{TypeParams: typeParams, Field: []adl.Field{}}
+		for _, fn := range fields {
+			f, err := r.field(fn, typeParams)
+			if err != nil {
+				return decl, fmt.Errorf("%s: %v", f.Name, err)
+			}
+			nm.Field = append(nm.Field, f)
+		}
+		if ts[0] == "struct" {
+			decl.Type.Struct = nm
+		} else {
+			decl.Type.Union = nm
+		}
+	case "type", "newtype":
+		texpr, err := r.typeExpr(te, typeParams)
+		if err != nil {
+			return decl, err
+		}
+		if ts[0] == "type" {
+			decl.Type.Type = &adl.TypeDef{TypeParams: typeParams, TypeExpr: texpr}
+			break
+		}
+		nt := &adl.NewType{TypeParams: typeParams, TypeExpr: texpr, Default: "nothing"}
+		if def != nil {
+			v, err := jsonValue(def)
+			if err != nil {
+				return decl, err
+			}
+			nt.Default = map[string]interface{}{"just": v}
+		}
+		decl.Type.Newtype = nt
+	}
+	return decl, nil
+}
+
+func (r *reader) field(n *cst.Node, typeParams []string) (adl.Field, error) {
+	ts := tokens(n)
+	f := adl.Field{Name: ts[0], SerializedName: ts[0], Default: "nothing"}
+	annons := []*cst.Node{}
+	for _, c := range rules(n) {
+		switch {
+		case isAnnon(c):
+			annons = append(annons, c)
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			te, err := r.typeExpr(c, typeParams)
+			if err != nil {
+				return f, err
+			}
+			f.TypeExpr = te
+		default:
+			v, err := jsonValue(c)
+			if err != nil {
+				return f, err
+			}
+			f.Default = map[string]interface{}{"just": v}
+		}
+	}
+	var err error
+	if f.Annotations, err = r.annos(annons); err != nil {
+		return f, err
+	}
+	if sn, ok := f.Annotations.Find(adl.SerializedNameAnno); ok {
+		if s, ok := sn.(string); ok {
+			f.SerializedName = s
+		}
+	}
+	return f, nil
+}
+
+func (r *reader) typeExpr(n *cst.Node, typeParams []string) (adl.TypeExpr, error) {
+	name := tokens(n)[0]
+	te := adl.TypeExpr{Parameters: []adl.TypeExpr{}}
+	switch {
+	case contains(typeParams, name):
+		te.TypeRef.TypeParam = &name
+	case adl.IsPrimitive(name):
+		te.TypeRef.Primitive = &name
+	default:
+		sn, err := r.resolve(name)
+		if err != nil {
+			return te, err
+		}
+		te.TypeRef.Reference = &sn
+	}
+	for _, p := range rules(n) {
+		pte, err := r.typeExpr(p, typeParams)
+		if err != nil {
+			return te, err
+		}
+		te.Parameters = append(te.Parameters, pte)
+	}
+	return te, nil
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// annoStmt adds the annotation of an annotation statement to the module, decl or field
+func (r *reader) annoStmt(n *cst.Node) error {
+	ts := tokens(n)
+	val, err := jsonValue(rules(n)[0])
+	if err != nil {
+		return err
+	}
+	if n.Rule == "ModuleAnnotation" {
+		key, err := r.resolve(ts[1])
+		if err != nil {
+			return err
+		}
+		r.mod.Annotations = append(r.mod.Annotations, adl.Annotation{Key: key, Val: val})
+		return nil
+	}
+	decl, ex := r.mod.Decls[ts[1]]
+	if !ex {
+		return fmt.Errorf("annotation of unknown decl '%s'", ts[1])
+	}
+	key, err := r.resolve(ts[len(ts)-2])
+	if err != nil {
+		return err
+	}
+	an := adl.Annotation{Key: key, Val: val}
+	if n.Rule == "DeclAnnotation" {
+		decl.Annotations = addAnno(decl.Annotations, an)
+		r.mod.Decls[ts[1]] = decl
+		return nil
+	}
+	for i, f := range decl.Fields() {
+		if f.Name == ts[3] {
+			fs := decl.Fields()
+			fs[i].Annotations = addAnno(fs[i].Annotations, an)
+			return nil
+		}
+	}
+	return fmt.Errorf("annotation of unknown field '%s::%s'", ts[1], ts[3])
+}
+
+// addAnno adds an annotation before the doc comment, which is last in the adlc ast
+func addAnno(ans adl.Annotations, an adl.Annotation) adl.Annotations {
+	if n := len(ans); n != 0 && ans[n-1].Key == adl.DocAnno {
+		return append(ans[:n-1:n-1], an, ans[n-1])
+	}
+	return append(ans, an)
+}
+
+func jsonValue(n *cst.Node) (interface{}, error) {
+	ts := tokens(n)
+	switch n.Rule {
+	case "TrueFalseNull":
+		switch ts[0] {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, nil
+	case "StringStatement":
+		return unquote(ts[0])
+	case "NumberStatement", "FloatStatement":
+		return strconv.ParseFloat(ts[0], 64)
+	case "ArrayStatement":
+		arr := []interface{}{}
+		for _, c := range rules(n) {
+			v, err := jsonValue(c)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case "ObjStatement":
+		obj := map[string]interface{}{}
+		for _, c := range rules(n) {
+			k, err := unquote(c.Children[0].Token.Text)
+			if err != nil {
+				return nil, err
+			}
+			if obj[k], err = jsonValue(rules(c)[0]); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	}
+	return nil, fmt.Errorf("unexpected json %s", n.Rule)
+}
+
+// unquote a double or single quoted ADL string
+func unquote(s string) (string, error) {
+	if strings.HasPrefix(s, "'") && len(s) >= 2 {
+		s = `"` + strings.Replace(strings.Replace(s[1:len(s)-1], `\'`, `'`, -1), `"`, `\"`, -1) + `"`
+	}
+	return strconv.Unquote(s)
+}
@@ -0,0 +1,518 @@
+// Package adlfmt formats ADL source into its canonical layout.
+//
+// The layout is two space indentation inside struct and union bodies, one space between tokens,
+// `A<B, C>` type expressions, imports sorted within blank line separated groups, doc comments before
+// prefix annotations which are sorted by name, and JSON values on one line when they fit in 80 columns.
+// Blank lines between statements are kept, collapsed to one.
+// `//` comments are kept, a comment ending a line stays at the end of the line, other comments stay above
+// the token they precede.
+package adlfmt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	antlr "github.com/wxio/goantlr"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+	parser "github.com/wxio/tron-go/internal/adllp"
+)
+
+// Width is the column limit for single line JSON values
+const Width = 80
+
+// Format returns the canonical layout of src.
+// Source that doesn't parse isn't formatted, the first error is returned.
+func Format(src string) (string, error) {
+	_, _, _, _, errs := adl.BuildAdlAST(src)
+	if errs.Error() != nil {
+		for _, ms := range [][]adl.DiagMessage{errs.LexErr, errs.ParseErr, errs.SyntaxErr} {
+			if len(ms) != 0 {
+				return "", fmt.Errorf("%d:%d: %s", ms[0].Line()+1, ms[0].Column()+1, ms[0].Message())
+			}
+		}
+		return "", errs.Error()
+	}
+	p := &printer{toks: scan(src)}
+	p.file()
+	return p.out.String(), nil
+}
+
+type comment struct {
+	text string
+	// blank is set when a blank line precedes the comment
+	blank bool
+}
+
+type token struct {
+	typ  int
+	text string
+	// lead are the comments on the lines before the token
+	lead []comment
+	// trail is the comment after the token on the same line
+	trail []string
+	// blank is set when a blank line precedes the token, after any leading comments
+	blank bool
+}
+
+// scan converts the tokens of the lossless tree, a comment on the line of a token trails it, other comments lead the next token
+func scan(src string) []*token {
+	toks := []*token{}
+	lineStart := true
+	for _, ct := range cst.Parse(src).Tokens {
+		tok := &token{typ: ct.Type, text: strings.TrimRight(ct.Text, " \t\r")}
+		first := len(toks) == 0
+		// empty counts the blank lines since the previous comment or token
+		empty := 0
+		for _, tv := range ct.Leading {
+			switch tv.Kind {
+			case cst.Newline:
+				if lineStart {
+					empty++
+				}
+				lineStart = true
+			case cst.Comment:
+				tok.lead = append(tok.lead, comment{text: strings.TrimRight(tv.Text, " \t"), blank: empty > 0 && (!first || len(tok.lead) != 0)})
+				empty = 0
+				lineStart = false
+			}
+		}
+		tok.blank = empty > 0 && (!first || len(tok.lead) != 0)
+		for _, c := range cst.Comments(ct.Trailing) {
+			tok.trail = append(tok.trail, strings.TrimRight(c, " \t"))
+		}
+		lineStart = len(ct.Trailing) != 0 && ct.Trailing[len(ct.Trailing)-1].Kind == cst.Newline
+		toks = append(toks, tok)
+	}
+	return toks
+}
+
+type printer struct {
+	toks   []*token
+	pos    int
+	indent int
+	out    strings.Builder
+	// cur is the line being built, hoist the comments that go above it and trail those that go at its end
+	cur   strings.Builder
+	hoist []string
+	trail []string
+	// blank is a pending blank line before the current line
+	blank bool
+	// keep honours the blank line before the next token
+	keep bool
+	// open is set when the last line opened a block, blank lines are dropped at the start of blocks
+	open    bool
+	started bool
+}
+
+func (p *printer) peek(k int) *token {
+	if p.pos+k < len(p.toks) {
+		return p.toks[p.pos+k]
+	}
+	return p.toks[len(p.toks)-1]
+}
+
+func (p *printer) at(text string) bool {
+	return p.peek(0).typ != antlr.TokenEOF && p.peek(0).text == text
+}
+
+// stmt marks the next token as the start of a statement, a blank line before it is kept
+func (p *printer) stmt() {
+	p.keep = true
+}
+
+// tok prints the next token, sep is written before it unless it starts the line
+func (p *printer) tok(sep string) {
+	t := p.peek(0)
+	p.pos++
+	if p.cur.Len() == 0 {
+		for _, c := range t.lead {
+			p.blank = p.blank || c.blank
+			p.line(c.text)
+		}
+		p.blank = p.blank || (p.keep && t.blank)
+	} else {
+		for _, c := range t.lead {
+			p.hoist = append(p.hoist, c.text)
+		}
+		p.cur.WriteString(sep)
+	}
+	p.keep = false
+	p.cur.WriteString(t.text)
+	p.trail = append(p.trail, t.trail...)
+}
+
+// line writes a line at the current indent, preceded by a pending blank line
+func (p *printer) line(text string) {
+	if p.blank && p.started && !p.open {
+		p.out.WriteString("\n")
+	}
+	p.blank = false
+	p.out.WriteString(strings.Repeat("  ", p.indent))
+	p.out.WriteString(text)
+	p.out.WriteString("\n")
+	p.started = true
+	p.open = false
+}
+
+// nl ends the current line
+func (p *printer) nl() {
+	if p.cur.Len() == 0 && len(p.hoist) == 0 && len(p.trail) == 0 {
+		return
+	}
+	for _, c := range p.hoist {
+		p.line(c)
+	}
+	text := p.cur.String()
+	if len(p.trail) != 0 {
+		text += " " + strings.Join(p.trail, " ")
+	}
+	p.line(text)
+	p.open = strings.HasSuffix(p.cur.String(), "{") || strings.HasSuffix(p.cur.String(), "[")
+	p.cur.Reset()
+	p.hoist = nil
+	p.trail = nil
+}
+
+func (p *printer) file() {
+	if !p.annons() {
+		p.stmt()
+	}
+	p.tok("")
+	p.name()
+	p.tok(" ")
+	p.nl()
+	// unlike struct bodies the module body isn't indented and may start with a blank line
+	p.open = false
+	p.imports()
+	for !p.at("}") && p.peek(0).typ != antlr.TokenEOF {
+		p.statement()
+	}
+	p.stmt()
+	p.tok("")
+	p.tok("")
+	p.nl()
+	for _, c := range p.peek(0).lead {
+		p.blank = c.blank
+		p.line(c.text)
+	}
+}
+
+// name prints a dotted name
+func (p *printer) name() {
+	p.tok(" ")
+	for p.at(".") {
+		p.tok("")
+		p.tok("")
+	}
+}
+
+// stmtEnd is the index after the statement starting at i, which ends with a ';'
+func (p *printer) stmtEnd(i int) int {
+	for i < len(p.toks) && p.toks[i].text != ";" {
+		i++
+	}
+	return i + 1
+}
+
+// sortRanges stably sorts the token ranges between the bounds, the first range takes over the blank line
+// before the original first range
+func (p *printer) sortRanges(bounds []int, less func(a, b []*token) bool) {
+	rngs := [][]*token{}
+	for i := 0; i+1 < len(bounds); i++ {
+		rngs = append(rngs, append([]*token{}, p.toks[bounds[i]:bounds[i+1]]...))
+	}
+	if len(rngs) < 2 {
+		return
+	}
+	blank := startBlank(rngs[0][0], false)
+	for _, r := range rngs {
+		startBlank(r[0], true)
+	}
+	sort.SliceStable(rngs, func(i, j int) bool { return less(rngs[i], rngs[j]) })
+	if blank {
+		if len(rngs[0][0].lead) != 0 {
+			rngs[0][0].lead[0].blank = true
+		} else {
+			rngs[0][0].blank = true
+		}
+	}
+	i := bounds[0]
+	for _, r := range rngs {
+		i += copy(p.toks[i:], r)
+	}
+}
+
+// startBlank reports whether a blank line precedes t or its leading comments, optionally clearing it
+func startBlank(t *token, clear bool) bool {
+	blank := t.blank
+	if len(t.lead) != 0 {
+		blank = t.lead[0].blank
+		if clear {
+			t.lead[0].blank = false
+		}
+	} else if clear {
+		t.blank = false
+	}
+	return blank
+}
+
+func (p *printer) imports() {
+	for p.at("import") {
+		// a group ends at a blank line
+		bounds := []int{p.pos}
+		for i := p.pos; i < len(p.toks) && p.toks[i].text == "import"; {
+			if i != p.pos && startBlank(p.toks[i], false) {
+				break
+			}
+			i = p.stmtEnd(i)
+			bounds = append(bounds, i)
+		}
+		p.sortRanges(bounds, func(a, b []*token) bool { return text(a) < text(b) })
+		for p.pos < bounds[len(bounds)-1] {
+			p.stmt()
+			p.tok("")
+			p.name()
+			p.tok("")
+			p.nl()
+		}
+	}
+}
+
+func text(ts []*token) string {
+	var buf strings.Builder
+	for _, t := range ts {
+		buf.WriteString(t.text)
+	}
+	return buf.String()
+}
+
+func (p *printer) statement() {
+	if !p.annons() {
+		p.stmt()
+	}
+	switch p.peek(0).text {
+	case "struct", "union":
+		p.tok("")
+		p.tok(" ")
+		p.typeParams()
+		p.tok(" ")
+		p.nl()
+		p.indent++
+		for !p.at("}") && p.peek(0).typ != antlr.TokenEOF {
+			p.field()
+		}
+		p.indent--
+		p.tok("")
+		p.tok("")
+	case "type", "newtype":
+		p.tok("")
+		p.tok(" ")
+		p.typeParams()
+		p.tok(" ")
+		p.typeExpr(" ")
+		if p.at("=") {
+			p.tok(" ")
+			p.json(" ")
+		}
+		p.tok("")
+	case "annotation":
+		p.tok("")
+		p.tok(" ")
+		if p.at("::") {
+			p.tok("")
+			p.tok("")
+		}
+		// the annotation name, unless it is a true, false or null value
+		if p.peek(0).typ == parser.AdlLID && p.peek(1).text != ";" {
+			p.tok(" ")
+		}
+		p.json(" ")
+		p.tok("")
+	default:
+		// not reached for source that parses
+		for !p.at(";") && p.peek(0).typ != antlr.TokenEOF {
+			p.tok(" ")
+		}
+		p.tok("")
+	}
+	p.nl()
+}
+
+func (p *printer) field() {
+	if !p.annons() {
+		p.stmt()
+	}
+	p.typeExpr("")
+	p.tok(" ")
+	if p.at("=") {
+		p.tok(" ")
+		p.json(" ")
+	}
+	p.tok("")
+	p.nl()
+}
+
+func (p *printer) typeParams() {
+	if !p.at("<") {
+		return
+	}
+	p.tok("")
+	p.tok("")
+	for p.at(",") {
+		p.tok("")
+		p.tok(" ")
+	}
+	p.tok("")
+}
+
+func (p *printer) typeExpr(sep string) {
+	p.tok(sep)
+	if !p.at("<") {
+		return
+	}
+	p.tok("")
+	p.typeExpr("")
+	for p.at(",") {
+		p.tok("")
+		p.typeExpr(" ")
+	}
+	p.tok("")
+}
+
+// annons prints the doc comments and prefix annotations, one per line, and reports whether there were any
+func (p *printer) annons() bool {
+	bounds := []int{p.pos}
+	for i := p.pos; ; {
+		switch {
+		case p.toks[i].typ == parser.AdlLLINE_DOC:
+			i++
+		case p.toks[i].typ == parser.AdlLAT:
+			i += 2
+			if hasJson(p.toks[i]) {
+				i = p.jsonEnd(i)
+			}
+		default:
+			// docs first, then annotations by name
+			p.sortRanges(bounds, func(a, b []*token) bool {
+				if a[0].typ == parser.AdlLLINE_DOC || b[0].typ == parser.AdlLLINE_DOC {
+					return a[0].typ == parser.AdlLLINE_DOC && b[0].typ != parser.AdlLLINE_DOC
+				}
+				return a[1].text < b[1].text
+			})
+			for p.pos < i {
+				p.stmt()
+				if p.peek(0).typ == parser.AdlLLINE_DOC {
+					p.tok("")
+				} else {
+					p.tok("")
+					p.tok("")
+					if hasJson(p.peek(0)) {
+						p.json(" ")
+					}
+				}
+				p.nl()
+			}
+			return len(bounds) > 1
+		}
+		bounds = append(bounds, i)
+	}
+}
+
+// hasJson reports whether t starts a JSON value
+func hasJson(t *token) bool {
+	switch t.typ {
+	case parser.AdlLSTR, parser.AdlLINT, parser.AdlLFLT, parser.AdlLLCUR, parser.AdlLLSQ:
+		return true
+	case parser.AdlLID:
+		return t.text == "true" || t.text == "false" || t.text == "null"
+	}
+	return false
+}
+
+// jsonEnd is the index after the JSON value starting at i
+func (p *printer) jsonEnd(i int) int {
+	depth := 0
+	for ; i < len(p.toks); i++ {
+		switch p.toks[i].typ {
+		case parser.AdlLLCUR, parser.AdlLLSQ:
+			depth++
+		case parser.AdlLRCUR, parser.AdlLRSQ:
+			depth--
+		case antlr.TokenEOF:
+			return i
+		}
+		if depth == 0 {
+			return i + 1
+		}
+	}
+	return i
+}
+
+// flat is the single line layout of the tokens of a JSON value
+func flat(ts []*token) string {
+	var buf strings.Builder
+	for i, t := range ts {
+		if i != 0 {
+			prev := ts[i-1]
+			switch {
+			case prev.typ == parser.AdlLLSQ || t.typ == parser.AdlLRSQ || t.typ == parser.AdlLCOMMA:
+			case prev.typ == parser.AdlLLCUR && t.typ == parser.AdlLRCUR:
+			default:
+				buf.WriteString(" ")
+			}
+		}
+		buf.WriteString(t.text)
+	}
+	return buf.String()
+}
+
+// json prints a JSON value on one line if it fits and holds no comments, otherwise one element per line
+func (p *printer) json(sep string) {
+	end := p.jsonEnd(p.pos)
+	ts := p.toks[p.pos:end]
+	comments := false
+	for i, t := range ts {
+		if i != 0 && len(t.lead) != 0 || i != len(ts)-1 && len(t.trail) != 0 {
+			comments = true
+		}
+	}
+	fl := flat(ts)
+	width := 2*p.indent + utf8.RuneCountInString(p.cur.String()+sep+fl) + 1
+	if !comments && width <= Width || len(ts) == 1 {
+		p.tok(sep)
+		for p.pos < end {
+			prev, t := p.toks[p.pos-1], p.peek(0)
+			switch {
+			case prev.typ == parser.AdlLLSQ || t.typ == parser.AdlLRSQ || t.typ == parser.AdlLCOMMA:
+				p.tok("")
+			case prev.typ == parser.AdlLLCUR && t.typ == parser.AdlLRCUR:
+				p.tok("")
+			default:
+				p.tok(" ")
+			}
+		}
+		return
+	}
+	obj := p.peek(0).typ == parser.AdlLLCUR
+	p.tok(sep)
+	p.nl()
+	p.indent++
+	for p.pos < end-1 {
+		if obj {
+			p.tok("")
+			p.tok(" ")
+			p.json(" ")
+		} else {
+			p.json("")
+		}
+		if p.at(",") {
+			p.tok("")
+		}
+		p.nl()
+	}
+	p.indent--
+	p.tok("")
+}
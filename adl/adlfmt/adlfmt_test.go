@@ -0,0 +1,147 @@
+package adlfmt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+const messy = `// header
+
+module   a.b{ // mod
+import  x.z.*;   // z
+// about y
+import x.y;
+
+import b.a;
+
+
+/// doc B
+   @Z   1 // zz
+@A
+/// doc C
+struct B<T,U>{
+
+  // first
+  Int32   a=1;// one
+
+
+  Vector<  T >   b = [ 1,2,
+    3 ];
+  @X {"a":{"b":[1,2]},   "c":null} String c = "x";
+  StringMap<String> d = { // why
+    "k" : "v"
+  };
+};
+type X = Post<A, // a
+  B>;
+annotation B DbTable { "withIdPrimaryKey" : true, "indexes" : [["username"], ["email", "username"]] };
+  };  // end
+
+
+// trailer
+`
+
+const tidy = `// header
+
+module a.b { // mod
+// about y
+import x.y;
+import x.z.*; // z
+
+import b.a;
+
+/// doc B
+/// doc C
+@A
+@Z 1 // zz
+struct B<T, U> {
+  // first
+  Int32 a = 1; // one
+
+  Vector<T> b = [1, 2, 3];
+  @X { "a" : { "b" : [1, 2] }, "c" : null }
+  String c = "x";
+  StringMap<String> d = { // why
+    "k" : "v"
+  };
+};
+type X = Post<A, B>; // a
+annotation B DbTable {
+  "withIdPrimaryKey" : true,
+  "indexes" : [["username"], ["email", "username"]]
+};
+}; // end
+
+// trailer
+`
+
+func TestFormat(t *testing.T) {
+	out, err := Format(messy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != tidy {
+		t.Errorf("expected\n%s\ngot\n%s\n%s", tidy, out, Unified("tidy", tidy, out))
+	}
+	if _, _, _, _, errs := adl.BuildAdlAST(out); errs.Error() != nil {
+		t.Errorf("formatted source doesn't parse %v", errs.Error())
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	src, err := Format(adltest.OneOfEachAdl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range []string{src, tidy} {
+		out, err := Format(src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != src {
+			t.Errorf("formatting changed canonical source\n%s", Unified("src", src, out))
+		}
+	}
+}
+
+func TestInvalid(t *testing.T) {
+	_, err := Format("module a { struct A { Int32 a; } };")
+	if err == nil || !strings.HasPrefix(err.Error(), "1:") {
+		t.Errorf("expected a positioned parse error, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk"
+	edits := Diff(a, b)
+	if len(edits) != 2 || edits[0].Start != 1 || edits[0].End != 2 || edits[0].Lines[0] != "B\n" ||
+		edits[1].Start != 10 || edits[1].End != 10 || edits[1].Lines[0] != "k" {
+		t.Errorf("unexpected edits %+v", edits)
+	}
+	exp := `--- x.orig
++++ x
+@@ -1,5 +1,5 @@
+ a
+-b
++B
+ c
+ d
+ e
+@@ -8,3 +8,4 @@
+ h
+ i
+ j
++k
+\ No newline at end of file
+`
+	if u := Unified("x", a, b); u != exp {
+		t.Errorf("expected\n%s\ngot\n%s", exp, u)
+	}
+	if len(Diff(a, a)) != 0 || Unified("x", a, a) != "" {
+		t.Errorf("expected no edits for the same text")
+	}
+}
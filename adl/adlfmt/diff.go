@@ -0,0 +1,164 @@
+package adlfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Edit replaces the lines [Start, End) of the original, zero based, with Lines.
+// Lines keep their line endings.
+type Edit struct {
+	Start, End int
+	Lines      []string
+}
+
+// maxCells bounds the size of the LCS table, bigger changes are a single edit
+const maxCells = 1 << 22
+
+// Lines splits s after each newline
+func Lines(s string) []string {
+	ls := strings.SplitAfter(s, "\n")
+	if ls[len(ls)-1] == "" {
+		ls = ls[:len(ls)-1]
+	}
+	return ls
+}
+
+// Diff returns the line edits, in order, that turn a into b
+func Diff(a, b string) []Edit {
+	al, bl := Lines(a), Lines(b)
+	pre := 0
+	for pre < len(al) && pre < len(bl) && al[pre] == bl[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(al)-pre && suf < len(bl)-pre && al[len(al)-1-suf] == bl[len(bl)-1-suf] {
+		suf++
+	}
+	am, bm := al[pre:len(al)-suf], bl[pre:len(bl)-suf]
+	if len(am) == 0 && len(bm) == 0 {
+		return nil
+	}
+	if len(am)*len(bm) > maxCells {
+		return []Edit{{Start: pre, End: pre + len(am), Lines: bm}}
+	}
+	// lcs[i][j] is the length of the longest common subsequence of am[i:] and bm[j:]
+	lcs := make([][]int, len(am)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(bm)+1)
+	}
+	for i := len(am) - 1; i >= 0; i-- {
+		for j := len(bm) - 1; j >= 0; j-- {
+			if am[i] == bm[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	edits := []Edit{}
+	var cur *Edit
+	flush := func() {
+		if cur != nil {
+			edits = append(edits, *cur)
+			cur = nil
+		}
+	}
+	i, j := 0, 0
+	for i < len(am) || j < len(bm) {
+		switch {
+		case i < len(am) && j < len(bm) && am[i] == bm[j]:
+			flush()
+			i++
+			j++
+			continue
+		case cur == nil:
+			cur = &Edit{Start: pre + i, End: pre + i}
+		}
+		if j == len(bm) || i < len(am) && lcs[i+1][j] >= lcs[i][j+1] {
+			i++
+			cur.End = pre + i
+		} else {
+			cur.Lines = append(cur.Lines, bm[j])
+			j++
+		}
+	}
+	flush()
+	return edits
+}
+
+// Unified returns the diff of a and b in unified format, like gofmt -d, with 3 lines of context, empty when they are the same
+func Unified(name, a, b string) string {
+	const context = 3
+	edits := Diff(a, b)
+	if len(edits) == 0 {
+		return ""
+	}
+	al := Lines(a)
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "--- %s.orig\n+++ %s\n", name, name)
+	line := func(prefix, l string) {
+		buf.WriteString(prefix + l)
+		if !strings.HasSuffix(l, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+	// delta is the change in the number of lines before the current hunk
+	delta := 0
+	for h := 0; h < len(edits); {
+		// a hunk holds the edits whose context overlaps
+		e := h + 1
+		for e < len(edits) && edits[e].Start-context <= edits[e-1].End+context {
+			e++
+		}
+		start := max(0, edits[h].Start-context)
+		end := min(len(al), edits[e-1].End+context)
+		blen := end - start
+		for _, ed := range edits[h:e] {
+			blen += len(ed.Lines) - (ed.End - ed.Start)
+		}
+		fmt.Fprintf(&buf, "@@ -%s +%s @@\n", span(start, end-start), span(start+delta, blen))
+		pos := start
+		for _, ed := range edits[h:e] {
+			for ; pos < ed.Start; pos++ {
+				line(" ", al[pos])
+			}
+			for ; pos < ed.End; pos++ {
+				line("-", al[pos])
+			}
+			for _, l := range ed.Lines {
+				line("+", l)
+			}
+			delta += len(ed.Lines) - (ed.End - ed.Start)
+		}
+		for ; pos < end; pos++ {
+			line(" ", al[pos])
+		}
+		h = e
+	}
+	return buf.String()
+}
+
+// span is a hunk range, an empty range starts at the line before it
+func span(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, n)
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
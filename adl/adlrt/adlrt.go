@@ -0,0 +1,239 @@
+// Package adlrt is the runtime support for Go code generated from ADL.
+//
+// It implements the ADL JSON serialization rules,
+// structs are objects with every field without a default required and no unknown fields,
+// unions are objects with exactly one key or, for Void branches, the branch name as a string.
+package adlrt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Void is the ADL Void type, it serializes as null
+type Void struct{}
+
+func (Void) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+func (*Void) UnmarshalJSON(b []byte) error {
+	if !isNull(b) {
+		return errors.New("expected null")
+	}
+	return nil
+}
+
+// DecodeError is returned when a value doesn't satisfy the ADL JSON rules.
+// Path is the field and branch names leading to the offending value.
+type DecodeError struct {
+	Path []string
+	Msg  string
+}
+
+func (er *DecodeError) Error() string {
+	if len(er.Path) == 0 {
+		return er.Msg
+	}
+	return strings.Join(er.Path, ".") + ": " + er.Msg
+}
+
+func withPath(name string, err error) error {
+	if de, ok := err.(*DecodeError); ok {
+		return &DecodeError{Path: append([]string{name}, de.Path...), Msg: de.Msg}
+	}
+	return &DecodeError{Path: []string{name}, Msg: err.Error()}
+}
+
+// Field describes a field of a generated struct
+type Field struct {
+	// Serialized name
+	Name string
+	// Pointer to the go struct field
+	Ptr interface{}
+	// JSON of the default value, "" if the field is required
+	Default string
+	// Nullable fields accept a JSON null
+	Nullable bool
+}
+
+// Branch describes a branch of a generated union
+type Branch struct {
+	// Serialized name
+	Name string
+	// Pointer to the go pointer field holding the branch value
+	Ptr interface{}
+	// Void branches serialize as the name string
+	Void bool
+}
+
+func isNull(b []byte) bool {
+	return string(bytes.TrimSpace(b)) == "null"
+}
+
+// Decode unmarshals a JSON value, null is rejected unless nullable
+func Decode(b []byte, v interface{}, nullable bool) error {
+	if len(bytes.TrimSpace(b)) == 0 {
+		return &DecodeError{Msg: "empty value"}
+	}
+	if isNull(b) && !nullable {
+		return &DecodeError{Msg: "null not allowed"}
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		if _, ok := err.(*DecodeError); ok {
+			return err
+		}
+		return &DecodeError{Msg: err.Error()}
+	}
+	return nil
+}
+
+// DecodeStruct unmarshals a JSON object into the fields.
+// Missing fields are set to their default, unknown fields are an error.
+func DecodeStruct(b []byte, fields []Field) error {
+	if isNull(b) {
+		return &DecodeError{Msg: "expected object, got null"}
+	}
+	obj := map[string]json.RawMessage{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return &DecodeError{Msg: "expected object"}
+	}
+	for _, f := range fields {
+		raw, ex := obj[f.Name]
+		if !ex {
+			if f.Default == "" {
+				return &DecodeError{Path: []string{f.Name}, Msg: "missing required field"}
+			}
+			raw = json.RawMessage(f.Default)
+		}
+		delete(obj, f.Name)
+		if err := Decode(raw, f.Ptr, f.Nullable); err != nil {
+			return withPath(f.Name, err)
+		}
+	}
+	for k := range obj {
+		return &DecodeError{Path: []string{k}, Msg: "unknown field"}
+	}
+	return nil
+}
+
+// EncodeStruct marshals the fields as a JSON object.
+// Nil slices and maps are written as empty arrays and objects.
+func EncodeStruct(fields []Field) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i != 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(f.Name)
+		buf.Write(k)
+		buf.WriteByte(':')
+		v, err := encodeValue(reflect.ValueOf(f.Ptr).Elem())
+		if err != nil {
+			return nil, withPath(f.Name, err)
+		}
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+var marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func encodeValue(rv reflect.Value) ([]byte, error) {
+	if rv.IsValid() && !rv.Type().Implements(marshalerType) {
+		switch rv.Kind() {
+		case reflect.Slice:
+			if rv.IsNil() {
+				if rv.Type().Elem().Kind() == reflect.Uint8 {
+					return []byte(`""`), nil
+				}
+				return []byte("[]"), nil
+			}
+		case reflect.Map:
+			if rv.IsNil() {
+				return []byte("{}"), nil
+			}
+		}
+	}
+	return json.Marshal(rv.Interface())
+}
+
+// DecodeUnion unmarshals a JSON union value into the matching branch
+func DecodeUnion(b []byte, branches []Branch) error {
+	var name string
+	var raw json.RawMessage
+	if err := json.Unmarshal(b, &name); err == nil {
+		raw = json.RawMessage("null")
+	} else {
+		obj := map[string]json.RawMessage{}
+		if err := json.Unmarshal(b, &obj); err != nil || isNull(b) {
+			return &DecodeError{Msg: "expected union object or branch name"}
+		}
+		if len(obj) != 1 {
+			return &DecodeError{Msg: fmt.Sprintf("union must have exactly one branch, got %d", len(obj))}
+		}
+		for k, v := range obj {
+			name, raw = k, v
+		}
+	}
+	for _, br := range branches {
+		if br.Name != name {
+			continue
+		}
+		pp := reflect.ValueOf(br.Ptr).Elem()
+		val := reflect.New(pp.Type().Elem())
+		if br.Void {
+			if !isNull(raw) {
+				return &DecodeError{Path: []string{name}, Msg: "expected null"}
+			}
+		} else if err := Decode(raw, val.Interface(), isNullable(pp.Type().Elem())); err != nil {
+			return withPath(name, err)
+		}
+		pp.Set(val)
+		return nil
+	}
+	return &DecodeError{Path: []string{name}, Msg: "unknown union branch"}
+}
+
+// A nullable branch value is a pointer, ie Nullable<T>, or a Void or Json
+func isNullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr || t == reflect.TypeOf(Void{}) || t == reflect.TypeOf(json.RawMessage{})
+}
+
+// EncodeUnion marshals the single set branch
+func EncodeUnion(branches []Branch) ([]byte, error) {
+	var set *Branch
+	for i := range branches {
+		if reflect.ValueOf(branches[i].Ptr).Elem().IsNil() {
+			continue
+		}
+		if set != nil {
+			return nil, &DecodeError{Msg: fmt.Sprintf("union has more than one branch set, '%s' and '%s'", set.Name, branches[i].Name)}
+		}
+		set = &branches[i]
+	}
+	if set == nil {
+		return nil, &DecodeError{Msg: "union has no branch set"}
+	}
+	k, _ := json.Marshal(set.Name)
+	if set.Void {
+		return k, nil
+	}
+	v, err := encodeValue(reflect.ValueOf(set.Ptr).Elem().Elem())
+	if err != nil {
+		return nil, withPath(set.Name, err)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
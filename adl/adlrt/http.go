@@ -0,0 +1,98 @@
+package adlrt
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// HttpError can be returned by a handler to control the response status
+type HttpError struct {
+	Status int
+	Msg    string
+}
+
+func (er *HttpError) Error() string {
+	return fmt.Sprintf("%d %s", er.Status, er.Msg)
+}
+
+// Serve handles a single request.
+// The body is decoded into req, nil for GET requests, before calling fn and writing its result as JSON.
+func Serve(rw http.ResponseWriter, req *http.Request, method string, body interface{}, nullable bool, fn func(ctx context.Context) (interface{}, error)) {
+	if req.Method != method {
+		rw.Header().Set("Allow", method)
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if body != nil {
+		by, err := ioutil.ReadAll(req.Body)
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := Decode(by, body, nullable); err != nil {
+			http.Error(rw, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	resp, err := fn(req.Context())
+	if err != nil {
+		if he, ok := err.(*HttpError); ok {
+			http.Error(rw, he.Msg, he.Status)
+			return
+		}
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	by, err := json.Marshal(resp)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.Write(by)
+}
+
+// Call makes a request, req is nil for GET requests, and decodes the JSON response into resp.
+// A non 2xx status is returned as a *HttpError.
+func Call(ctx context.Context, hc *http.Client, method, url string, body interface{}, resp interface{}) error {
+	var rd *bytes.Reader
+	if body != nil {
+		by, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(by)
+	} else {
+		rd = bytes.NewReader(nil)
+	}
+	req, err := http.NewRequest(method, url, rd)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if hc == nil {
+		hc = http.DefaultClient
+	}
+	res, err := hc.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	by, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return &HttpError{Status: res.StatusCode, Msg: strings.TrimSpace(string(by))}
+	}
+	return Decode(by, resp, true)
+}
@@ -0,0 +1,260 @@
+// Package cst is a lossless concrete syntax tree of ADL source.
+//
+// The lexer skips whitespace and // comments, here they are kept as trivia on the neighbouring tokens.
+// A token's trailing trivia runs to the end of its line, including the newline, the rest of the gap is the
+// leading trivia of the next token. Text the lexer rejects is kept as Skipped trivia, so printing the tokens of
+// any input, valid or not, returns it exactly.
+//
+// The tree follows the ADL parse tree, ctree nodes are mapped to their tokens with File.Token.
+package cst
+
+import (
+	"reflect"
+	"strings"
+	"unicode"
+
+	antlr "github.com/wxio/goantlr"
+	parser "github.com/wxio/tron-go/internal/adllp"
+)
+
+type TriviaKind int
+
+const (
+	// Space is a run of whitespace other than newlines
+	Space TriviaKind = iota
+	// Newline is \n or \r\n
+	Newline
+	// Comment is a // comment without its newline
+	Comment
+	// Skipped is text the lexer rejected
+	Skipped
+)
+
+func (k TriviaKind) String() string {
+	return [...]string{"Space", "Newline", "Comment", "Skipped"}[k]
+}
+
+type Trivia struct {
+	Kind TriviaKind
+	Text string
+}
+
+type Token struct {
+	// Type is the lexer token type, ie adllp.AdlLID, or antlr.TokenEOF for the end of the file
+	Type int
+	Text string
+	// Offset is the rune offset of the token, Line is one based and Column zero based, as in antlr
+	Offset, Line, Column int
+	Leading, Trailing    []Trivia
+}
+
+// String is the source of the token including its trivia
+func (t *Token) String() string {
+	var buf strings.Builder
+	t.write(&buf, true, true)
+	return buf.String()
+}
+
+func (t *Token) write(buf *strings.Builder, leading, trailing bool) {
+	if leading {
+		for _, tv := range t.Leading {
+			buf.WriteString(tv.Text)
+		}
+	}
+	buf.WriteString(t.Text)
+	if trailing {
+		for _, tv := range t.Trailing {
+			buf.WriteString(tv.Text)
+		}
+	}
+}
+
+// Comments returns the comments of the trivia
+func Comments(tvs []Trivia) []string {
+	cs := []string{}
+	for _, tv := range tvs {
+		if tv.Kind == Comment {
+			cs = append(cs, tv.Text)
+		}
+	}
+	return cs
+}
+
+// Node is a parser rule with its children, or a leaf holding a token
+type Node struct {
+	// Rule is the name of the parser rule or labelled alternative, ie StructOrUnion, empty for leaves
+	Rule     string
+	Token    *Token
+	Parent   *Node
+	Children []*Node
+}
+
+// Walk visits the node and its descendants depth first, children are skipped when fn returns false
+func (n *Node) Walk(fn func(depth int, n *Node) bool) {
+	n.walk(0, fn)
+}
+
+func (n *Node) walk(depth int, fn func(depth int, n *Node) bool) {
+	if !fn(depth, n) {
+		return
+	}
+	for _, c := range n.Children {
+		c.walk(depth+1, fn)
+	}
+}
+
+// Tokens are the tokens of the leaves under n, in source order
+func (n *Node) Tokens() []*Token {
+	toks := []*Token{}
+	n.Walk(func(_ int, n *Node) bool {
+		if n.Token != nil {
+			toks = append(toks, n.Token)
+		}
+		return true
+	})
+	return toks
+}
+
+// Text is the source of the node, without the leading trivia of its first token and the trailing trivia of its last
+func (n *Node) Text() string {
+	var buf strings.Builder
+	toks := n.Tokens()
+	for i, t := range toks {
+		t.write(&buf, i != 0, i != len(toks)-1)
+	}
+	return buf.String()
+}
+
+type File struct {
+	// Tokens are all the tokens of the source, the last is EOF and holds the trivia at the end of the file
+	Tokens []*Token
+	// Root is the Adl rule
+	Root     *Node
+	byOffset map[int]*Token
+}
+
+// Parse builds the tree of src, source with errors is parsed with the parser's error recovery
+func Parse(src string) *File {
+	f := &File{byOffset: map[int]*Token{}}
+	rs := []rune(src)
+	lex := parser.NewAdlL(antlr.NewInputStream(src))
+	lex.RemoveErrorListeners()
+	pos := 0
+	var prev *Token
+	for {
+		t := lex.NextToken()
+		start := t.GetStart()
+		if t.GetTokenType() == antlr.TokenEOF || start > len(rs) {
+			start = len(rs)
+		}
+		tok := &Token{Type: t.GetTokenType(), Text: t.GetText(), Offset: start, Line: t.GetLine(), Column: t.GetColumn()}
+		trailing, leading := split(trivia(rs[pos:start]), prev != nil)
+		if prev != nil {
+			prev.Trailing = trailing
+		}
+		tok.Leading = leading
+		if tok.Type == antlr.TokenEOF {
+			tok.Text = ""
+			f.Tokens = append(f.Tokens, tok)
+			break
+		}
+		f.Tokens = append(f.Tokens, tok)
+		f.byOffset[start] = tok
+		pos = t.GetStop() + 1
+		prev = tok
+	}
+	lex = parser.NewAdlL(antlr.NewInputStream(src))
+	lex.RemoveErrorListeners()
+	p := parser.NewAdlP(antlr.NewCommonTokenStream(lex, antlr.TokenDefaultChannel))
+	p.RemoveErrorListeners()
+	f.Root = f.build(p.Adl(), nil)
+	return f
+}
+
+// trivia splits the text between two tokens
+func trivia(rs []rune) []Trivia {
+	tvs := []Trivia{}
+	for i := 0; i < len(rs); {
+		j := i + 1
+		kind := Skipped
+		switch {
+		case rs[i] == '\n':
+			kind = Newline
+		case rs[i] == '\r' && j < len(rs) && rs[j] == '\n':
+			kind = Newline
+			j++
+		case rs[i] == '/' && j < len(rs) && rs[j] == '/':
+			kind = Comment
+			for j < len(rs) && rs[j] != '\n' && !(rs[j] == '\r' && j+1 < len(rs) && rs[j+1] == '\n') {
+				j++
+			}
+		case unicode.IsSpace(rs[i]):
+			kind = Space
+			for j < len(rs) && unicode.IsSpace(rs[j]) && rs[j] != '\n' && !(rs[j] == '\r' && j+1 < len(rs) && rs[j+1] == '\n') {
+				j++
+			}
+		default:
+			for j < len(rs) && !unicode.IsSpace(rs[j]) && !(rs[j] == '/' && j+1 < len(rs) && rs[j+1] == '/') {
+				j++
+			}
+		}
+		tvs = append(tvs, Trivia{Kind: kind, Text: string(rs[i:j])})
+		i = j
+	}
+	return tvs
+}
+
+// split gives the trivia up to and including the first newline to the previous token
+func split(tvs []Trivia, prev bool) (trailing, leading []Trivia) {
+	if !prev {
+		return nil, tvs
+	}
+	for i, tv := range tvs {
+		if tv.Kind == Newline {
+			return tvs[:i+1], tvs[i+1:]
+		}
+	}
+	return tvs, nil
+}
+
+func (f *File) build(tr antlr.Tree, parent *Node) *Node {
+	n := &Node{Parent: parent}
+	switch tr := tr.(type) {
+	case antlr.TerminalNode:
+		// tokens conjured by error recovery aren't in the source
+		tok, ex := f.byOffset[tr.GetSymbol().GetStart()]
+		if tr.GetSymbol().GetTokenType() == antlr.TokenEOF {
+			tok, ex = f.Tokens[len(f.Tokens)-1], true
+		}
+		if !ex {
+			return nil
+		}
+		n.Token = tok
+	default:
+		name := reflect.TypeOf(tr).String()
+		n.Rule = strings.TrimSuffix(name[strings.LastIndex(name, ".")+1:], "Context")
+		for _, c := range tr.GetChildren() {
+			if cn := f.build(c, n); cn != nil {
+				n.Children = append(n.Children, cn)
+			}
+		}
+	}
+	return n
+}
+
+// String is the source, exactly
+func (f *File) String() string {
+	var buf strings.Builder
+	for _, t := range f.Tokens {
+		t.write(&buf, true, true)
+	}
+	return buf.String()
+}
+
+// Token returns the token starting at the same offset as t, ie the start token of a ctree.TreeNode
+func (f *File) Token(t antlr.Token) *Token {
+	if t == nil {
+		return nil
+	}
+	return f.byOffset[t.GetStart()]
+}
@@ -0,0 +1,121 @@
+package cst
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+	"github.com/wxio/tron-go/internal/ctree"
+)
+
+func TestRoundTrip(t *testing.T) {
+	for _, src := range []string{
+		adltest.OneOfEachAdl,
+		"",
+		"// only a comment",
+		"\r\n// crlf\r\nmodule a {\r\n  struct A { Int32 a; };\r\n};\r\n",
+		// lex errors, the '-', '#' and '$' are skipped by the lexer
+		"module a { struct A { Int32 a = -1; }; # $ };",
+		// parse errors
+		"module a { struct A { Int32 a; } }\n\t// trailing\n",
+	} {
+		f := Parse(src)
+		if f.String() != src {
+			t.Errorf("expected\n%q\ngot\n%q", src, f.String())
+		}
+	}
+}
+
+func TestTrivia(t *testing.T) {
+	src := "// header\n\nmodule a { // mod\n  /// doc\n  struct A {\n    // leading\n    Int32 a; // trailing\n  };\n};\n// end\n"
+	f := Parse(src)
+	tok := func(text string) *Token {
+		for _, t := range f.Tokens {
+			if t.Text == text {
+				return t
+			}
+		}
+		t.Fatalf("no token %s", text)
+		return nil
+	}
+	for _, tc := range []struct {
+		tok      *Token
+		leading  string
+		trailing string
+	}{
+		{tok("module"), "// header\n\n", " "},
+		{tok("{"), "", " // mod\n"},
+		{tok("/// doc"), "  ", "\n"},
+		{tok("Int32"), "    // leading\n    ", " "},
+		{tok(";"), "", " // trailing\n"},
+		{f.Tokens[len(f.Tokens)-1], "// end\n", ""},
+	} {
+		var lead, trail strings.Builder
+		for _, tv := range tc.tok.Leading {
+			lead.WriteString(tv.Text)
+		}
+		for _, tv := range tc.tok.Trailing {
+			trail.WriteString(tv.Text)
+		}
+		if lead.String() != tc.leading || trail.String() != tc.trailing {
+			t.Errorf("%s: expected %q %q got %q %q", tc.tok.Text, tc.leading, tc.trailing, lead.String(), trail.String())
+		}
+	}
+	if cs := Comments(tok(";").Trailing); len(cs) != 1 || cs[0] != "// trailing" {
+		t.Errorf("unexpected comments %v", cs)
+	}
+}
+
+func TestTree(t *testing.T) {
+	f := Parse(adltest.OneOfEachAdl)
+	rules := map[string]int{}
+	var field *Node
+	f.Root.Walk(func(_ int, n *Node) bool {
+		rules[n.Rule]++
+		if n.Rule == "FieldStatement" && field == nil {
+			field = n
+		}
+		return true
+	})
+	for _, r := range []string{"ModuleStatement", "ImportModuleName", "ImportScopedName", "StructOrUnion", "TypeOrNewtype",
+		"DeclAnnotation", "FieldAnnotation", "ModuleAnnotation", "LocalAnno", "DocAnno", "TypeExprGeneric", "ObjStatement"} {
+		if rules[r] == 0 {
+			t.Errorf("no %s in tree", r)
+		}
+	}
+	if field.Text() != "String a;" || field.Parent.Rule != "StructOrUnion" {
+		t.Errorf("unexpected first field %q in %s", field.Text(), field.Parent.Rule)
+	}
+	if len(f.Root.Tokens()) != len(f.Tokens) {
+		t.Errorf("expected every token in the tree, got %d of %d", len(f.Root.Tokens()), len(f.Tokens))
+	}
+}
+
+func TestCtreeTokens(t *testing.T) {
+	src := adltest.OneOfEachAdl
+	tr, _, _, _, errs := adl.BuildAdlAST(src)
+	if errs.Error() != nil {
+		t.Fatal(errs.Error())
+	}
+	f := Parse(src)
+	found := false
+	tr.Walk(func(_ int, n ctree.INode) bool {
+		tn, ok := n.(ctree.TreeNode)
+		if !ok {
+			return true
+		}
+		tok := f.Token(tn.StartToken())
+		if tok == nil || tok.Text != tn.StartToken().GetText() {
+			t.Errorf("no token for %v", tn)
+			return true
+		}
+		if tok.Text == "///" || strings.HasPrefix(tok.Text, "/// doccmt") {
+			found = len(Comments(tok.Leading)) == 1
+		}
+		return true
+	})
+	if !found {
+		t.Errorf("expected the comment before '/// doccmt' as leading trivia of its node")
+	}
+}
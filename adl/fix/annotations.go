@@ -0,0 +1,118 @@
+package fix
+
+import (
+	"fmt"
+
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/nav"
+)
+
+// MoveAnnotations move the @Anno prefixes of decls and fields to annotation statements, and annotation
+// statements of the file's decls and fields to @Anno prefixes
+func MoveAnnotations(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	ms := moduleStatement(ix.File)
+	if ms == nil {
+		return fixes
+	}
+	src := ix.File.String()
+	decls := map[string]*cst.Node{}
+	for _, n := range rules(ms) {
+		if ts := leaves(n); (n.Rule == "StructOrUnion" || n.Rule == "TypeOrNewtype") && len(ts) >= 2 {
+			decls[ts[1].Text] = n
+		}
+	}
+	// inline to statements
+	toStatement := func(decl, target *cst.Node, name string) {
+		dts := decl.Tokens()
+		for _, an := range rules(target) {
+			if an.Rule != "LocalAnno" || !overlaps(span(an.Tokens()), r) {
+				continue
+			}
+			key := text(from(leaves(an), 1))
+			stmt := fmt.Sprintf("annotation %s %s %s;", name, key, value(an))
+			fixes = append(fixes, Fix{
+				Title: fmt.Sprintf("Move @%s to an annotation statement", key),
+				Kind:  Rewrite,
+				Edits: []Edit{remove(src, an.Tokens()), insertAfter(dts[len(dts)-1], stmt)},
+			})
+		}
+	}
+	for _, decl := range rules(ms) {
+		if decl.Rule != "StructOrUnion" && decl.Rule != "TypeOrNewtype" || len(leaves(decl)) < 2 {
+			continue
+		}
+		name := leaves(decl)[1].Text
+		toStatement(decl, decl, name)
+		for _, fs := range rules(decl) {
+			if ts := leaves(fs); fs.Rule == "FieldStatement" && len(ts) != 0 {
+				toStatement(decl, fs, name+"::"+ts[0].Text)
+			}
+		}
+	}
+	// statements to inline
+	for _, n := range rules(ms) {
+		ts := leaves(n)
+		var target *cst.Token
+		var on, key string
+		switch {
+		case n.Rule == "DeclAnnotation" && len(ts) >= 4 && decls[ts[1].Text] != nil:
+			target = leaves(decls[ts[1].Text])[0]
+			on, key = ts[1].Text, text(ts[2:len(ts)-1])
+		case n.Rule == "FieldAnnotation" && len(ts) >= 6 && decls[ts[1].Text] != nil:
+			target = fieldStart(decls[ts[1].Text], ts[3].Text)
+			on, key = ts[1].Text+"::"+ts[3].Text, text(ts[4:len(ts)-1])
+		}
+		if target == nil || !overlaps(span(n.Tokens()), r) {
+			continue
+		}
+		fixes = append(fixes, Fix{
+			Title: fmt.Sprintf("Move annotation to @%s on %s", key, on),
+			Kind:  Rewrite,
+			Edits: []Edit{remove(src, n.Tokens()), insertBefore(src, target, "@"+key+" "+value(n))},
+		})
+	}
+	return fixes
+}
+
+// fieldStart is the first token of the field after its prefix annotations
+func fieldStart(decl *cst.Node, field string) *cst.Token {
+	for _, fs := range rules(decl) {
+		if ts := leaves(fs); fs.Rule != "FieldStatement" || len(ts) == 0 || ts[0].Text != field {
+			continue
+		}
+		for _, c := range fs.Children {
+			if c.Rule != "LocalAnno" && c.Rule != "DocAnno" {
+				if ts := c.Tokens(); len(ts) != 0 {
+					return ts[0]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// value is the source of the annotation's value, null when it has none
+func value(an *cst.Node) string {
+	if vs := rules(an); len(vs) != 0 {
+		return vs[0].Text()
+	}
+	return "null"
+}
+
+// text of the tokens, ie a dotted name
+func text(ts []*cst.Token) string {
+	s := ""
+	for _, t := range ts {
+		s += t.Text
+	}
+	return s
+}
+
+// from are the tokens from i on
+func from(ts []*cst.Token, i int) []*cst.Token {
+	if len(ts) < i {
+		return nil
+	}
+	return ts[i:]
+}
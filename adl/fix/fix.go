@@ -0,0 +1,163 @@
+// Package fix computes quick fixes and refactorings of ADL source, the language server's code actions.
+//
+// Fixes are found for the lines of a range of the source, and are made of edits of its text: keywords the parser
+// rejected, names that an import would resolve, imports that aren't used, annotation values missing required
+// fields, and annotations that move between the inline @Anno form and annotation statements.
+package fix
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/nav"
+)
+
+// Kinds of fixes, the values are those of the lsp CodeActionKind
+const (
+	QuickFix = "quickfix"
+	Rewrite  = "refactor.rewrite"
+)
+
+// Edit replaces the range of the source with Text
+type Edit struct {
+	nav.Range
+	Text string
+}
+
+// Fix is a titled set of edits of the source, they don't overlap
+type Fix struct {
+	Title string
+	Kind  string
+	Edits []Edit
+}
+
+// Fixes are the fixes and refactorings for the lines of r, ix indexes the source
+func Fixes(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	fixes = append(fixes, Keywords(ix, r)...)
+	fixes = append(fixes, Imports(ix, r)...)
+	fixes = append(fixes, UnusedImports(ix, r)...)
+	fixes = append(fixes, RequiredFields(ix, r)...)
+	fixes = append(fixes, MoveAnnotations(ix, r)...)
+	return fixes
+}
+
+// Apply the edits to src, the edits don't overlap
+func Apply(src string, edits []Edit) string {
+	lines := strings.SplitAfter(src, "\n")
+	offset := func(line, column int) int {
+		o := 0
+		for i := 0; i < line && i < len(lines); i++ {
+			o += len([]rune(lines[i]))
+		}
+		return o + column
+	}
+	rs := []rune(src)
+	type span struct {
+		start, end int
+		text       string
+	}
+	spans := []span{}
+	for _, ed := range edits {
+		spans = append(spans, span{offset(ed.StartLine, ed.StartColumn), offset(ed.EndLine, ed.EndColumn), ed.Text})
+	}
+	// from the end so the offsets hold
+	sort.SliceStable(spans, func(i, j int) bool { return spans[i].start > spans[j].start })
+	for _, s := range spans {
+		rs = append(rs[:s.start:s.start], append([]rune(s.text), rs[s.end:]...)...)
+	}
+	return string(rs)
+}
+
+// overlaps is true when the ranges share a line
+func overlaps(a, b nav.Range) bool {
+	return a.StartLine <= b.EndLine && a.EndLine >= b.StartLine
+}
+
+// span is the range of the tokens
+func span(ts []*cst.Token) nav.Range {
+	if len(ts) == 0 {
+		return nav.Range{}
+	}
+	first, last := ts[0], ts[len(ts)-1]
+	return nav.Range{
+		StartLine:   first.Line - 1,
+		StartColumn: first.Column,
+		EndLine:     last.Line - 1,
+		EndColumn:   last.Column + len([]rune(last.Text)),
+	}
+}
+
+// leaves are the direct leaves of the node
+func leaves(n *cst.Node) []*cst.Token {
+	ts := []*cst.Token{}
+	for _, c := range n.Children {
+		if c.Token != nil {
+			ts = append(ts, c.Token)
+		}
+	}
+	return ts
+}
+
+// rules are the direct rule children of the node
+func rules(n *cst.Node) []*cst.Node {
+	rs := []*cst.Node{}
+	for _, c := range n.Children {
+		if c.Token == nil {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
+
+// moduleStatement is the last module statement of the file, as in nav.Parse
+func moduleStatement(f *cst.File) *cst.Node {
+	var ms *cst.Node
+	for _, c := range rules(f.Root) {
+		if c.Rule == "ModuleStatement" {
+			ms = c
+		}
+	}
+	return ms
+}
+
+// line is the text of the zero based line of src, without its newline
+func line(src string, l int) []rune {
+	lines := strings.Split(src, "\n")
+	if l < 0 || l >= len(lines) {
+		return nil
+	}
+	return []rune(strings.TrimSuffix(lines[l], "\r"))
+}
+
+// remove is the edit removing the tokens of a statement, with the lines they are on when nothing else is
+func remove(src string, ts []*cst.Token) Edit {
+	r := span(ts)
+	before, after := line(src, r.StartLine), line(src, r.EndLine)
+	if strings.TrimSpace(string(before[:r.StartColumn])) != "" {
+		return Edit{Range: r}
+	}
+	rest := strings.TrimSpace(string(after[r.EndColumn:]))
+	if rest != "" && !strings.HasPrefix(rest, "//") {
+		// up to the next token
+		r.EndColumn = len(after) - len([]rune(strings.TrimLeft(string(after[r.EndColumn:]), " \t")))
+		return Edit{Range: r}
+	}
+	return Edit{Range: nav.Range{StartLine: r.StartLine, EndLine: r.EndLine + 1}}
+}
+
+// insertBefore is the edit inserting a statement before the token, on a line of its own when the token starts one
+func insertBefore(src string, t *cst.Token, stmt string) Edit {
+	l := t.Line - 1
+	before := string(line(src, l)[:t.Column])
+	if strings.TrimSpace(before) != "" {
+		return Edit{Range: nav.Range{StartLine: l, StartColumn: t.Column, EndLine: l, EndColumn: t.Column}, Text: stmt + " "}
+	}
+	return Edit{Range: nav.Range{StartLine: l, EndLine: l}, Text: before + stmt + "\n"}
+}
+
+// insertAfter is the edit inserting a statement on the line after the token
+func insertAfter(t *cst.Token, stmt string) Edit {
+	return Edit{Range: nav.Range{StartLine: t.Line, EndLine: t.Line}, Text: stmt + "\n"}
+}
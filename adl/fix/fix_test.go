@@ -0,0 +1,58 @@
+package fix
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl/nav"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestFixes(t *testing.T) {
+	a := "module a {\nstruct A {\n  Int32 x;\n  String y = \"y\";\n  Bool z;\n};\nunion U {\n  Void v;\n};\n};\n"
+	for _, tc := range []struct {
+		src   string
+		line  int
+		title string
+		want  string
+	}{
+		{"module b {\nstrcut B {\n  Int32 b;\n};\n};\n", 1, "Replace 'strcut' with 'struct'",
+			"module b {\nstruct B {\n  Int32 b;\n};\n};\n"},
+		{"module b {\nstruct B {\n  A a;\n};\n};\n", 2, "Import a.A",
+			"module b {\nimport a.A;\nstruct B {\n  A a;\n};\n};\n"},
+		{"module b {\nimport a.U;\n\nstruct B {\n  A a;\n};\n};\n", 4, "Import a.A",
+			"module b {\nimport a.U;\nimport a.A;\n\nstruct B {\n  A a;\n};\n};\n"},
+		{"module b {\nimport a.A;\nimport a.U; // u\nstruct B {\n  A a;\n};\n};\n", 2, "Remove unused import a.U",
+			"module b {\nimport a.A;\nstruct B {\n  A a;\n};\n};\n"},
+		{"module b {\nimport a.A;\nimport a.U;\nstruct B {\n  Int32 b;\n};\n};\n", 1, "Remove all unused imports",
+			"module b {\nstruct B {\n  Int32 b;\n};\n};\n"},
+		{"module b {\nimport a.A;\n@A {}\nstruct B {\n  Int32 b;\n};\n};\n", 2, "Add the required fields of A: x, z",
+			"module b {\nimport a.A;\n@A { \"x\" : 0, \"z\" : false }\nstruct B {\n  Int32 b;\n};\n};\n"},
+		{"module b {\nimport a.A;\nstruct B {\n  A a = { \"z\" : true };\n};\n};\n", 3, "Add the required fields of A: x",
+			"module b {\nimport a.A;\nstruct B {\n  A a = { \"z\" : true, \"x\" : 0 };\n};\n};\n"},
+		{"module b {\nimport a.A;\nstruct B {\n  @A { \"x\" : 1, \"z\" : true }\n  Int32 b;\n};\n};\n", 3, "Move @A to an annotation statement",
+			"module b {\nimport a.A;\nstruct B {\n  Int32 b;\n};\nannotation B::b A { \"x\" : 1, \"z\" : true };\n};\n"},
+		{"module b {\nimport a.U;\n@U \"v\" struct B {\n  Int32 b;\n};\n};\n", 2, "Move @U to an annotation statement",
+			"module b {\nimport a.U;\nstruct B {\n  Int32 b;\n};\nannotation B U \"v\";\n};\n"},
+		{"module b {\nimport a.U;\nstruct B {\n  Int32 b;\n};\nannotation B::b U \"v\";\n};\n", 5, "Move annotation to @U on B::b",
+			"module b {\nimport a.U;\nstruct B {\n  @U \"v\"\n  Int32 b;\n};\n};\n"},
+		{"module b {\nimport a.U;\nstruct B {\n  Int32 b;\n};\nannotation B U \"v\"; // v\n};\n", 5, "Move annotation to @U on B",
+			"module b {\nimport a.U;\n@U \"v\"\nstruct B {\n  Int32 b;\n};\n};\n"},
+	} {
+		ws := nav.NewWorkspace()
+		ws.SetBase(adltest.Modules())
+		ws.Load(map[string]string{"file:///a.adl": a, "file:///b.adl": tc.src})
+		titles := []string{}
+		got := ""
+		ix, _ := ws.File("file:///b.adl")
+		for _, f := range Fixes(ix, nav.Range{StartLine: tc.line, EndLine: tc.line}) {
+			titles = append(titles, f.Title)
+			if f.Title == tc.title {
+				got = Apply(tc.src, f.Edits)
+			}
+		}
+		if got != tc.want {
+			t.Errorf("%s: fixes %s\ngot\n%s\nwant\n%s", tc.title, strings.Join(titles, "|"), got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package fix
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/nav"
+	"github.com/wxio/tron-go/adl/usage"
+)
+
+// Imports import the decls of the names that don't resolve, from each loaded module declaring one
+func Imports(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	ms := moduleStatement(ix.File)
+	if ms == nil {
+		return fixes
+	}
+	done := map[string]bool{}
+	for _, n := range ix.Names {
+		if n.Kind != nav.TypeRef && n.Kind != nav.Anno || n.Ref.ModuleName != "" {
+			continue
+		}
+		at := nav.Range{StartLine: n.Line, StartColumn: n.Column, EndLine: n.Line, EndColumn: n.Column + n.Len()}
+		if !overlaps(at, r) {
+			continue
+		}
+		mns := []string{}
+		for mn, mod := range ix.AllMod {
+			if _, ex := mod.Decls[n.Text]; ex && mn != ix.Module {
+				mns = append(mns, mn)
+			}
+		}
+		sort.Strings(mns)
+		for _, mn := range mns {
+			imp := mn + "." + n.Text
+			if done[imp] {
+				continue
+			}
+			done[imp] = true
+			fixes = append(fixes, Fix{
+				Title: fmt.Sprintf("Import %s", imp),
+				Kind:  QuickFix,
+				Edits: []Edit{insertImport(ms, imp)},
+			})
+		}
+	}
+	return fixes
+}
+
+// insertImport is the edit adding the import after the last import, or after the module's brace when there are none
+func insertImport(ms *cst.Node, imp string) Edit {
+	var after *cst.Token
+	for _, c := range ms.Children {
+		switch {
+		case c.Rule == "ImportModuleName" || c.Rule == "ImportScopedName":
+			if ts := c.Tokens(); len(ts) != 0 {
+				after = ts[len(ts)-1]
+			}
+		case c.Token != nil && c.Token.Text == "{" && after == nil:
+			after = c.Token
+		}
+	}
+	if after == nil {
+		ts := ms.Tokens()
+		after = ts[len(ts)-1]
+	}
+	return insertAfter(after, "import "+imp+";")
+}
+
+// UnusedImports remove each import nothing is used through, and all of them when there are more
+func UnusedImports(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	src := ix.File.String()
+	u, err := usage.Analyze(src, ix.AllMod)
+	if err != nil {
+		return fixes
+	}
+	unused := u.UnusedImports()
+	all := Fix{Title: "Remove all unused imports", Kind: QuickFix}
+	for _, imp := range unused {
+		ed := remove(src, imp.Node.Tokens())
+		all.Edits = append(all.Edits, ed)
+		if overlaps(span(imp.Node.Tokens()), r) {
+			fixes = append(fixes, Fix{
+				Title: fmt.Sprintf("Remove unused import %s", imp.Text),
+				Kind:  QuickFix,
+				Edits: []Edit{ed},
+			})
+		}
+	}
+	if len(fixes) != 0 && len(unused) > 1 {
+		fixes = append(fixes, all)
+	}
+	return fixes
+}
@@ -0,0 +1,64 @@
+package fix
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/nav"
+)
+
+// RequiredFields add the required fields missing from struct values, annotation values or defaults, with
+// placeholder values
+func RequiredFields(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	ix.File.Root.Walk(func(_ int, n *cst.Node) bool {
+		if n.Rule != "ObjStatement" {
+			return true
+		}
+		ts := n.Tokens()
+		if len(ts) < 2 || ts[len(ts)-1].Text != "}" || !overlaps(span(ts), r) {
+			return true
+		}
+		te, ok := ix.ValueType(n)
+		if !ok {
+			return true
+		}
+		fs, d, _, ok := ix.Fields(te)
+		if !ok || d.Type.Struct == nil {
+			return true
+		}
+		keys := map[string]bool{}
+		for _, kv := range rules(n) {
+			if ks := leaves(kv); len(ks) != 0 && len(ks[0].Text) >= 2 {
+				keys[ks[0].Text[1:len(ks[0].Text)-1]] = true
+			}
+		}
+		names, kvs := []string{}, []string{}
+		for _, f := range fs {
+			if _, ok := adl.Just(f.Default); ok || keys[f.SerializedName] {
+				continue
+			}
+			names = append(names, f.SerializedName)
+			kvs = append(kvs, `"`+f.SerializedName+`" : `+ix.Placeholder(f.TypeExpr))
+		}
+		if len(kvs) == 0 {
+			return true
+		}
+		// after the last value, or inside the braces
+		prev := ts[len(ts)-2]
+		text := ", " + strings.Join(kvs, ", ")
+		if prev.Text == "{" {
+			text = " " + strings.Join(kvs, ", ") + " "
+		}
+		end := span([]*cst.Token{prev})
+		fixes = append(fixes, Fix{
+			Title: fmt.Sprintf("Add the required fields of %s: %s", d.Name, strings.Join(names, ", ")),
+			Kind:  QuickFix,
+			Edits: []Edit{{Range: nav.Range{StartLine: end.EndLine, StartColumn: end.EndColumn, EndLine: end.EndLine, EndColumn: end.EndColumn}, Text: text}},
+		})
+		return true
+	})
+	return fixes
+}
@@ -0,0 +1,88 @@
+package fix
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/nav"
+)
+
+// Keywords replace the keywords the parser rejected with those it expected, the closest first
+func Keywords(ix *nav.Index, r nav.Range) []Fix {
+	fixes := []Fix{}
+	_, _, _, _, errs := adl.BuildAdlAST(ix.File.String())
+	for _, dm := range errs.SyntaxErr {
+		er, ok := dm.(adl.Error)
+		if !ok || er.Start == nil {
+			continue
+		}
+		// the error starts at its statement, which may start with annotations
+		start := ix.File.Token(er.Start)
+		if start == nil {
+			continue
+		}
+		var at *nav.Range
+		for _, t := range ix.File.Tokens {
+			if t.Offset >= start.Offset && t.Text == er.Received {
+				r := span([]*cst.Token{t})
+				at = &r
+				break
+			}
+		}
+		if at == nil || !overlaps(*at, r) {
+			continue
+		}
+		kws := []string{}
+		for _, e := range er.Expected {
+			for _, kw := range strings.Split(e, "|") {
+				if kw != "" && kw != "@" && !strings.HasPrefix(kw, "<") {
+					kws = append(kws, kw)
+				}
+			}
+		}
+		sort.SliceStable(kws, func(i, j int) bool { return distance(er.Received, kws[i]) < distance(er.Received, kws[j]) })
+		for _, kw := range kws {
+			fixes = append(fixes, Fix{
+				Title: fmt.Sprintf("Replace '%s' with '%s'", er.Received, kw),
+				Kind:  QuickFix,
+				Edits: []Edit{{Range: *at, Text: kw}},
+			})
+		}
+	}
+	return fixes
+}
+
+// distance is the Levenshtein distance of the strings
+func distance(a, b string) int {
+	ar, br := []rune(a), []rune(b)
+	prev := make([]int, len(br)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ar); i++ {
+		cur := make([]int, len(br)+1)
+		cur[0] = i
+		for j := 1; j <= len(br); j++ {
+			cost := 1
+			if ar[i-1] == br[j-1] {
+				cost = 0
+			}
+			cur[j] = min3(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(br)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
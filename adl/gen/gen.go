@@ -0,0 +1,186 @@
+// Package gen holds the naming rules and type helpers shared by the ADL code generators.
+package gen
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Well known annotation and request types
+var (
+	HttpPath = adl.ScopedName{ModuleName: "common.http", Name: "Path"}
+	HttpGet  = adl.ScopedName{ModuleName: "common.http", Name: "Get"}
+	HttpPost = adl.ScopedName{ModuleName: "common.http", Name: "Post"}
+)
+
+// ExportName upper cases the first letter of an ADL name
+func ExportName(name string) string {
+	if name == "" {
+		return name
+	}
+	rs := []rune(name)
+	rs[0] = unicode.ToUpper(rs[0])
+	return string(rs)
+}
+
+// LowerName lower cases the first letter of an ADL name
+func LowerName(name string) string {
+	if name == "" {
+		return name
+	}
+	rs := []rune(name)
+	rs[0] = unicode.ToLower(rs[0])
+	return string(rs)
+}
+
+// SnakeName converts a camel case ADL name to snake case, ie withIdPrimaryKey -> with_id_primary_key
+func SnakeName(name string) string {
+	var buf strings.Builder
+	rs := []rune(name)
+	for i, r := range rs {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(rs[i-1]) || (i+1 < len(rs) && unicode.IsLower(rs[i+1]))) {
+				buf.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// DeclName is the target name of a decl referenced from module.
+// Decls from other modules whose name clashes with a decl of module are prefixed with the last component of their module name,
+// ie common.http.Path referenced from a module with its own Path is HttpPath.
+func DeclName(allmod map[string]adl.Module, module string, sn adl.ScopedName) string {
+	if sn.ModuleName == "" || sn.ModuleName == module {
+		return sn.Name
+	}
+	if _, clash := allmod[module].Decls[sn.Name]; !clash {
+		return sn.Name
+	}
+	mn := sn.ModuleName[strings.LastIndex(sn.ModuleName, ".")+1:]
+	return ExportName(mn) + ExportName(sn.Name)
+}
+
+// InstanceName is the name of a decl instantiated with type arguments, ie LoginResp<LocalDate> -> LoginRespLocalDate.
+// Used by targets without generics.
+func InstanceName(name string, args []adl.TypeExpr) string {
+	var buf strings.Builder
+	buf.WriteString(ExportName(name))
+	for _, a := range args {
+		argName(&buf, a)
+	}
+	return buf.String()
+}
+
+func argName(buf *strings.Builder, te adl.TypeExpr) {
+	switch {
+	case te.TypeRef.Primitive != nil:
+		buf.WriteString(*te.TypeRef.Primitive)
+	case te.TypeRef.TypeParam != nil:
+		buf.WriteString(ExportName(*te.TypeRef.TypeParam))
+	case te.TypeRef.Reference != nil:
+		buf.WriteString(ExportName(te.TypeRef.Reference.Name))
+	}
+	for _, p := range te.Parameters {
+		argName(buf, p)
+	}
+}
+
+// Subst replaces the type params in te with the corresponding args
+func Subst(te adl.TypeExpr, params []string, args []adl.TypeExpr) adl.TypeExpr {
+	if te.TypeRef.TypeParam != nil {
+		for i, p := range params {
+			if p == *te.TypeRef.TypeParam && i < len(args) {
+				return args[i]
+			}
+		}
+		return te
+	}
+	ret := adl.TypeExpr{TypeRef: te.TypeRef, Parameters: make([]adl.TypeExpr, len(te.Parameters))}
+	for i, p := range te.Parameters {
+		ret.Parameters[i] = Subst(p, params, args)
+	}
+	return ret
+}
+
+// Qualify makes all references in te fully qualified relative to the module 'from'
+func Qualify(te adl.TypeExpr, from string) adl.TypeExpr {
+	ret := adl.TypeExpr{TypeRef: te.TypeRef, Parameters: make([]adl.TypeExpr, len(te.Parameters))}
+	if te.TypeRef.Reference != nil && te.TypeRef.Reference.ModuleName == "" {
+		ret.TypeRef.Reference = &adl.ScopedName{ModuleName: from, Name: te.TypeRef.Reference.Name}
+	}
+	for i, p := range te.Parameters {
+		ret.Parameters[i] = Qualify(p, from)
+	}
+	return ret
+}
+
+// Endpoint is a decl annotated with common.http.Path whose type is Get<O> or Post<I,O>
+type Endpoint struct {
+	Name   string
+	Decl   adl.Decl
+	Path   string
+	Method string // GET or POST
+	// Request is nil for GET
+	Request  *adl.TypeExpr
+	Response adl.TypeExpr
+}
+
+// Endpoints finds the http request decls of a module.
+// Decls with type params can't be served and are skipped.
+func Endpoints(allmod map[string]adl.Module, module string) ([]Endpoint, error) {
+	mod, ex := allmod[module]
+	if !ex {
+		return nil, fmt.Errorf("unknown module '%s'", module)
+	}
+	eps := []Endpoint{}
+	for _, name := range mod.DeclNames() {
+		decl := mod.Decls[name]
+		pv, ok := decl.Annotations.Find(HttpPath)
+		if !ok || len(decl.TypeParams()) != 0 {
+			continue
+		}
+		path, ok := pv.(string)
+		if !ok || !strings.HasPrefix(path, "/") {
+			return nil, fmt.Errorf("%s.%s: Path annotation must be a string starting with '/', got %v", module, name, pv)
+		}
+		var te adl.TypeExpr
+		switch {
+		case decl.Type.Type != nil:
+			te = decl.Type.Type.TypeExpr
+		case decl.Type.Newtype != nil:
+			te = decl.Type.Newtype.TypeExpr
+		default:
+			continue
+		}
+		te = Qualify(te, module)
+		if te.TypeRef.Reference == nil {
+			continue
+		}
+		ep := Endpoint{Name: name, Decl: decl, Path: path}
+		switch *te.TypeRef.Reference {
+		case HttpGet:
+			if len(te.Parameters) != 1 {
+				return nil, fmt.Errorf("%s.%s: Get expects one type parameter", module, name)
+			}
+			ep.Method = "GET"
+			ep.Response = te.Parameters[0]
+		case HttpPost:
+			if len(te.Parameters) != 2 {
+				return nil, fmt.Errorf("%s.%s: Post expects two type parameters", module, name)
+			}
+			ep.Method = "POST"
+			ep.Request = &te.Parameters[0]
+			ep.Response = te.Parameters[1]
+		default:
+			continue
+		}
+		eps = append(eps, ep)
+	}
+	return eps, nil
+}
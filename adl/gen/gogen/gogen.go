@@ -0,0 +1,402 @@
+// Package gogen generates Go types, and http server and client stubs, from a resolved ADL module.
+//
+// Go (as of go 1.12) has no generics so generic decls are instantiated for each use,
+// ie LoginResp<LocalDate> becomes LoginRespLocalDate.
+// Decls from other modules are generated into the same package as they are referenced.
+package gogen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+const adlrtImport = "github.com/wxio/tron-go/adl/adlrt"
+
+type Config struct {
+	// The ADL module to generate
+	Module string
+	// The Go package name, defaults to the last component of the module name
+	Package string
+	// Http generates a Service interface, NewRouter and Client for the common.http requests of the module
+	Http bool
+}
+
+type generator struct {
+	allmod    map[string]adl.Module
+	cfg       Config
+	imports   map[string]bool
+	insts     *gen.Instances
+	endpoints map[string]bool
+}
+
+// Generate returns the formatted Go source for the module
+func Generate(allmod map[string]adl.Module, cfg Config) ([]byte, error) {
+	mod, ex := allmod[cfg.Module]
+	if !ex {
+		return nil, fmt.Errorf("unknown module '%s'", cfg.Module)
+	}
+	if cfg.Package == "" {
+		cfg.Package = cfg.Module[strings.LastIndex(cfg.Module, ".")+1:]
+	}
+	g := &generator{
+		allmod:    allmod,
+		cfg:       cfg,
+		imports:   map[string]bool{},
+		insts:     gen.NewInstances(allmod, cfg.Module, "go"),
+		endpoints: map[string]bool{},
+	}
+	var eps []gen.Endpoint
+	if cfg.Http {
+		var err error
+		eps, err = gen.Endpoints(allmod, cfg.Module)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range eps {
+			g.endpoints[ep.Name] = true
+		}
+	}
+	for _, name := range mod.DeclNames() {
+		decl := mod.Decls[name]
+		if len(decl.TypeParams()) != 0 || g.endpoints[name] {
+			continue
+		}
+		if _, err := g.insts.Need(adl.ScopedName{ModuleName: cfg.Module, Name: name}, nil); err != nil {
+			return nil, err
+		}
+	}
+	var httpCode bytes.Buffer
+	if cfg.Http && len(eps) != 0 {
+		if err := g.genHttp(&httpCode, eps); err != nil {
+			return nil, err
+		}
+	}
+	insts, err := g.insts.Generate(g.genInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tron-go adl gen go. DO NOT EDIT.\n")
+	fmt.Fprintf(&out, "// ADL module %s\n\n", cfg.Module)
+	fmt.Fprintf(&out, "package %s\n\n", cfg.Package)
+	if len(g.imports) != 0 {
+		imps := make([]string, 0, len(g.imports))
+		for imp := range g.imports {
+			imps = append(imps, imp)
+		}
+		sort.Slice(imps, func(i, j int) bool {
+			si, sj := strings.Contains(imps[i], "."), strings.Contains(imps[j], ".")
+			if si != sj {
+				return sj
+			}
+			return imps[i] < imps[j]
+		})
+		fmt.Fprintf(&out, "import (\n")
+		for i, imp := range imps {
+			// std lib imports first
+			if i > 0 && strings.Contains(imp, ".") && !strings.Contains(imps[i-1], ".") {
+				fmt.Fprintf(&out, "\n")
+			}
+			fmt.Fprintf(&out, "\t%q\n", imp)
+		}
+		fmt.Fprintf(&out, ")\n\n")
+	}
+	out.Write(httpCode.Bytes())
+	for _, inst := range insts {
+		out.Write(inst.Code.Bytes())
+	}
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("generated code doesn't format: %v", err)
+	}
+	return src, nil
+}
+
+// typeOf returns the Go type of a fully qualified type expression
+func (g *generator) typeOf(te adl.TypeExpr) (string, error) {
+	param := func(i int) (string, error) {
+		if len(te.Parameters) <= i {
+			return "", fmt.Errorf("%s missing type param", te)
+		}
+		return g.typeOf(te.Parameters[i])
+	}
+	switch {
+	case te.TypeRef.Primitive != nil:
+		switch p := *te.TypeRef.Primitive; p {
+		case "Void", "TypeToken":
+			g.imports[adlrtImport] = true
+			return "adlrt.Void", nil
+		case "Bool":
+			return "bool", nil
+		case "Int8", "Int16", "Int32", "Int64":
+			return "int" + p[3:], nil
+		case "Word8", "Word16", "Word32", "Word64":
+			return "uint" + p[4:], nil
+		case "Float":
+			return "float32", nil
+		case "Double":
+			return "float64", nil
+		case "String":
+			return "string", nil
+		case "Bytes":
+			return "[]byte", nil
+		case "Json":
+			g.imports["encoding/json"] = true
+			return "json.RawMessage", nil
+		case "Vector":
+			t, err := param(0)
+			return "[]" + t, err
+		case "StringMap":
+			t, err := param(0)
+			return "map[string]" + t, err
+		case "Nullable":
+			t, err := param(0)
+			return "*" + t, err
+		default:
+			return "", fmt.Errorf("unknown primitive '%s'", p)
+		}
+	case te.TypeRef.TypeParam != nil:
+		return "", fmt.Errorf("unbound type param '%s'", *te.TypeRef.TypeParam)
+	case te.TypeRef.Reference != nil:
+		return g.insts.Need(*te.TypeRef.Reference, te.Parameters)
+	}
+	return "", fmt.Errorf("empty type expression")
+}
+
+// nullable reports whether the JSON serialization of a fully qualified type expression admits null
+func (g *generator) nullable(te adl.TypeExpr) bool {
+	switch {
+	case te.TypeRef.Primitive != nil:
+		switch *te.TypeRef.Primitive {
+		case "Void", "TypeToken", "Json", "Nullable":
+			return true
+		}
+	case te.TypeRef.Reference != nil:
+		decl, sn, ex := adl.Lookup(g.allmod, g.cfg.Module, *te.TypeRef.Reference)
+		if !ex {
+			return false
+		}
+		switch {
+		case decl.Type.Type != nil:
+			return g.nullable(gen.Subst(gen.Qualify(decl.Type.Type.TypeExpr, sn.ModuleName), decl.TypeParams(), te.Parameters))
+		case decl.Type.Newtype != nil:
+			return g.nullable(gen.Subst(gen.Qualify(decl.Type.Newtype.TypeExpr, sn.ModuleName), decl.TypeParams(), te.Parameters))
+		}
+	}
+	return false
+}
+
+func writeDoc(buf *bytes.Buffer, indent, doc string) {
+	doc = strings.TrimSpace(doc)
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
+
+func (g *generator) genInstance(inst *gen.Instance) error {
+	buf := &inst.Code
+	decl := inst.Decl
+	writeDoc(buf, "", decl.Doc())
+	if inst.Name != gen.ExportName(decl.Name) || len(inst.Args) != 0 {
+		fmt.Fprintf(buf, "//\n// ADL %s\n", adl.TypeExpr{TypeRef: adl.TypeRef{Reference: &inst.SN}, Parameters: inst.Args})
+	}
+	switch {
+	case decl.Type.Struct != nil:
+		return g.genStruct(inst)
+	case decl.Type.Union != nil:
+		return g.genUnion(inst)
+	case decl.Type.Type != nil:
+		t, err := g.typeOf(inst.FieldType(decl.Type.Type.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.SN, err)
+		}
+		fmt.Fprintf(buf, "type %s = %s\n\n", inst.Name, t)
+	case decl.Type.Newtype != nil:
+		t, err := g.typeOf(inst.FieldType(decl.Type.Newtype.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.SN, err)
+		}
+		g.imports["encoding/json"] = true
+		fmt.Fprintf(buf, "type %s %s\n\n", inst.Name, t)
+		fmt.Fprintf(buf, "func (v %s) MarshalJSON() ([]byte, error) { return json.Marshal(%s(v)) }\n", inst.Name, parenType(t))
+		fmt.Fprintf(buf, "func (v *%s) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, (*%s)(v)) }\n\n", inst.Name, t)
+	default:
+		return fmt.Errorf("%s: empty decl", inst.SN)
+	}
+	return nil
+}
+
+// parenType wraps types, such as *T, that need parentheses in a conversion
+func parenType(t string) string {
+	if strings.HasPrefix(t, "*") {
+		return "(" + t + ")"
+	}
+	return t
+}
+
+func (g *generator) genStruct(inst *gen.Instance) error {
+	buf := &inst.Code
+	g.imports[adlrtImport] = true
+	fields := inst.Decl.Type.Struct.Field
+	fmt.Fprintf(buf, "type %s struct {\n", inst.Name)
+	for _, f := range fields {
+		t, err := g.typeOf(inst.FieldType(f.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", inst.SN, f.Name, err)
+		}
+		writeDoc(buf, "\t", f.Doc())
+		fmt.Fprintf(buf, "\t%s %s `json:\"%s\"`\n", gen.ExportName(f.Name), t, serializedName(f))
+	}
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "func (v *%s) fields() []adlrt.Field {\n", inst.Name)
+	if len(fields) == 0 {
+		fmt.Fprintf(buf, "return nil\n}\n\n")
+	} else {
+		fmt.Fprintf(buf, "return []adlrt.Field{\n")
+		for _, f := range fields {
+			fmt.Fprintf(buf, "{Name: %q, Ptr: &v.%s", serializedName(f), gen.ExportName(f.Name))
+			if d, ok := adl.Just(f.Default); ok {
+				by, err := json.Marshal(d)
+				if err != nil {
+					return fmt.Errorf("%s.%s default: %v", inst.SN, f.Name, err)
+				}
+				fmt.Fprintf(buf, ", Default: %q", string(by))
+			}
+			if g.nullable(inst.FieldType(f.TypeExpr)) {
+				fmt.Fprintf(buf, ", Nullable: true")
+			}
+			fmt.Fprintf(buf, "},\n")
+		}
+		fmt.Fprintf(buf, "}\n}\n\n")
+	}
+	fmt.Fprintf(buf, "func (v %s) MarshalJSON() ([]byte, error) { return adlrt.EncodeStruct(v.fields()) }\n", inst.Name)
+	fmt.Fprintf(buf, "func (v *%s) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }\n\n", inst.Name)
+	return nil
+}
+
+func (g *generator) genUnion(inst *gen.Instance) error {
+	buf := &inst.Code
+	g.imports[adlrtImport] = true
+	fields := inst.Decl.Type.Union.Field
+	fmt.Fprintf(buf, "//\n// Exactly one field must be set.\n")
+	fmt.Fprintf(buf, "type %s struct {\n", inst.Name)
+	for _, f := range fields {
+		t, err := g.typeOf(inst.FieldType(f.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", inst.SN, f.Name, err)
+		}
+		writeDoc(buf, "\t", f.Doc())
+		fmt.Fprintf(buf, "\t%s *%s\n", gen.ExportName(f.Name), t)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "func (v *%s) branches() []adlrt.Branch {\n", inst.Name)
+	fmt.Fprintf(buf, "return []adlrt.Branch{\n")
+	for _, f := range fields {
+		fmt.Fprintf(buf, "{Name: %q, Ptr: &v.%s", serializedName(f), gen.ExportName(f.Name))
+		te := inst.FieldType(f.TypeExpr)
+		if te.TypeRef.Primitive != nil && *te.TypeRef.Primitive == "Void" {
+			fmt.Fprintf(buf, ", Void: true")
+		}
+		fmt.Fprintf(buf, "},\n")
+	}
+	fmt.Fprintf(buf, "}\n}\n\n")
+	fmt.Fprintf(buf, "func (v %s) MarshalJSON() ([]byte, error) { return adlrt.EncodeUnion(v.branches()) }\n", inst.Name)
+	fmt.Fprintf(buf, "func (v *%s) UnmarshalJSON(b []byte) error { return adlrt.DecodeUnion(b, v.branches()) }\n\n", inst.Name)
+	return nil
+}
+
+func serializedName(f adl.Field) string {
+	if f.SerializedName != "" {
+		return f.SerializedName
+	}
+	return f.Name
+}
+
+func (g *generator) genHttp(buf *bytes.Buffer, eps []gen.Endpoint) error {
+	g.imports["context"] = true
+	g.imports["net/http"] = true
+	g.imports["strings"] = true
+	g.imports[adlrtImport] = true
+	type sig struct {
+		ep             gen.Endpoint
+		reqT, respT    string
+		reqNullable    bool
+		method, params string
+	}
+	sigs := make([]sig, len(eps))
+	for i, ep := range eps {
+		s := sig{ep: ep, method: "http.MethodGet", params: "ctx context.Context"}
+		var err error
+		if ep.Request != nil {
+			s.method = "http.MethodPost"
+			if s.reqT, err = g.typeOf(*ep.Request); err != nil {
+				return fmt.Errorf("%s request: %v", ep.Name, err)
+			}
+			s.reqNullable = g.nullable(*ep.Request)
+			s.params += ", req " + s.reqT
+		}
+		if s.respT, err = g.typeOf(ep.Response); err != nil {
+			return fmt.Errorf("%s response: %v", ep.Name, err)
+		}
+		sigs[i] = s
+	}
+	fmt.Fprintf(buf, "// Service is implemented by the server of the %s http requests\n", g.cfg.Module)
+	fmt.Fprintf(buf, "type Service interface {\n")
+	for _, s := range sigs {
+		writeDoc(buf, "\t", s.ep.Decl.Doc())
+		fmt.Fprintf(buf, "\t// %s %s\n", s.ep.Method, s.ep.Path)
+		fmt.Fprintf(buf, "\t%s(%s) (%s, error)\n", gen.ExportName(s.ep.Name), s.params, s.respT)
+	}
+	fmt.Fprintf(buf, "}\n\n")
+
+	fmt.Fprintf(buf, "// NewRouter returns a http.Handler serving the requests of svc.\n")
+	fmt.Fprintf(buf, "// Request bodies are validated against the ADL JSON rules before svc is called.\n")
+	fmt.Fprintf(buf, "func NewRouter(svc Service) http.Handler {\n")
+	fmt.Fprintf(buf, "mux := http.NewServeMux()\n")
+	for _, s := range sigs {
+		fmt.Fprintf(buf, "mux.HandleFunc(%q, func(rw http.ResponseWriter, req *http.Request) {\n", s.ep.Path)
+		if s.ep.Request != nil {
+			fmt.Fprintf(buf, "var body %s\n", s.reqT)
+			fmt.Fprintf(buf, "adlrt.Serve(rw, req, %s, &body, %v, func(ctx context.Context) (interface{}, error) {\n", s.method, s.reqNullable)
+			fmt.Fprintf(buf, "return svc.%s(ctx, body)\n", gen.ExportName(s.ep.Name))
+		} else {
+			fmt.Fprintf(buf, "adlrt.Serve(rw, req, %s, nil, false, func(ctx context.Context) (interface{}, error) {\n", s.method)
+			fmt.Fprintf(buf, "return svc.%s(ctx)\n", gen.ExportName(s.ep.Name))
+		}
+		fmt.Fprintf(buf, "})\n})\n")
+	}
+	fmt.Fprintf(buf, "return mux\n}\n\n")
+
+	fmt.Fprintf(buf, "// Client makes the %s http requests\n", g.cfg.Module)
+	fmt.Fprintf(buf, "type Client struct {\n")
+	fmt.Fprintf(buf, "BaseURL string\n")
+	fmt.Fprintf(buf, "// HTTPClient defaults to http.DefaultClient\n")
+	fmt.Fprintf(buf, "HTTPClient *http.Client\n")
+	fmt.Fprintf(buf, "}\n\n")
+	fmt.Fprintf(buf, "func NewClient(baseURL string) *Client {\n")
+	fmt.Fprintf(buf, "return &Client{BaseURL: strings.TrimRight(baseURL, \"/\")}\n")
+	fmt.Fprintf(buf, "}\n\n")
+	for _, s := range sigs {
+		writeDoc(buf, "", s.ep.Decl.Doc())
+		fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s, error) {\n", gen.ExportName(s.ep.Name), s.params, s.respT)
+		fmt.Fprintf(buf, "var resp %s\n", s.respT)
+		body := "nil"
+		if s.ep.Request != nil {
+			body = "req"
+		}
+		fmt.Fprintf(buf, "err := adlrt.Call(ctx, c.HTTPClient, %s, c.BaseURL+%q, %s, &resp)\n", s.method, s.ep.Path, body)
+		fmt.Fprintf(buf, "return resp, err\n}\n\n")
+	}
+	return nil
+}
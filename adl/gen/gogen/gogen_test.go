@@ -0,0 +1,68 @@
+package gogen
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated code in internal/requests")
+
+func TestGenerateRequests(t *testing.T) {
+	src, err := Generate(adltest.Modules(), Config{Module: "helix.protoapp.requests", Http: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("internal", "requests", "requests_adl.go")
+	if *update {
+		if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s, rerun with -update", golden)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	allmod := adltest.Modules()
+	if _, err := Generate(allmod, Config{Module: "no.such.module"}); err == nil {
+		t.Errorf("expected unknown module error")
+	}
+	mod := allmod["helix.protoapp.requests"]
+	login := mod.Decls["Login"]
+	login.Annotations = adl.Annotations{{Key: adl.ScopedName{ModuleName: "common.http", Name: "Path"}, Val: "login"}}
+	mod.Decls["Login"] = login
+	_, err := Generate(allmod, Config{Module: "helix.protoapp.requests", Http: true})
+	if err == nil || !strings.Contains(err.Error(), "Path annotation") {
+		t.Errorf("expected Path annotation error got %v", err)
+	}
+}
+
+func TestGenerateWithoutHttp(t *testing.T) {
+	src, err := Generate(adltest.Modules(), Config{Module: "helix.protoapp.requests", Package: "reqs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(src)
+	if !strings.Contains(s, "package reqs\n") {
+		t.Errorf("expected package reqs")
+	}
+	if strings.Contains(s, "type Service interface") {
+		t.Errorf("unexpected http stubs")
+	}
+	// without http the request decls are plain types
+	if !strings.Contains(s, "type Login = PostLoginReqLoginResult") {
+		t.Errorf("expected Login type alias")
+	}
+}
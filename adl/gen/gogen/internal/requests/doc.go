@@ -0,0 +1,5 @@
+// Package requests is generated from the helix.protoapp.requests test module,
+// it is used to test the generated http server and client.
+package requests
+
+//go:generate go test github.com/wxio/tron-go/adl/gen/gogen -run TestGenerateRequests -update
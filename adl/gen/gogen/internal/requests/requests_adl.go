@@ -0,0 +1,321 @@
+// Code generated by tron-go adl gen go. DO NOT EDIT.
+// ADL module helix.protoapp.requests
+
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/wxio/tron-go/adl/adlrt"
+)
+
+// Service is implemented by the server of the helix.protoapp.requests http requests
+type Service interface {
+	// docontype
+	// GET /debug/time
+	CurrentTime(ctx context.Context) (Instant, error)
+	// doconnewtype
+	// POST /debug/dummy-exception
+	DummyException(ctx context.Context, req string) (Unit, error)
+	// Authenticate a user
+	// POST /login
+	Login(ctx context.Context, req LoginReq) (LoginResult, error)
+	// POST /hello
+	SayHello(ctx context.Context, req HelloReq) (Greeting, error)
+}
+
+// NewRouter returns a http.Handler serving the requests of svc.
+// Request bodies are validated against the ADL JSON rules before svc is called.
+func NewRouter(svc Service) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/time", func(rw http.ResponseWriter, req *http.Request) {
+		adlrt.Serve(rw, req, http.MethodGet, nil, false, func(ctx context.Context) (interface{}, error) {
+			return svc.CurrentTime(ctx)
+		})
+	})
+	mux.HandleFunc("/debug/dummy-exception", func(rw http.ResponseWriter, req *http.Request) {
+		var body string
+		adlrt.Serve(rw, req, http.MethodPost, &body, false, func(ctx context.Context) (interface{}, error) {
+			return svc.DummyException(ctx, body)
+		})
+	})
+	mux.HandleFunc("/login", func(rw http.ResponseWriter, req *http.Request) {
+		var body LoginReq
+		adlrt.Serve(rw, req, http.MethodPost, &body, false, func(ctx context.Context) (interface{}, error) {
+			return svc.Login(ctx, body)
+		})
+	})
+	mux.HandleFunc("/hello", func(rw http.ResponseWriter, req *http.Request) {
+		var body HelloReq
+		adlrt.Serve(rw, req, http.MethodPost, &body, false, func(ctx context.Context) (interface{}, error) {
+			return svc.SayHello(ctx, body)
+		})
+	})
+	return mux
+}
+
+// Client makes the helix.protoapp.requests http requests
+type Client struct {
+	BaseURL string
+	// HTTPClient defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func NewClient(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// docontype
+func (c *Client) CurrentTime(ctx context.Context) (Instant, error) {
+	var resp Instant
+	err := adlrt.Call(ctx, c.HTTPClient, http.MethodGet, c.BaseURL+"/debug/time", nil, &resp)
+	return resp, err
+}
+
+// doconnewtype
+func (c *Client) DummyException(ctx context.Context, req string) (Unit, error) {
+	var resp Unit
+	err := adlrt.Call(ctx, c.HTTPClient, http.MethodPost, c.BaseURL+"/debug/dummy-exception", req, &resp)
+	return resp, err
+}
+
+// Authenticate a user
+func (c *Client) Login(ctx context.Context, req LoginReq) (LoginResult, error) {
+	var resp LoginResult
+	err := adlrt.Call(ctx, c.HTTPClient, http.MethodPost, c.BaseURL+"/login", req, &resp)
+	return resp, err
+}
+
+func (c *Client) SayHello(ctx context.Context, req HelloReq) (Greeting, error) {
+	var resp Greeting
+	err := adlrt.Call(ctx, c.HTTPClient, http.MethodPost, c.BaseURL+"/hello", req, &resp)
+	return resp, err
+}
+
+type A struct {
+	A string `json:"a"`
+}
+
+func (v *A) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "a", Ptr: &v.A},
+	}
+}
+
+func (v A) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *A) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+// An audit log entry
+type Audit struct {
+	Who      string          `json:"who"`
+	When     Instant         `json:"when"`
+	Day      LocalDate       `json:"day"`
+	Detail   json.RawMessage `json:"detail"`
+	Previous *Audit          `json:"previous"`
+	Pairs    MapStringInt64  `json:"pairs"`
+}
+
+func (v *Audit) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "who", Ptr: &v.Who},
+		{Name: "when", Ptr: &v.When},
+		{Name: "day", Ptr: &v.Day},
+		{Name: "detail", Ptr: &v.Detail, Nullable: true},
+		{Name: "previous", Ptr: &v.Previous, Default: "null", Nullable: true},
+		{Name: "pairs", Ptr: &v.Pairs, Default: "[]"},
+	}
+}
+
+func (v Audit) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *Audit) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+type B struct {
+	B int32 `json:"b"`
+}
+
+func (v *B) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "b", Ptr: &v.B},
+	}
+}
+
+func (v B) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *B) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+type Greeting struct {
+	Message string             `json:"message"`
+	Count   int32              `json:"count"`
+	Extras  map[string]float64 `json:"extras"`
+}
+
+func (v *Greeting) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "message", Ptr: &v.Message},
+		{Name: "count", Ptr: &v.Count, Default: "1"},
+		{Name: "extras", Ptr: &v.Extras, Default: "{}"},
+	}
+}
+
+func (v Greeting) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *Greeting) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+// doconstr
+type HelloReq struct {
+	Name string `json:"name"`
+	// Unique login name
+	Username  string   `json:"username"`
+	Email     *string  `json:"email"`
+	Age       int32    `json:"age"`
+	CreatedAt Instant  `json:"createdAt"`
+	Config    MyConfig `json:"config"`
+	Tags      []string `json:"labels"`
+}
+
+func (v *HelloReq) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "name", Ptr: &v.Name},
+		{Name: "username", Ptr: &v.Username},
+		{Name: "email", Ptr: &v.Email, Default: "null", Nullable: true},
+		{Name: "age", Ptr: &v.Age, Default: "0"},
+		{Name: "createdAt", Ptr: &v.CreatedAt},
+		{Name: "config", Ptr: &v.Config},
+		{Name: "labels", Ptr: &v.Tags, Default: "[]"},
+	}
+}
+
+func (v HelloReq) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *HelloReq) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+// Milliseconds since the epoch
+type Instant int64
+
+func (v Instant) MarshalJSON() ([]byte, error)  { return json.Marshal(int64(v)) }
+func (v *Instant) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, (*int64)(v)) }
+
+// ADL helix.protoapp.requests.Literal<String>
+type LiteralString = map[string]string
+
+// A date in ISO 8601 format
+type LocalDate string
+
+func (v LocalDate) MarshalJSON() ([]byte, error)  { return json.Marshal(string(v)) }
+func (v *LocalDate) UnmarshalJSON(b []byte) error { return json.Unmarshal(b, (*string)(v)) }
+
+type LoginReq struct {
+	Username string  `json:"username"`
+	Password string  `json:"password"`
+	Otp      *string `json:"otp"`
+}
+
+func (v *LoginReq) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "username", Ptr: &v.Username},
+		{Name: "password", Ptr: &v.Password},
+		{Name: "otp", Ptr: &v.Otp, Default: "null", Nullable: true},
+	}
+}
+
+func (v LoginReq) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *LoginReq) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+// ADL helix.protoapp.requests.LoginResp<common.LocalDate>
+//
+// Exactly one field must be set.
+type LoginRespLocalDate struct {
+	AccessToken *LocalDate
+}
+
+func (v *LoginRespLocalDate) branches() []adlrt.Branch {
+	return []adlrt.Branch{
+		{Name: "accessToken", Ptr: &v.AccessToken},
+	}
+}
+
+func (v LoginRespLocalDate) MarshalJSON() ([]byte, error)  { return adlrt.EncodeUnion(v.branches()) }
+func (v *LoginRespLocalDate) UnmarshalJSON(b []byte) error { return adlrt.DecodeUnion(b, v.branches()) }
+
+// The outcome of a login attempt
+//
+// Exactly one field must be set.
+type LoginResult struct {
+	AccessToken        *string
+	InvalidCredentials *adlrt.Void
+	Locked             *LoginRespLocalDate
+}
+
+func (v *LoginResult) branches() []adlrt.Branch {
+	return []adlrt.Branch{
+		{Name: "accessToken", Ptr: &v.AccessToken},
+		{Name: "invalidCredentials", Ptr: &v.InvalidCredentials, Void: true},
+		{Name: "locked", Ptr: &v.Locked},
+	}
+}
+
+func (v LoginResult) MarshalJSON() ([]byte, error)  { return adlrt.EncodeUnion(v.branches()) }
+func (v *LoginResult) UnmarshalJSON(b []byte) error { return adlrt.DecodeUnion(b, v.branches()) }
+
+// ADL sys.types.Map<String,Int64>
+type MapStringInt64 = []PairStringInt64
+
+// Exactly one field must be set.
+type MyConfig struct {
+	A *A
+	B *B
+}
+
+func (v *MyConfig) branches() []adlrt.Branch {
+	return []adlrt.Branch{
+		{Name: "a", Ptr: &v.A},
+		{Name: "b", Ptr: &v.B},
+	}
+}
+
+func (v MyConfig) MarshalJSON() ([]byte, error)  { return adlrt.EncodeUnion(v.branches()) }
+func (v *MyConfig) UnmarshalJSON(b []byte) error { return adlrt.DecodeUnion(b, v.branches()) }
+
+type MyConfigMap = map[string]MyConfig
+
+// ADL sys.types.Pair<String,Int64>
+type PairStringInt64 struct {
+	V1 string `json:"v1"`
+	V2 int64  `json:"v2"`
+}
+
+func (v *PairStringInt64) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "v1", Ptr: &v.V1},
+		{Name: "v2", Ptr: &v.V2},
+	}
+}
+
+func (v PairStringInt64) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *PairStringInt64) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+type SA struct {
+	A string `json:"a"`
+}
+
+func (v *SA) fields() []adlrt.Field {
+	return []adlrt.Field{
+		{Name: "a", Ptr: &v.A},
+	}
+}
+
+func (v SA) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *SA) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
+
+type StrLiteral = LiteralString
+
+// A type with a single value
+type Unit struct {
+}
+
+func (v *Unit) fields() []adlrt.Field {
+	return nil
+}
+
+func (v Unit) MarshalJSON() ([]byte, error)  { return adlrt.EncodeStruct(v.fields()) }
+func (v *Unit) UnmarshalJSON(b []byte) error { return adlrt.DecodeStruct(b, v.fields()) }
@@ -0,0 +1,158 @@
+package requests
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl/adlrt"
+)
+
+type service struct {
+	hello HelloReq
+}
+
+func (s *service) CurrentTime(ctx context.Context) (Instant, error) {
+	return Instant(1234), nil
+}
+func (s *service) DummyException(ctx context.Context, req string) (Unit, error) {
+	return Unit{}, &adlrt.HttpError{Status: http.StatusTeapot, Msg: req}
+}
+func (s *service) Login(ctx context.Context, req LoginReq) (LoginResult, error) {
+	switch {
+	case req.Password == "secret":
+		tok := "token-" + req.Username
+		return LoginResult{AccessToken: &tok}, nil
+	case req.Otp != nil:
+		date := LocalDate("2019-06-01")
+		return LoginResult{Locked: &LoginRespLocalDate{AccessToken: &date}}, nil
+	case req.Password == "broken":
+		return LoginResult{}, errors.New("broken")
+	}
+	return LoginResult{InvalidCredentials: &adlrt.Void{}}, nil
+}
+func (s *service) SayHello(ctx context.Context, req HelloReq) (Greeting, error) {
+	s.hello = req
+	return Greeting{Message: "hello " + req.Name, Count: req.Age}, nil
+}
+
+func setup(t *testing.T) (*service, *httptest.Server, *Client) {
+	svc := &service{}
+	srv := httptest.NewServer(NewRouter(svc))
+	return svc, srv, NewClient(srv.URL + "/")
+}
+
+func TestClientServer(t *testing.T) {
+	_, srv, cl := setup(t)
+	defer srv.Close()
+	ctx := context.Background()
+
+	now, err := cl.CurrentTime(ctx)
+	if err != nil || now != 1234 {
+		t.Errorf("CurrentTime %v %v", now, err)
+	}
+	res, err := cl.Login(ctx, LoginReq{Username: "bob", Password: "secret"})
+	if err != nil || res.AccessToken == nil || *res.AccessToken != "token-bob" {
+		t.Errorf("Login %+v %v", res, err)
+	}
+	res, err = cl.Login(ctx, LoginReq{Username: "bob", Password: "wrong"})
+	if err != nil || res.InvalidCredentials == nil || res.AccessToken != nil {
+		t.Errorf("Login void branch %+v %v", res, err)
+	}
+	otp := "123456"
+	res, err = cl.Login(ctx, LoginReq{Username: "bob", Password: "wrong", Otp: &otp})
+	if err != nil || res.Locked == nil || *res.Locked.AccessToken != "2019-06-01" {
+		t.Errorf("Login generic branch %+v %v", res, err)
+	}
+	_, err = cl.Login(ctx, LoginReq{Username: "bob", Password: "broken"})
+	if he, ok := err.(*adlrt.HttpError); !ok || he.Status != http.StatusInternalServerError {
+		t.Errorf("expected 500 got %v", err)
+	}
+	_, err = cl.DummyException(ctx, "teapot")
+	if he, ok := err.(*adlrt.HttpError); !ok || he.Status != http.StatusTeapot || he.Msg != "teapot" {
+		t.Errorf("expected 418 got %v", err)
+	}
+}
+
+func TestServerDefaults(t *testing.T) {
+	svc, srv, _ := setup(t)
+	defer srv.Close()
+	body := `{"name":"n","username":"u","createdAt":5,"config":{"b":{"b":2}}}`
+	res, err := http.Post(srv.URL+"/hello", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	by, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("status %d %s", res.StatusCode, by)
+	}
+	if svc.hello.Age != 0 || svc.hello.Tags == nil || svc.hello.Email != nil || svc.hello.Config.B == nil {
+		t.Errorf("defaults not applied %+v", svc.hello)
+	}
+	resp := map[string]interface{}{}
+	if err := json.Unmarshal(by, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resp["extras"].(map[string]interface{}); !ok {
+		t.Errorf("expected extras to be an object %s", by)
+	}
+}
+
+func TestServerValidation(t *testing.T) {
+	_, srv, _ := setup(t)
+	defer srv.Close()
+	for _, tc := range []struct {
+		method, path, body string
+		status             int
+		msg                string
+	}{
+		{"POST", "/login", `{"username":"bob"}`, 400, "password: missing required field"},
+		{"POST", "/login", `{"username":"bob","password":"x","extra":1}`, 400, "extra: unknown field"},
+		{"POST", "/login", `{"username":null,"password":"x"}`, 400, "username: null not allowed"},
+		{"POST", "/login", `{"username":1,"password":"x"}`, 400, "username"},
+		{"POST", "/login", `null`, 400, "null"},
+		{"POST", "/login", ``, 400, "empty"},
+		{"POST", "/hello", `{"name":"n","username":"u","createdAt":5,"config":{"a":{"a":"x"},"b":{"b":2}}}`, 400, "config: union must have exactly one branch"},
+		{"POST", "/hello", `{"name":"n","username":"u","createdAt":5,"config":{"c":{}}}`, 400, "config.c: unknown union branch"},
+		{"POST", "/hello", `{"name":"n","username":"u","createdAt":5,"config":{"a":{}}}`, 400, "config.a.a: missing required field"},
+		{"POST", "/debug/dummy-exception", `null`, 400, "null not allowed"},
+		{"GET", "/login", ``, 405, "method not allowed"},
+		{"POST", "/debug/time", `{}`, 405, "method not allowed"},
+		{"GET", "/nothere", ``, 404, ""},
+	} {
+		req, _ := http.NewRequest(tc.method, srv.URL+tc.path, strings.NewReader(tc.body))
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		by, _ := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		if res.StatusCode != tc.status || !strings.Contains(string(by), tc.msg) {
+			t.Errorf("%s %s %s: expected %d '%s' got %d '%s'", tc.method, tc.path, tc.body, tc.status, tc.msg, res.StatusCode, by)
+		}
+	}
+}
+
+func TestUnionEncoding(t *testing.T) {
+	by, err := json.Marshal(LoginResult{InvalidCredentials: &adlrt.Void{}})
+	if err != nil || string(by) != `"invalidCredentials"` {
+		t.Errorf("void branch %s %v", by, err)
+	}
+	tok := "t"
+	if _, err := json.Marshal(LoginResult{AccessToken: &tok, InvalidCredentials: &adlrt.Void{}}); err == nil {
+		t.Errorf("expected error for two branches")
+	}
+	if _, err := json.Marshal(LoginResult{}); err == nil {
+		t.Errorf("expected error for no branches")
+	}
+	var res LoginResult
+	if err := json.Unmarshal([]byte(`{"invalidCredentials":null}`), &res); err != nil || res.InvalidCredentials == nil {
+		t.Errorf("void branch as object %+v %v", res, err)
+	}
+}
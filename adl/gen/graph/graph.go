@@ -0,0 +1,277 @@
+// Package graph exports the dependencies between ADL decls, or modules, as Graphviz DOT or Mermaid.
+//
+// A decl depends on the decls referenced by its fields and type expressions, edges are labelled with the field name,
+// and on the decls of its annotations, these edges are dashed and labelled with @ and the annotated field, if any.
+// Doc annotations are not dependencies and are left out.
+package graph
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+type Options struct {
+	// Modules collapses decls into their modules
+	Modules bool
+	// Root limits the graph to the transitive closure of a decl, ie common.http.Post
+	Root string
+}
+
+type Node struct {
+	// ID is the scoped name of the decl, or the module name
+	ID     string
+	Module string
+	Label  string
+}
+
+type Edge struct {
+	From, To string
+	Label    string
+	// Anno is set for annotation usages
+	Anno bool
+}
+
+type Graph struct {
+	// Modules is set for module granularity
+	Modules bool
+	Nodes   []Node
+	Edges   []Edge
+}
+
+// Build walks the modules and returns the dependency graph, nodes and edges are sorted
+func Build(allmod map[string]adl.Module, opts Options) (*Graph, error) {
+	nodes := map[string]Node{}
+	edges := map[Edge]bool{}
+	out := map[string][]Edge{}
+	for _, mn := range adl.ModuleNames(allmod) {
+		mod := allmod[mn]
+		for _, dn := range mod.DeclNames() {
+			decl := mod.Decls[dn]
+			from := adl.ScopedName{ModuleName: mn, Name: dn}.String()
+			nodes[from] = Node{ID: from, Module: mn, Label: dn}
+			add := func(sn adl.ScopedName, label string, anno bool) {
+				_, sn, ex := adl.Lookup(allmod, mn, sn)
+				if !ex {
+					return
+				}
+				e := Edge{From: from, To: sn.String(), Label: label, Anno: anno}
+				if !edges[e] {
+					edges[e] = true
+					out[from] = append(out[from], e)
+				}
+			}
+			annos := func(as adl.Annotations, label string) {
+				for _, a := range as {
+					if a.Key != adl.DocAnno {
+						add(a.Key, label, true)
+					}
+				}
+			}
+			annos(decl.Annotations, "@")
+			var fields []adl.Field
+			switch {
+			case decl.Type.Struct != nil:
+				fields = decl.Type.Struct.Field
+			case decl.Type.Union != nil:
+				fields = decl.Type.Union.Field
+			case decl.Type.Type != nil:
+				refs(decl.Type.Type.TypeExpr, func(sn adl.ScopedName) { add(sn, "", false) })
+			case decl.Type.Newtype != nil:
+				refs(decl.Type.Newtype.TypeExpr, func(sn adl.ScopedName) { add(sn, "", false) })
+			}
+			for _, f := range fields {
+				refs(f.TypeExpr, func(sn adl.ScopedName) { add(sn, f.Name, false) })
+				annos(f.Annotations, "@"+f.Name)
+			}
+		}
+	}
+	if opts.Root != "" {
+		i := strings.LastIndex(opts.Root, ".")
+		if i == -1 {
+			return nil, fmt.Errorf("root '%s' must be a scoped name, ie module.Decl", opts.Root)
+		}
+		if _, _, ex := adl.Lookup(allmod, "", adl.ScopedName{ModuleName: opts.Root[:i], Name: opts.Root[i+1:]}); !ex {
+			return nil, fmt.Errorf("unknown root decl '%s'", opts.Root)
+		}
+		// transitive closure
+		keep := map[string]bool{opts.Root: true}
+		stack := []string{opts.Root}
+		for len(stack) != 0 {
+			id := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			for _, e := range out[id] {
+				if !keep[e.To] {
+					keep[e.To] = true
+					stack = append(stack, e.To)
+				}
+			}
+		}
+		for id := range nodes {
+			if !keep[id] {
+				delete(nodes, id)
+			}
+		}
+		for e := range edges {
+			if !keep[e.From] {
+				delete(edges, e)
+			}
+		}
+	}
+	g := &Graph{}
+	if opts.Modules {
+		g.Modules = true
+		mnodes := map[string]Node{}
+		for _, n := range nodes {
+			mnodes[n.Module] = Node{ID: n.Module, Module: n.Module, Label: n.Module}
+		}
+		// an edge between modules is an annotation edge only if every usage is an annotation
+		medges := map[[2]string]bool{}
+		for e := range edges {
+			from, to := nodes[e.From].Module, nodes[e.To].Module
+			if from == to {
+				continue
+			}
+			k := [2]string{from, to}
+			anno, ex := medges[k]
+			medges[k] = e.Anno && (anno || !ex)
+		}
+		if opts.Root == "" {
+			for mn, mod := range allmod {
+				for _, a := range mod.Annotations {
+					if _, sn, ex := adl.Lookup(allmod, mn, a.Key); ex && sn.ModuleName != mn {
+						k := [2]string{mn, sn.ModuleName}
+						if _, ex := medges[k]; !ex {
+							medges[k] = true
+						}
+					}
+				}
+			}
+		}
+		nodes = mnodes
+		edges = map[Edge]bool{}
+		for k, anno := range medges {
+			e := Edge{From: k[0], To: k[1], Anno: anno}
+			if anno {
+				e.Label = "@"
+			}
+			edges[e] = true
+		}
+	}
+	for _, n := range nodes {
+		g.Nodes = append(g.Nodes, n)
+	}
+	sort.Slice(g.Nodes, func(i, j int) bool { return g.Nodes[i].ID < g.Nodes[j].ID })
+	for e := range edges {
+		g.Edges = append(g.Edges, e)
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		a, b := g.Edges[i], g.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Label < b.Label
+	})
+	return g, nil
+}
+
+// refs calls fn for each reference in a type expression
+func refs(te adl.TypeExpr, fn func(sn adl.ScopedName)) {
+	if te.TypeRef.Reference != nil {
+		fn(*te.TypeRef.Reference)
+	}
+	for _, p := range te.Parameters {
+		refs(p, fn)
+	}
+}
+
+// byModule groups the nodes, which are sorted, by module
+func (g *Graph) byModule() ([]string, map[string][]Node) {
+	mods := []string{}
+	by := map[string][]Node{}
+	for _, n := range g.Nodes {
+		if _, ex := by[n.Module]; !ex {
+			mods = append(mods, n.Module)
+		}
+		by[n.Module] = append(by[n.Module], n)
+	}
+	return mods, by
+}
+
+// Dot returns the graph in Graphviz DOT, decls are clustered by module
+func (g *Graph) Dot() string {
+	var buf strings.Builder
+	buf.WriteString("digraph adl {\n  rankdir=LR;\n  node [shape=box];\n")
+	if g.Modules {
+		for _, n := range g.Nodes {
+			fmt.Fprintf(&buf, "  %q;\n", n.ID)
+		}
+	} else {
+		mods, by := g.byModule()
+		for i, mn := range mods {
+			fmt.Fprintf(&buf, "  subgraph cluster_%d {\n    label=%q;\n", i, mn)
+			for _, n := range by[mn] {
+				fmt.Fprintf(&buf, "    %q [label=%q];\n", n.ID, n.Label)
+			}
+			buf.WriteString("  }\n")
+		}
+	}
+	for _, e := range g.Edges {
+		attrs := []string{}
+		if e.Label != "" {
+			attrs = append(attrs, fmt.Sprintf("label=%q", e.Label))
+		}
+		if e.Anno {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&buf, "  %q -> %q", e.From, e.To)
+		if len(attrs) != 0 {
+			fmt.Fprintf(&buf, " [%s]", strings.Join(attrs, ", "))
+		}
+		buf.WriteString(";\n")
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// Mermaid returns the graph as a Mermaid flowchart, decls are grouped into a subgraph per module
+func (g *Graph) Mermaid() string {
+	var buf strings.Builder
+	buf.WriteString("graph LR\n")
+	// mermaid ids can't contain dots
+	ids := map[string]string{}
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	if g.Modules {
+		for _, n := range g.Nodes {
+			fmt.Fprintf(&buf, "  %s[%q]\n", ids[n.ID], n.Label)
+		}
+	} else {
+		mods, by := g.byModule()
+		for i, mn := range mods {
+			fmt.Fprintf(&buf, "  subgraph m%d [%q]\n", i, mn)
+			for _, n := range by[mn] {
+				fmt.Fprintf(&buf, "    %s[%q]\n", ids[n.ID], n.Label)
+			}
+			buf.WriteString("  end\n")
+		}
+	}
+	for _, e := range g.Edges {
+		arrow := "-->"
+		if e.Anno {
+			arrow = "-.->"
+		}
+		if e.Label != "" {
+			fmt.Fprintf(&buf, "  %s %s|%q| %s\n", ids[e.From], arrow, e.Label, ids[e.To])
+		} else {
+			fmt.Fprintf(&buf, "  %s %s %s\n", ids[e.From], arrow, ids[e.To])
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestRootClosure(t *testing.T) {
+	g, err := Build(adltest.Modules(), Options{Root: "helix.protoapp.requests.Login"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := []string{}
+	for _, n := range g.Nodes {
+		ids = append(ids, n.ID)
+	}
+	// SA is reached through the field annotation on LoginResp.accessToken
+	exp := "common.LocalDate,common.http.Path,common.http.Post,helix.protoapp.requests.Login,helix.protoapp.requests.LoginReq," +
+		"helix.protoapp.requests.LoginResp,helix.protoapp.requests.LoginResult,helix.protoapp.requests.SA"
+	if strings.Join(ids, ",") != exp {
+		t.Errorf("expected %s got %s", exp, strings.Join(ids, ","))
+	}
+	dot := g.Dot()
+	for _, exp := range []string{
+		`"helix.protoapp.requests.Login" -> "common.http.Post";`,
+		`"helix.protoapp.requests.LoginResult" -> "helix.protoapp.requests.LoginResp" [label="locked"];`,
+		`"helix.protoapp.requests.Login" -> "common.http.Path" [label="@", style=dashed];`,
+		`"helix.protoapp.requests.LoginResp" -> "helix.protoapp.requests.SA" [label="@accessToken", style=dashed];`,
+		"    label=\"common.http\";\n    \"common.http.Path\" [label=\"Path\"];\n",
+	} {
+		if !strings.Contains(dot, exp) {
+			t.Errorf("expected %s in\n%s", exp, dot)
+		}
+	}
+	mm := g.Mermaid()
+	for _, exp := range []string{
+		"graph LR\n  subgraph m0 [\"common\"]\n    n0[\"LocalDate\"]\n  end\n",
+		"  n3 -.->|\"@\"| n1\n",
+		"  n6 -->|\"locked\"| n5\n",
+	} {
+		if !strings.Contains(mm, exp) {
+			t.Errorf("expected %q in\n%s", exp, mm)
+		}
+	}
+}
+
+func TestModules(t *testing.T) {
+	g, err := Build(adltest.Modules(), Options{Modules: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	edges := []string{}
+	for _, e := range g.Edges {
+		s := e.From + "->" + e.To
+		if e.Anno {
+			s += "@"
+		}
+		edges = append(edges, s)
+	}
+	exp := "helix.protoapp.requests->common,helix.protoapp.requests->common.db@,helix.protoapp.requests->common.http," +
+		"helix.protoapp.requests->sys.annotations@,helix.protoapp.requests->sys.types"
+	if strings.Join(edges, ",") != exp {
+		t.Errorf("expected %s got %s", exp, strings.Join(edges, ","))
+	}
+	if len(g.Nodes) != 6 {
+		t.Errorf("expected a node per module got %v", g.Nodes)
+	}
+}
+
+func TestRootErrors(t *testing.T) {
+	for _, root := range []string{"Login", "helix.protoapp.requests.Nothere"} {
+		if _, err := Build(adltest.Modules(), Options{Root: root}); err == nil {
+			t.Errorf("%s: expected error", root)
+		}
+	}
+}
@@ -0,0 +1,461 @@
+// Package htmldoc generates a static, cross-linked HTML documentation site from resolved ADL modules.
+//
+// The site has an index page listing every module and decl, one page per module with an anchor per decl,
+// and a search script. Types link to their definitions and annotation values are rendered as JSON.
+package htmldoc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Site is the set of pages for a group of modules
+type Site struct {
+	allmod map[string]adl.Module
+	// Title of the index page
+	Title string
+}
+
+func New(allmod map[string]adl.Module) *Site {
+	return &Site{allmod: allmod, Title: "ADL"}
+}
+
+// ModulePage is the file name of a module's page
+func ModulePage(module string) string {
+	return module + ".html"
+}
+
+// Pages returns the content of each file in the site keyed by its file name
+func (s *Site) Pages() (map[string][]byte, error) {
+	pages := map[string][]byte{}
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "index", s.index()); err != nil {
+		return nil, err
+	}
+	pages["index.html"] = buf.Bytes()
+	for _, mn := range adl.ModuleNames(s.allmod) {
+		var buf bytes.Buffer
+		if err := tmpl.ExecuteTemplate(&buf, "module", s.module(mn)); err != nil {
+			return nil, fmt.Errorf("%s: %v", mn, err)
+		}
+		pages[ModulePage(mn)] = buf.Bytes()
+	}
+	js, err := s.searchJs()
+	if err != nil {
+		return nil, err
+	}
+	pages["search.js"] = js
+	pages["style.css"] = []byte(style)
+	return pages, nil
+}
+
+// Write writes the site into dir, creating it if needed
+func (s *Site) Write(dir string) error {
+	pages, err := s.Pages()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	for name, by := range pages {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), by, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Handler serves the site for the modules returned by allmod, the pages are rebuilt on each request
+func Handler(title string, allmod func() map[string]adl.Module) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		mods := allmod()
+		if mods == nil {
+			http.Error(rw, "no modules compiled yet", http.StatusServiceUnavailable)
+			return
+		}
+		site := New(mods)
+		site.Title = title
+		pages, err := site.Pages()
+		if err != nil {
+			http.Error(rw, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		name := path.Base(req.URL.Path)
+		if name == "/" || name == "." {
+			name = "index.html"
+		}
+		by, ex := pages[name]
+		if !ex {
+			http.NotFound(rw, req)
+			return
+		}
+		switch path.Ext(name) {
+		case ".js":
+			rw.Header().Set("Content-Type", "application/javascript")
+		case ".css":
+			rw.Header().Set("Content-Type", "text/css")
+		default:
+			rw.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+		rw.Write(by)
+	})
+}
+
+type indexEntry struct {
+	Name   string
+	Module string
+	Kind   string
+	Href   string
+	// first line of the doc
+	Summary string
+}
+
+type indexPage struct {
+	Title   string
+	Modules []moduleEntry
+	Decls   []indexEntry
+}
+
+type moduleEntry struct {
+	Name    string
+	Href    string
+	Summary string
+}
+
+func summary(doc string) string {
+	doc = strings.TrimSpace(doc)
+	if i := strings.Index(doc, "\n"); i != -1 {
+		doc = doc[:i]
+	}
+	return doc
+}
+
+func (s *Site) entries() []indexEntry {
+	ents := []indexEntry{}
+	for _, mn := range adl.ModuleNames(s.allmod) {
+		mod := s.allmod[mn]
+		for _, dn := range mod.DeclNames() {
+			decl := mod.Decls[dn]
+			ents = append(ents, indexEntry{
+				Name:    dn,
+				Module:  mn,
+				Kind:    decl.Type.Kind(),
+				Href:    ModulePage(mn) + "#" + dn,
+				Summary: summary(decl.Doc()),
+			})
+		}
+	}
+	sort.SliceStable(ents, func(i, j int) bool {
+		if ents[i].Name != ents[j].Name {
+			return ents[i].Name < ents[j].Name
+		}
+		return ents[i].Module < ents[j].Module
+	})
+	return ents
+}
+
+func (s *Site) index() indexPage {
+	pg := indexPage{Title: s.Title, Decls: s.entries()}
+	for _, mn := range adl.ModuleNames(s.allmod) {
+		pg.Modules = append(pg.Modules, moduleEntry{
+			Name:    mn,
+			Href:    ModulePage(mn),
+			Summary: summary(s.allmod[mn].Doc()),
+		})
+	}
+	return pg
+}
+
+func (s *Site) searchJs() ([]byte, error) {
+	by, err := json.Marshal(s.entries())
+	if err != nil {
+		return nil, err
+	}
+	return []byte("var adlIndex = " + string(by) + ";\n" + searchScript), nil
+}
+
+type modulePage struct {
+	Title       string
+	Name        string
+	Doc         string
+	Imports     []template.HTML
+	Annotations []annoRow
+	Decls       []declSection
+}
+
+type annoRow struct {
+	Key   template.HTML
+	Value string
+}
+
+type declSection struct {
+	Name        string
+	Kind        string
+	TypeParams  string
+	Doc         string
+	Annotations []annoRow
+	// Type is set for type and newtype decls
+	Type    template.HTML
+	Default string
+	Fields  []fieldRow
+	// FieldsTitle is Fields or Branches
+	FieldsTitle string
+}
+
+type fieldRow struct {
+	Name           string
+	SerializedName string
+	Type           template.HTML
+	Default        string
+	Doc            string
+	Annotations    []annoRow
+}
+
+func (s *Site) module(mn string) modulePage {
+	mod := s.allmod[mn]
+	pg := modulePage{Title: s.Title, Name: mn, Doc: strings.TrimSpace(mod.Doc())}
+	for _, imp := range mod.Imports {
+		pg.Imports = append(pg.Imports, s.importLink(imp))
+	}
+	pg.Annotations = s.annotations(mn, mod.Annotations)
+	for _, dn := range mod.DeclNames() {
+		decl := mod.Decls[dn]
+		sec := declSection{
+			Name:        dn,
+			Kind:        decl.Type.Kind(),
+			Doc:         strings.TrimSpace(decl.Doc()),
+			Annotations: s.annotations(mn, decl.Annotations),
+		}
+		if tps := decl.TypeParams(); len(tps) != 0 {
+			sec.TypeParams = "<" + strings.Join(tps, ",") + ">"
+		}
+		var fields []adl.Field
+		switch {
+		case decl.Type.Struct != nil:
+			fields, sec.FieldsTitle = decl.Type.Struct.Field, "Fields"
+		case decl.Type.Union != nil:
+			fields, sec.FieldsTitle = decl.Type.Union.Field, "Branches"
+		case decl.Type.Type != nil:
+			sec.Type = s.typeLink(mn, decl.Type.Type.TypeExpr)
+		case decl.Type.Newtype != nil:
+			sec.Type = s.typeLink(mn, decl.Type.Newtype.TypeExpr)
+			sec.Default = maybeJson(decl.Type.Newtype.Default)
+		}
+		for _, f := range fields {
+			row := fieldRow{
+				Name:        f.Name,
+				Type:        s.typeLink(mn, f.TypeExpr),
+				Default:     maybeJson(f.Default),
+				Doc:         strings.TrimSpace(f.Doc()),
+				Annotations: s.annotations(mn, f.Annotations),
+			}
+			if f.SerializedName != "" && f.SerializedName != f.Name {
+				row.SerializedName = f.SerializedName
+			}
+			sec.Fields = append(sec.Fields, row)
+		}
+		pg.Decls = append(pg.Decls, sec)
+	}
+	return pg
+}
+
+// annotations renders the annotations other than Doc
+func (s *Site) annotations(from string, as adl.Annotations) []annoRow {
+	rows := []annoRow{}
+	for _, a := range as {
+		if a.Key == adl.DocAnno {
+			continue
+		}
+		rows = append(rows, annoRow{Key: s.refLink(from, a.Key), Value: jsonString(a.Val)})
+	}
+	return rows
+}
+
+func (s *Site) importLink(imp adl.Import) template.HTML {
+	switch {
+	case imp.ModuleName != nil:
+		mn := *imp.ModuleName
+		if _, ex := s.allmod[mn]; ex {
+			return template.HTML(fmt.Sprintf(`<a href="%s">%s.*</a>`, template.HTMLEscapeString(ModulePage(mn)), template.HTMLEscapeString(mn)))
+		}
+		return template.HTML(template.HTMLEscapeString(mn + ".*"))
+	case imp.ScopedName != nil:
+		return s.refLink("", *imp.ScopedName)
+	}
+	return ""
+}
+
+// refLink links to a decl, the name is qualified when it is from another module
+func (s *Site) refLink(from string, sn adl.ScopedName) template.HTML {
+	if sn.ModuleName == "" {
+		sn.ModuleName = from
+	}
+	text := sn.Name
+	if sn.ModuleName != from {
+		text = sn.String()
+	}
+	if _, _, ex := adl.Lookup(s.allmod, from, sn); !ex {
+		return template.HTML(template.HTMLEscapeString(text))
+	}
+	return template.HTML(fmt.Sprintf(`<a href="%s#%s" title="%s">%s</a>`,
+		template.HTMLEscapeString(ModulePage(sn.ModuleName)),
+		template.HTMLEscapeString(sn.Name),
+		template.HTMLEscapeString(sn.String()),
+		template.HTMLEscapeString(text)))
+}
+
+// typeLink renders a type expression with its references linked
+func (s *Site) typeLink(from string, te adl.TypeExpr) template.HTML {
+	var buf strings.Builder
+	switch {
+	case te.TypeRef.Primitive != nil:
+		fmt.Fprintf(&buf, `<span class="prim">%s</span>`, template.HTMLEscapeString(*te.TypeRef.Primitive))
+	case te.TypeRef.TypeParam != nil:
+		fmt.Fprintf(&buf, `<span class="tparam">%s</span>`, template.HTMLEscapeString(*te.TypeRef.TypeParam))
+	case te.TypeRef.Reference != nil:
+		buf.WriteString(string(s.refLink(from, *te.TypeRef.Reference)))
+	}
+	if len(te.Parameters) != 0 {
+		buf.WriteString("&lt;")
+		for i, p := range te.Parameters {
+			if i != 0 {
+				buf.WriteString(",")
+			}
+			buf.WriteString(string(s.typeLink(from, p)))
+		}
+		buf.WriteString("&gt;")
+	}
+	return template.HTML(buf.String())
+}
+
+func maybeJson(m interface{}) string {
+	if v, ok := adl.Just(m); ok {
+		return jsonString(v)
+	}
+	return ""
+}
+
+func jsonString(v interface{}) string {
+	if v == nil {
+		return "null"
+	}
+	by, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(by)
+}
+
+var tmpl = template.Must(template.New("").Parse(`
+{{define "head"}}<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<link rel="stylesheet" href="style.css">
+<script src="search.js" defer></script>
+</head>
+<body>
+<header>
+<a href="index.html">Index</a>
+<input id="search" type="search" placeholder="Search decls" autocomplete="off">
+<ul id="results"></ul>
+</header>
+{{end}}
+
+{{define "annos"}}{{if .}}<table class="annos">
+<tr><th>Annotation</th><th>Value</th></tr>
+{{range .}}<tr><td>{{.Key}}</td><td><pre>{{.Value}}</pre></td></tr>
+{{end}}</table>
+{{end}}{{end}}
+
+{{define "index"}}{{template "head" .Title}}<main>
+<h1>{{.Title}}</h1>
+<h2>Modules</h2>
+<table class="modules">
+{{range .Modules}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{.Summary}}</td></tr>
+{{end}}</table>
+<h2>Index</h2>
+<table class="index">
+{{range .Decls}}<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td>{{.Kind}}</td><td>{{.Module}}</td><td>{{.Summary}}</td></tr>
+{{end}}</table>
+</main>
+</body>
+</html>
+{{end}}
+
+{{define "module"}}{{template "head" .Name}}<main>
+<h1>module {{.Name}}</h1>
+{{if .Doc}}<div class="doc">{{.Doc}}</div>
+{{end}}{{if .Imports}}<h2>Imports</h2>
+<ul class="imports">
+{{range .Imports}}<li>{{.}}</li>
+{{end}}</ul>
+{{end}}{{template "annos" .Annotations}}<h2>Decls</h2>
+<ul class="toc">
+{{range .Decls}}<li><a href="#{{.Name}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{range $decl := .Decls}}<section id="{{.Name}}">
+<h3><span class="kind">{{.Kind}}</span> {{.Name}}{{.TypeParams}}{{if .Type}} = {{.Type}}{{end}}</h3>
+{{if .Doc}}<div class="doc">{{.Doc}}</div>
+{{end}}{{if .Default}}<p>Default <code>{{.Default}}</code></p>
+{{end}}{{template "annos" .Annotations}}{{if .Fields}}<table class="fields">
+<tr><th>{{.FieldsTitle}}</th><th>Type</th><th>Default</th><th>Description</th></tr>
+{{range .Fields}}<tr id="{{$decl.Name}}.{{.Name}}"><td>{{.Name}}{{if .SerializedName}} <span class="sername">({{.SerializedName}})</span>{{end}}</td><td>{{.Type}}</td><td>{{if .Default}}<pre>{{.Default}}</pre>{{end}}</td><td>{{if .Doc}}<div class="doc">{{.Doc}}</div>{{end}}{{template "annos" .Annotations}}</td></tr>
+{{end}}</table>
+{{end}}</section>
+{{end}}</main>
+</body>
+</html>
+{{end}}
+`))
+
+const style = `body { font-family: sans-serif; margin: 0; }
+header { background: #333; color: #fff; padding: 0.5em 1em; position: sticky; top: 0; }
+header a { color: #fff; margin-right: 1em; }
+#results { position: absolute; background: #fff; list-style: none; margin: 0; padding: 0; box-shadow: 0 2px 4px #888; }
+#results li a { display: block; color: #333; padding: 0.2em 0.5em; }
+main { padding: 1em; }
+section { border-top: 1px solid #ddd; margin-top: 1em; }
+section:target { background: #ffd; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ddd; padding: 0.2em 0.5em; text-align: left; vertical-align: top; }
+pre { margin: 0; }
+.doc { white-space: pre-wrap; }
+.kind, .sername { color: #888; font-weight: normal; }
+.prim { color: #a31515; }
+.tparam { font-style: italic; }
+`
+
+const searchScript = `document.addEventListener("DOMContentLoaded", function () {
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  input.addEventListener("input", function () {
+    var q = input.value.toLowerCase();
+    results.innerHTML = "";
+    if (q === "") return;
+    adlIndex.filter(function (e) {
+      return (e.Module + "." + e.Name).toLowerCase().indexOf(q) !== -1 || e.Summary.toLowerCase().indexOf(q) !== -1;
+    }).slice(0, 20).forEach(function (e) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = e.Href;
+      a.textContent = e.Module + "." + e.Name + " (" + e.Kind + ")";
+      li.appendChild(a);
+      results.appendChild(li);
+    });
+  });
+});
+`
@@ -0,0 +1,84 @@
+package htmldoc
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestPages(t *testing.T) {
+	pages, err := New(adltest.Modules()).Pages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "search.js", "style.css", "helix.protoapp.requests.html", "common.html", "sys.types.html"} {
+		if _, ex := pages[name]; !ex {
+			t.Errorf("missing page %s", name)
+		}
+	}
+	for page, exps := range map[string][]string{
+		"helix.protoapp.requests.html": {
+			`<section id="Audit">`,
+			`<div class="doc">An audit log entry</div>`,
+			// cross module links are qualified
+			`<a href="common.html#Instant" title="common.Instant">common.Instant</a>`,
+			`<span class="prim">Nullable</span>&lt;<a href="helix.protoapp.requests.html#Audit" title="helix.protoapp.requests.Audit">Audit</a>&gt;`,
+			`<tr id="Audit.previous">`,
+			`<td>tags <span class="sername">(labels)</span></td>`,
+			// annotation values are rendered as json
+			`&#34;tableName&#34;: &#34;audit_log&#34;`,
+			`<h3><span class="kind">type</span> Login = <a href="common.http.html#Post"`,
+			`<li><a href="common.html">common.*</a></li>`,
+		},
+		"common.html": {
+			`<h3><span class="kind">newtype</span> LocalDate = <span class="prim">String</span></h3>`,
+			`<p>Default <code>&#34;1970-01-01&#34;</code></p>`,
+		},
+		"index.html": {
+			`<a href="helix.protoapp.requests.html#HelloReq">HelloReq</a></td><td>struct</td>`,
+			`<a href="sys.types.html">sys.types</a>`,
+		},
+		"search.js": {
+			`"Name":"LoginResult","Module":"helix.protoapp.requests","Kind":"union","Href":"helix.protoapp.requests.html#LoginResult"`,
+		},
+	} {
+		for _, exp := range exps {
+			if !strings.Contains(string(pages[page]), exp) {
+				t.Errorf("%s: expected %s", page, exp)
+			}
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	var allmod map[string]adl.Module
+	srv := httptest.NewServer(Handler("Test", func() map[string]adl.Module { return allmod }))
+	defer srv.Close()
+	get := func(path string) (int, string) {
+		res, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		by, _ := ioutil.ReadAll(res.Body)
+		return res.StatusCode, string(by)
+	}
+	if status, _ := get("/"); status != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before compile got %d", status)
+	}
+	allmod = adltest.Modules()
+	if status, body := get("/"); status != http.StatusOK || !strings.Contains(body, "<h1>Test</h1>") {
+		t.Errorf("index %d %s", status, body)
+	}
+	if status, body := get("/common.html"); status != http.StatusOK || !strings.Contains(body, `<section id="Instant">`) {
+		t.Errorf("module page %d %s", status, body)
+	}
+	if status, _ := get("/nothere.html"); status != http.StatusNotFound {
+		t.Errorf("expected 404 got %d", status)
+	}
+}
@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Instance of a decl, for generic decls one per distinct set of type args
+type Instance struct {
+	// Name is the target name, see InstanceName
+	Name string
+	SN   adl.ScopedName
+	Decl adl.Decl
+	Args []adl.TypeExpr
+	// Code is the generated code of the instance
+	Code bytes.Buffer
+}
+
+// FieldType substitutes the instance's type args into a type expression of its decl
+func (inst *Instance) FieldType(te adl.TypeExpr) adl.TypeExpr {
+	return Subst(Qualify(te, inst.SN.ModuleName), inst.Decl.TypeParams(), inst.Args)
+}
+
+// Instances queues the instances of the decls used by the generated code of a module, for targets without generics.
+// Decls from other modules are generated along with the module's.
+type Instances struct {
+	allmod map[string]adl.Module
+	module string
+	target string
+	byKey  map[string]*Instance
+	byName map[string]*Instance
+	queue  []*Instance
+}
+
+// NewInstances queues instances for the module, target names the target language in errors
+func NewInstances(allmod map[string]adl.Module, module, target string) *Instances {
+	return &Instances{
+		allmod: allmod,
+		module: module,
+		target: target,
+		byKey:  map[string]*Instance{},
+		byName: map[string]*Instance{},
+	}
+}
+
+// Need queues the instance of sn with args, args must be fully qualified, and returns its name
+func (is *Instances) Need(sn adl.ScopedName, args []adl.TypeExpr) (string, error) {
+	key := adl.TypeExpr{TypeRef: adl.TypeRef{Reference: &sn}, Parameters: args}.String()
+	if inst, ex := is.byKey[key]; ex {
+		return inst.Name, nil
+	}
+	decl, sn, ex := adl.Lookup(is.allmod, is.module, sn)
+	if !ex {
+		return "", fmt.Errorf("unknown decl '%s'", sn)
+	}
+	if len(decl.TypeParams()) != len(args) {
+		return "", fmt.Errorf("%s expects %d type params got %d", sn, len(decl.TypeParams()), len(args))
+	}
+	name := InstanceName(DeclName(is.allmod, is.module, sn), args)
+	if other, ex := is.byName[name]; ex {
+		return "", fmt.Errorf("%s name %s used by both %s and %s", is.target, name, other.SN, sn)
+	}
+	inst := &Instance{Name: name, SN: sn, Decl: decl, Args: args}
+	is.byKey[key] = inst
+	is.byName[name] = inst
+	is.queue = append(is.queue, inst)
+	return name, nil
+}
+
+// Generate calls gen for each queued instance, including those queued by gen, and returns them sorted by name
+func (is *Instances) Generate(gen func(inst *Instance) error) ([]*Instance, error) {
+	// the queue grows as instances reference other decls
+	for i := 0; i < len(is.queue); i++ {
+		if err := gen(is.queue[i]); err != nil {
+			return nil, err
+		}
+	}
+	insts := append([]*Instance{}, is.queue...)
+	sort.Slice(insts, func(i, j int) bool { return insts[i].Name < insts[j].Name })
+	return insts, nil
+}
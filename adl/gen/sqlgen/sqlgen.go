@@ -0,0 +1,432 @@
+// Package sqlgen generates SQL DDL for the structs annotated with common.db.DbTable.
+//
+// Fields map to columns, primitives to the dialect's column types, Nullable to NULL columns,
+// and vectors, maps and nested structs and unions to JSON columns.
+package sqlgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+// Well known annotations
+var (
+	DbTable      = adl.ScopedName{ModuleName: "common.db", Name: "DbTable"}
+	DbColumnName = adl.ScopedName{ModuleName: "common.db", Name: "DbColumnName"}
+	DbColumnType = adl.ScopedName{ModuleName: "common.db", Name: "DbColumnType"}
+)
+
+// Table is a struct annotated with DbTable
+type Table struct {
+	Name   string
+	Module string
+	Decl   adl.Decl
+	// Id adds an id text primary key column
+	Id      bool
+	Columns []Column
+	// Indexes and Unique are lists of column names
+	Indexes [][]string
+	Unique  [][]string
+}
+
+type Column struct {
+	Name  string
+	Field adl.Field
+	// Kind is an ADL primitive name, "Json" for structured values, or "" when Type is set from DbColumnType
+	Kind     string
+	Type     string
+	Nullable bool
+	// Default is the default value, nil if there is none
+	Default interface{}
+}
+
+// dbTable mirrors common.db.DbTable
+type dbTable struct {
+	TableName             string     `json:"tableName"`
+	WithIdPrimaryKey      bool       `json:"withIdPrimaryKey"`
+	Indexes               [][]string `json:"indexes"`
+	UniquenessConstraints [][]string `json:"uniquenessConstraints"`
+}
+
+// Tables returns the DbTable annotated structs of the module, or all modules when module is "", sorted by name
+func Tables(allmod map[string]adl.Module, module string) ([]Table, error) {
+	tables := []Table{}
+	for _, mn := range adl.ModuleNames(allmod) {
+		if module != "" && mn != module {
+			continue
+		}
+		mod := allmod[mn]
+		for _, name := range mod.DeclNames() {
+			decl := mod.Decls[name]
+			av, ok := decl.Annotations.Find(DbTable)
+			if !ok {
+				continue
+			}
+			if decl.Type.Struct == nil {
+				return nil, fmt.Errorf("%s.%s: DbTable annotation on a %s", mn, name, decl.Type.Kind())
+			}
+			if len(decl.TypeParams()) != 0 {
+				return nil, fmt.Errorf("%s.%s: DbTable annotation on a generic struct", mn, name)
+			}
+			tbl, err := table(allmod, mn, decl, av)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", mn, name, err)
+			}
+			tables = append(tables, tbl)
+		}
+	}
+	if module != "" {
+		if _, ex := allmod[module]; !ex {
+			return nil, fmt.Errorf("unknown module '%s'", module)
+		}
+	}
+	sort.SliceStable(tables, func(i, j int) bool { return tables[i].Name < tables[j].Name })
+	return tables, nil
+}
+
+func table(allmod map[string]adl.Module, module string, decl adl.Decl, av interface{}) (Table, error) {
+	var dt dbTable
+	if av != nil {
+		by, err := json.Marshal(av)
+		if err != nil {
+			return Table{}, err
+		}
+		if err := json.Unmarshal(by, &dt); err != nil {
+			return Table{}, fmt.Errorf("invalid DbTable annotation: %v", err)
+		}
+	}
+	tbl := Table{Name: dt.TableName, Module: module, Decl: decl, Id: dt.WithIdPrimaryKey}
+	if tbl.Name == "" {
+		tbl.Name = gen.SnakeName(decl.Name)
+	}
+	// field name to column name
+	cols := map[string]string{}
+	seen := map[string]bool{}
+	if tbl.Id {
+		seen["id"] = true
+	}
+	for _, f := range decl.Type.Struct.Field {
+		col := Column{Name: gen.SnakeName(f.Name), Field: f}
+		if v, ok := f.Annotations.Find(DbColumnName); ok {
+			if s, ok := v.(string); ok {
+				col.Name = s
+			}
+		}
+		if seen[col.Name] {
+			return tbl, fmt.Errorf("duplicate column '%s'", col.Name)
+		}
+		seen[col.Name] = true
+		if v, ok := f.Annotations.Find(DbColumnType); ok {
+			s, _ := v.(string)
+			col.Type = s
+			col.Nullable = isNullable(gen.Qualify(f.TypeExpr, module))
+		} else {
+			kind, nullable, err := columnKind(allmod, gen.Qualify(f.TypeExpr, module))
+			if err != nil {
+				return tbl, fmt.Errorf("field %s: %v", f.Name, err)
+			}
+			col.Kind, col.Nullable = kind, nullable
+		}
+		if d, ok := adl.Just(f.Default); ok {
+			col.Default = d
+		}
+		cols[f.Name] = col.Name
+		tbl.Columns = append(tbl.Columns, col)
+	}
+	names := func(what string, fss [][]string) ([][]string, error) {
+		ret := make([][]string, len(fss))
+		for i, fs := range fss {
+			for _, f := range fs {
+				cn, ok := cols[f]
+				if !ok {
+					if f == "id" && tbl.Id {
+						cn = "id"
+					} else {
+						return nil, fmt.Errorf("%s refers to unknown field '%s'", what, f)
+					}
+				}
+				ret[i] = append(ret[i], cn)
+			}
+		}
+		return ret, nil
+	}
+	var err error
+	if tbl.Indexes, err = names("index", dt.Indexes); err != nil {
+		return tbl, err
+	}
+	if tbl.Unique, err = names("uniqueness constraint", dt.UniquenessConstraints); err != nil {
+		return tbl, err
+	}
+	return tbl, nil
+}
+
+func isNullable(te adl.TypeExpr) bool {
+	return te.TypeRef.Primitive != nil && *te.TypeRef.Primitive == "Nullable"
+}
+
+// columnKind resolves a fully qualified type expression to a primitive or Json
+func columnKind(allmod map[string]adl.Module, te adl.TypeExpr) (string, bool, error) {
+	switch {
+	case te.TypeRef.Primitive != nil:
+		switch p := *te.TypeRef.Primitive; p {
+		case "Nullable":
+			if len(te.Parameters) != 1 {
+				return "", false, fmt.Errorf("Nullable expects one type param")
+			}
+			kind, nullable, err := columnKind(allmod, te.Parameters[0])
+			if nullable {
+				// Nullable<Nullable<T>> can't be represented by a single null
+				return "Json", true, err
+			}
+			return kind, true, err
+		case "Vector", "StringMap", "Json", "Void", "TypeToken":
+			return "Json", false, nil
+		default:
+			return p, false, nil
+		}
+	case te.TypeRef.TypeParam != nil:
+		return "", false, fmt.Errorf("unbound type param '%s'", *te.TypeRef.TypeParam)
+	case te.TypeRef.Reference != nil:
+		decl, sn, ex := adl.Lookup(allmod, "", *te.TypeRef.Reference)
+		if !ex {
+			return "", false, fmt.Errorf("unknown type '%s'", te.TypeRef.Reference)
+		}
+		switch {
+		case decl.Type.Type != nil:
+			return columnKind(allmod, gen.Subst(gen.Qualify(decl.Type.Type.TypeExpr, sn.ModuleName), decl.TypeParams(), te.Parameters))
+		case decl.Type.Newtype != nil:
+			return columnKind(allmod, gen.Subst(gen.Qualify(decl.Type.Newtype.TypeExpr, sn.ModuleName), decl.TypeParams(), te.Parameters))
+		case decl.Type.Union != nil && isEnum(decl):
+			// serialized as the branch name
+			return "String", false, nil
+		}
+		return "Json", false, nil
+	}
+	return "", false, fmt.Errorf("empty type expression")
+}
+
+// isEnum reports whether all the branches of a union are Void
+func isEnum(decl adl.Decl) bool {
+	for _, f := range decl.Type.Union.Field {
+		if f.TypeExpr.TypeRef.Primitive == nil || *f.TypeExpr.TypeRef.Primitive != "Void" {
+			return false
+		}
+	}
+	return len(decl.Type.Union.Field) != 0
+}
+
+type Dialect struct {
+	Name  string
+	types map[string]string
+	// literal for booleans
+	true_, false_ string
+}
+
+var Postgres = &Dialect{
+	Name: "postgres",
+	types: map[string]string{
+		"Bool":   "boolean",
+		"Int8":   "smallint",
+		"Int16":  "smallint",
+		"Int32":  "integer",
+		"Int64":  "bigint",
+		"Word8":  "smallint",
+		"Word16": "integer",
+		"Word32": "bigint",
+		"Word64": "numeric(20)",
+		"Float":  "real",
+		"Double": "double precision",
+		"String": "text",
+		"Bytes":  "bytea",
+		"Json":   "jsonb",
+	},
+	true_:  "true",
+	false_: "false",
+}
+
+var Sqlite = &Dialect{
+	Name: "sqlite",
+	types: map[string]string{
+		"Bool":   "integer",
+		"Int8":   "integer",
+		"Int16":  "integer",
+		"Int32":  "integer",
+		"Int64":  "integer",
+		"Word8":  "integer",
+		"Word16": "integer",
+		"Word32": "integer",
+		"Word64": "integer",
+		"Float":  "real",
+		"Double": "real",
+		"String": "text",
+		"Bytes":  "blob",
+		"Json":   "text",
+	},
+	true_:  "1",
+	false_: "0",
+}
+
+var Dialects = []*Dialect{Postgres, Sqlite}
+
+func DialectByName(name string) (*Dialect, error) {
+	for _, d := range Dialects {
+		if d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown sql dialect '%s', expected postgres or sqlite", name)
+}
+
+// ColumnType is the dialect's type for a column
+func (d *Dialect) ColumnType(col Column) string {
+	if col.Type != "" {
+		return col.Type
+	}
+	return d.types[col.Kind]
+}
+
+// literal renders a default value, "" if it can't be
+func (d *Dialect) literal(col Column) string {
+	if col.Default == nil || col.Type != "" {
+		return ""
+	}
+	if col.Kind == "Json" {
+		by, err := json.Marshal(col.Default)
+		if err != nil {
+			return ""
+		}
+		return quoteString(string(by))
+	}
+	switch v := col.Default.(type) {
+	case bool:
+		if v {
+			return d.true_
+		}
+		return d.false_
+	case string:
+		return quoteString(v)
+	case float64:
+		return fmt.Sprintf("%v", v)
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
+
+// DDL returns the create table and create index statements for the tables
+func (d *Dialect) DDL(tables []Table) string {
+	var buf bytes.Buffer
+	for i, tbl := range tables {
+		if i != 0 {
+			buf.WriteString("\n")
+		}
+		if doc := strings.TrimSpace(tbl.Decl.Doc()); doc != "" {
+			for _, line := range strings.Split(doc, "\n") {
+				fmt.Fprintf(&buf, "-- %s\n", strings.TrimSpace(line))
+			}
+		}
+		tn := Ident(tbl.Name)
+		fmt.Fprintf(&buf, "create table %s(\n", tn)
+		lines := []string{}
+		if tbl.Id {
+			lines = append(lines, "id text not null")
+		}
+		for _, col := range tbl.Columns {
+			line := Ident(col.Name) + " " + d.ColumnType(col)
+			if !col.Nullable {
+				line += " not null"
+			}
+			if lit := d.literal(col); lit != "" {
+				line += " default " + lit
+			}
+			lines = append(lines, line)
+		}
+		if tbl.Id {
+			lines = append(lines, "primary key(id)")
+		}
+		for j, con := range tbl.Unique {
+			lines = append(lines, fmt.Sprintf("constraint %s unique (%s)", Ident(fmt.Sprintf("%s_%d_con", tbl.Name, j+1)), idents(con)))
+		}
+		buf.WriteString("  " + strings.Join(lines, ",\n  ") + "\n);\n")
+		for j, idx := range tbl.Indexes {
+			fmt.Fprintf(&buf, "create index %s on %s(%s);\n", Ident(fmt.Sprintf("%s_%d_idx", tbl.Name, j+1)), tn, idents(idx))
+		}
+	}
+	return buf.String()
+}
+
+type Config struct {
+	// Module to generate, "" for all modules
+	Module  string
+	Dialect *Dialect
+}
+
+// Generate returns the DDL script for the tables of the module
+func Generate(allmod map[string]adl.Module, cfg Config) ([]byte, error) {
+	tables, err := Tables(allmod, cfg.Module)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	from := cfg.Module
+	if from == "" {
+		from = "all modules"
+	}
+	fmt.Fprintf(&buf, "-- Generated by tron-go adl gen sql from %s for %s. DO NOT EDIT.\n\n", from, cfg.Dialect.Name)
+	buf.WriteString(cfg.Dialect.DDL(tables))
+	return buf.Bytes(), nil
+}
+
+func idents(names []string) string {
+	qs := make([]string, len(names))
+	for i, n := range names {
+		qs[i] = Ident(n)
+	}
+	return strings.Join(qs, ", ")
+}
+
+// Ident quotes an identifier if it is a keyword or not a plain lower case name
+func Ident(name string) string {
+	plain := name != ""
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r == '_' || i > 0 && r >= '0' && r <= '9') {
+			plain = false
+			break
+		}
+	}
+	if plain && !reserved[name] {
+		return name
+	}
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// reserved words of postgres and sqlite
+var reserved = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		abort action add after all alter always analyze and any array as asc asymmetric attach autoincrement
+		before begin between both by cascade case cast check collate column commit conflict constraint create
+		cross current current_date current_role current_time current_timestamp current_user database default
+		deferrable deferred delete desc detach distinct do drop each else end escape except exclude exclusive
+		exists explain fail false fetch filter following for foreign from full generated glob group groups having
+		if ignore immediate in index indexed initially inner insert instead intersect into is isnull join key lateral
+		leading left like limit localtime localtimestamp match materialized natural no not nothing notnull null
+		nulls of offset on only or order others outer over partition placing plan pragma preceding primary query
+		raise range recursive references regexp reindex release rename replace restrict returning right rollback
+		row rows savepoint select session_user set similar some symmetric table temp temporary then ties to
+		trailing transaction trigger true unbounded union unique update user using vacuum values variadic
+		verbose view virtual when where window with without`) {
+		reserved[w] = true
+	}
+}
@@ -0,0 +1,109 @@
+package sqlgen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestTables(t *testing.T) {
+	tables, err := Tables(adltest.Modules(), "helix.protoapp.requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 2 || tables[0].Name != "audit_log" || tables[1].Name != "hello_req" {
+		t.Fatalf("unexpected tables %+v", tables)
+	}
+	kinds := map[string]string{}
+	nullable := map[string]bool{}
+	for _, col := range tables[0].Columns {
+		kinds[col.Name] = col.Kind
+		nullable[col.Name] = col.Nullable
+	}
+	for col, kind := range map[string]string{
+		"who":      "String",
+		"when":     "Int64",
+		"day":      "String",
+		"detail":   "Json",
+		"previous": "Json",
+		"pairs":    "Json",
+	} {
+		if kinds[col] != kind {
+			t.Errorf("%s: expected %s got %s", col, kind, kinds[col])
+		}
+	}
+	if !nullable["previous"] || nullable["who"] {
+		t.Errorf("unexpected nullability %v", nullable)
+	}
+	if !tables[1].Id || tables[0].Id {
+		t.Errorf("expected only hello_req to have an id")
+	}
+}
+
+func TestPostgres(t *testing.T) {
+	by, err := Generate(adltest.Modules(), Config{Module: "helix.protoapp.requests", Dialect: Postgres})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ddl := string(by)
+	for _, exp := range []string{
+		"create table audit_log(\n",
+		`  "when" bigint not null,`,
+		"  detail jsonb not null,",
+		"  previous jsonb,",
+		"  pairs jsonb not null default '[]',",
+		`  constraint audit_log_1_con unique (who, "when")`,
+		`create index audit_log_2_idx on audit_log("when", who);`,
+		"  id text not null,",
+		"  email text,",
+		"  age integer not null default 0,",
+		"  primary key(id)",
+		"create index hello_req_1_idx on hello_req(username);",
+	} {
+		if !strings.Contains(ddl, exp) {
+			t.Errorf("expected '%s' in\n%s", exp, ddl)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	allmod := adltest.Modules()
+	mod := allmod["helix.protoapp.requests"]
+	decl := mod.Decls["Audit"]
+	decl.Annotations = adl.Annotations{{
+		Key: DbTable,
+		Val: map[string]interface{}{"indexes": []interface{}{[]interface{}{"nothere"}}},
+	}}
+	mod.Decls["Audit"] = decl
+	for _, tc := range []struct {
+		module string
+		msg    string
+	}{
+		{"helix.protoapp.requests", "index refers to unknown field 'nothere'"},
+		{"nothere", "unknown module"},
+	} {
+		_, err := Generate(allmod, Config{Module: tc.module, Dialect: Sqlite})
+		if err == nil || !strings.Contains(err.Error(), tc.msg) {
+			t.Errorf("%s: expected '%s' got %v", tc.module, tc.msg, err)
+		}
+	}
+	if _, err := DialectByName("oracle"); err == nil {
+		t.Errorf("expected unknown dialect error")
+	}
+}
+
+func TestIdent(t *testing.T) {
+	for in, exp := range map[string]string{
+		"who":       "who",
+		"when":      `"when"`,
+		"createdAt": `"createdAt"`,
+		"a_1":       "a_1",
+		`x"y`:       `"x""y"`,
+	} {
+		if got := Ident(in); got != exp {
+			t.Errorf("%s: expected %s got %s", in, exp, got)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+//go:build cgo
+// +build cgo
+
+package sqlgen
+
+import (
+	"database/sql"
+	"sort"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestSqlite(t *testing.T) {
+	ddl, err := Generate(adltest.Modules(), Config{Dialect: Sqlite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(string(ddl)); err != nil {
+		t.Fatalf("%v\n%s", err, ddl)
+	}
+	rows, err := db.Query(`select type, name from sqlite_master where name not like 'sqlite_%' order by name`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for rows.Next() {
+		var typ, name string
+		if err := rows.Scan(&typ, &name); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, typ+" "+name)
+	}
+	rows.Close()
+	sort.Strings(got)
+	exp := "index audit_log_1_idx,index audit_log_2_idx,index hello_req_1_idx,table audit_log,table hello_req"
+	if strings.Join(got, ",") != exp {
+		t.Errorf("expected %s got %s", exp, strings.Join(got, ","))
+	}
+
+	// defaults are applied and nullable columns accept null
+	if _, err := db.Exec(`insert into audit_log(who, "when", day, detail, previous) values ('bob', 1, '2019-01-01', '{}', null)`); err != nil {
+		t.Fatal(err)
+	}
+	var pairs string
+	if err := db.QueryRow(`select pairs from audit_log where who = 'bob'`).Scan(&pairs); err != nil || pairs != "[]" {
+		t.Errorf("expected default pairs got '%s' %v", pairs, err)
+	}
+	for _, stmt := range []string{
+		// uniqueness constraint
+		`insert into audit_log(who, "when", day, detail) values ('bob', 1, '2019-01-02', '{}')`,
+		// not null
+		`insert into audit_log(who, "when", day) values ('alice', 1, '2019-01-02')`,
+		// primary key
+		`insert into hello_req(id, name, username, created_at, config) values (null, 'n', 'u', 1, '{}')`,
+	} {
+		if _, err := db.Exec(stmt); err == nil {
+			t.Errorf("expected constraint violation for %s", stmt)
+		}
+	}
+	if _, err := db.Exec(`insert into hello_req(id, name, username, created_at, config) values ('1', 'n', 'u', 1, '{}')`); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,696 @@
+// Code generated by tron-go adl gen ts. DO NOT EDIT.
+// ADL module helix.protoapp.requests
+
+/** HttpError is thrown by the Client for a non 2xx response */
+export class HttpError extends Error {
+  constructor(readonly status: number, message: string) {
+    super(message);
+  }
+}
+
+/**
+ * Client makes the helix.protoapp.requests http requests.
+ * Responses are validated before they are returned.
+ */
+export class Client {
+  constructor(readonly baseUrl: string, readonly fetchFn: typeof fetch = fetch) {}
+
+  /**
+   * docontype
+   *
+   * GET /debug/time
+   */
+  currentTime(): Promise<Instant> {
+    return this.call<Instant>("GET", "/debug/time", undefined, checkInstant);
+  }
+
+  /**
+   * doconnewtype
+   *
+   * POST /debug/dummy-exception
+   */
+  dummyException(req: string): Promise<Unit> {
+    return this.call<Unit>("POST", "/debug/dummy-exception", req, checkUnit);
+  }
+
+  /**
+   * Authenticate a user
+   *
+   * POST /login
+   */
+  login(req: LoginReq): Promise<LoginResult> {
+    return this.call<LoginResult>("POST", "/login", req, checkLoginResult);
+  }
+
+  /** POST /hello */
+  sayHello(req: HelloReq): Promise<Greeting> {
+    return this.call<Greeting>("POST", "/hello", req, checkGreeting);
+  }
+
+  private async call<T>(method: string, path: string, body: unknown, check: Check): Promise<T> {
+    const init: RequestInit = { method, headers: { Accept: "application/json" } };
+    if (body !== undefined) {
+      init.headers = { Accept: "application/json", "Content-Type": "application/json" };
+      init.body = JSON.stringify(body);
+    }
+    const res = await this.fetchFn(this.baseUrl.replace(/\/+$/, "") + path, init);
+    const text = await res.text();
+    if (!res.ok) {
+      throw new HttpError(res.status, text.trim());
+    }
+    const v: unknown = JSON.parse(text);
+    const errs: string[] = [];
+    check(v, "", errs);
+    if (errs.length !== 0) {
+      throw new Error("invalid response: " + errs.join(", "));
+    }
+    return v as T;
+  }
+}
+
+export interface A {
+  a: string;
+}
+
+/** makeA fills in the fields with defaults */
+export function makeA(input: { a: string }): A {
+  return {
+    a: input.a,
+  };
+}
+
+const checkAFields: [string, Check, boolean][] = [
+  ["a", (v, path, errs) => checkString(v, path, errs), false],
+];
+
+function checkA(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkAFields);
+}
+
+/** validateA returns the reasons v isn't a valid A */
+export function validateA(v: unknown): string[] {
+  const errs: string[] = [];
+  checkA(v, "", errs);
+  return errs;
+}
+
+export function isA(v: unknown): v is A {
+  return validateA(v).length === 0;
+}
+
+/** An audit log entry */
+export interface Audit {
+  who: string;
+  when: Instant;
+  day: LocalDate;
+  detail: unknown;
+  previous: Audit | null;
+  pairs: MapStringInt64;
+}
+
+/** makeAudit fills in the fields with defaults */
+export function makeAudit(input: { who: string; when: Instant; day: LocalDate; detail: unknown; previous?: Audit | null; pairs?: MapStringInt64 }): Audit {
+  return {
+    who: input.who,
+    when: input.when,
+    day: input.day,
+    detail: input.detail,
+    previous: input.previous === undefined ? (null as Audit | null) : input.previous,
+    pairs: input.pairs === undefined ? ([] as MapStringInt64) : input.pairs,
+  };
+}
+
+const checkAuditFields: [string, Check, boolean][] = [
+  ["who", (v, path, errs) => checkString(v, path, errs), false],
+  ["when", (v, path, errs) => checkInstant(v, path, errs), false],
+  ["day", (v, path, errs) => checkLocalDate(v, path, errs), false],
+  ["detail", (v, path, errs) => checkJson(v, path, errs), false],
+  ["previous", (v, path, errs) => checkNullable(checkAudit)(v, path, errs), true],
+  ["pairs", (v, path, errs) => checkMapStringInt64(v, path, errs), true],
+];
+
+function checkAudit(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkAuditFields);
+}
+
+/** validateAudit returns the reasons v isn't a valid Audit */
+export function validateAudit(v: unknown): string[] {
+  const errs: string[] = [];
+  checkAudit(v, "", errs);
+  return errs;
+}
+
+export function isAudit(v: unknown): v is Audit {
+  return validateAudit(v).length === 0;
+}
+
+export interface B {
+  b: number;
+}
+
+/** makeB fills in the fields with defaults */
+export function makeB(input: { b: number }): B {
+  return {
+    b: input.b,
+  };
+}
+
+const checkBFields: [string, Check, boolean][] = [
+  ["b", (v, path, errs) => checkInt(-2147483648, 2147483647)(v, path, errs), false],
+];
+
+function checkB(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkBFields);
+}
+
+/** validateB returns the reasons v isn't a valid B */
+export function validateB(v: unknown): string[] {
+  const errs: string[] = [];
+  checkB(v, "", errs);
+  return errs;
+}
+
+export function isB(v: unknown): v is B {
+  return validateB(v).length === 0;
+}
+
+export interface Greeting {
+  message: string;
+  count: number;
+  extras: { [key: string]: number };
+}
+
+/** makeGreeting fills in the fields with defaults */
+export function makeGreeting(input: { message: string; count?: number; extras?: { [key: string]: number } }): Greeting {
+  return {
+    message: input.message,
+    count: input.count === undefined ? 1 : input.count,
+    extras: input.extras === undefined ? {} : input.extras,
+  };
+}
+
+const checkGreetingFields: [string, Check, boolean][] = [
+  ["message", (v, path, errs) => checkString(v, path, errs), false],
+  ["count", (v, path, errs) => checkInt(-2147483648, 2147483647)(v, path, errs), true],
+  ["extras", (v, path, errs) => checkStringMap(checkNumber)(v, path, errs), true],
+];
+
+function checkGreeting(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkGreetingFields);
+}
+
+/** validateGreeting returns the reasons v isn't a valid Greeting */
+export function validateGreeting(v: unknown): string[] {
+  const errs: string[] = [];
+  checkGreeting(v, "", errs);
+  return errs;
+}
+
+export function isGreeting(v: unknown): v is Greeting {
+  return validateGreeting(v).length === 0;
+}
+
+/** doconstr */
+export interface HelloReq {
+  name: string;
+  /** Unique login name */
+  username: string;
+  email: string | null;
+  age: number;
+  createdAt: Instant;
+  config: MyConfig;
+  labels: string[];
+}
+
+/** makeHelloReq fills in the fields with defaults */
+export function makeHelloReq(input: { name: string; username: string; email?: string | null; age?: number; createdAt: Instant; config: MyConfig; labels?: string[] }): HelloReq {
+  return {
+    name: input.name,
+    username: input.username,
+    email: input.email === undefined ? null : input.email,
+    age: input.age === undefined ? 0 : input.age,
+    createdAt: input.createdAt,
+    config: input.config,
+    labels: input.labels === undefined ? [] : input.labels,
+  };
+}
+
+const checkHelloReqFields: [string, Check, boolean][] = [
+  ["name", (v, path, errs) => checkString(v, path, errs), false],
+  ["username", (v, path, errs) => checkString(v, path, errs), false],
+  ["email", (v, path, errs) => checkNullable(checkString)(v, path, errs), true],
+  ["age", (v, path, errs) => checkInt(-2147483648, 2147483647)(v, path, errs), true],
+  ["createdAt", (v, path, errs) => checkInstant(v, path, errs), false],
+  ["config", (v, path, errs) => checkMyConfig(v, path, errs), false],
+  ["labels", (v, path, errs) => checkVector(checkString)(v, path, errs), true],
+];
+
+function checkHelloReq(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkHelloReqFields);
+}
+
+/** validateHelloReq returns the reasons v isn't a valid HelloReq */
+export function validateHelloReq(v: unknown): string[] {
+  const errs: string[] = [];
+  checkHelloReq(v, "", errs);
+  return errs;
+}
+
+export function isHelloReq(v: unknown): v is HelloReq {
+  return validateHelloReq(v).length === 0;
+}
+
+/** Milliseconds since the epoch */
+export type Instant = number & { readonly __brand: "Instant" };
+
+export function makeInstant(v: number): Instant {
+  return v as Instant;
+}
+
+function checkInstant(v: unknown, path: string, errs: string[]): void {
+  checkInt(Number.MIN_SAFE_INTEGER, Number.MAX_SAFE_INTEGER)(v, path, errs);
+}
+
+/** validateInstant returns the reasons v isn't a valid Instant */
+export function validateInstant(v: unknown): string[] {
+  const errs: string[] = [];
+  checkInstant(v, "", errs);
+  return errs;
+}
+
+export function isInstant(v: unknown): v is Instant {
+  return validateInstant(v).length === 0;
+}
+
+/** ADL helix.protoapp.requests.Literal<String> */
+export type LiteralString = { [key: string]: string };
+
+function checkLiteralString(v: unknown, path: string, errs: string[]): void {
+  checkStringMap(checkString)(v, path, errs);
+}
+
+/** validateLiteralString returns the reasons v isn't a valid LiteralString */
+export function validateLiteralString(v: unknown): string[] {
+  const errs: string[] = [];
+  checkLiteralString(v, "", errs);
+  return errs;
+}
+
+export function isLiteralString(v: unknown): v is LiteralString {
+  return validateLiteralString(v).length === 0;
+}
+
+/** A date in ISO 8601 format */
+export type LocalDate = string & { readonly __brand: "LocalDate" };
+
+export function makeLocalDate(v: string): LocalDate {
+  return v as LocalDate;
+}
+
+function checkLocalDate(v: unknown, path: string, errs: string[]): void {
+  checkString(v, path, errs);
+}
+
+/** validateLocalDate returns the reasons v isn't a valid LocalDate */
+export function validateLocalDate(v: unknown): string[] {
+  const errs: string[] = [];
+  checkLocalDate(v, "", errs);
+  return errs;
+}
+
+export function isLocalDate(v: unknown): v is LocalDate {
+  return validateLocalDate(v).length === 0;
+}
+
+export interface LoginReq {
+  username: string;
+  password: string;
+  otp: string | null;
+}
+
+/** makeLoginReq fills in the fields with defaults */
+export function makeLoginReq(input: { username: string; password: string; otp?: string | null }): LoginReq {
+  return {
+    username: input.username,
+    password: input.password,
+    otp: input.otp === undefined ? null : input.otp,
+  };
+}
+
+const checkLoginReqFields: [string, Check, boolean][] = [
+  ["username", (v, path, errs) => checkString(v, path, errs), false],
+  ["password", (v, path, errs) => checkString(v, path, errs), false],
+  ["otp", (v, path, errs) => checkNullable(checkString)(v, path, errs), true],
+];
+
+function checkLoginReq(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkLoginReqFields);
+}
+
+/** validateLoginReq returns the reasons v isn't a valid LoginReq */
+export function validateLoginReq(v: unknown): string[] {
+  const errs: string[] = [];
+  checkLoginReq(v, "", errs);
+  return errs;
+}
+
+export function isLoginReq(v: unknown): v is LoginReq {
+  return validateLoginReq(v).length === 0;
+}
+
+/** ADL helix.protoapp.requests.LoginResp<common.LocalDate> */
+export type LoginRespLocalDate =
+  | { accessToken: LocalDate };
+
+const checkLoginRespLocalDateBranches: [string, Check | null][] = [
+  ["accessToken", (v, path, errs) => checkLocalDate(v, path, errs)],
+];
+
+function checkLoginRespLocalDate(v: unknown, path: string, errs: string[]): void {
+  checkUnion(v, path, errs, checkLoginRespLocalDateBranches);
+}
+
+/** validateLoginRespLocalDate returns the reasons v isn't a valid LoginRespLocalDate */
+export function validateLoginRespLocalDate(v: unknown): string[] {
+  const errs: string[] = [];
+  checkLoginRespLocalDate(v, "", errs);
+  return errs;
+}
+
+export function isLoginRespLocalDate(v: unknown): v is LoginRespLocalDate {
+  return validateLoginRespLocalDate(v).length === 0;
+}
+
+/** The outcome of a login attempt */
+export type LoginResult =
+  | { accessToken: string }
+  | "invalidCredentials"
+  | { locked: LoginRespLocalDate };
+
+const checkLoginResultBranches: [string, Check | null][] = [
+  ["accessToken", (v, path, errs) => checkString(v, path, errs)],
+  ["invalidCredentials", null],
+  ["locked", (v, path, errs) => checkLoginRespLocalDate(v, path, errs)],
+];
+
+function checkLoginResult(v: unknown, path: string, errs: string[]): void {
+  checkUnion(v, path, errs, checkLoginResultBranches);
+}
+
+/** validateLoginResult returns the reasons v isn't a valid LoginResult */
+export function validateLoginResult(v: unknown): string[] {
+  const errs: string[] = [];
+  checkLoginResult(v, "", errs);
+  return errs;
+}
+
+export function isLoginResult(v: unknown): v is LoginResult {
+  return validateLoginResult(v).length === 0;
+}
+
+/** ADL sys.types.Map<String,Int64> */
+export type MapStringInt64 = PairStringInt64[];
+
+function checkMapStringInt64(v: unknown, path: string, errs: string[]): void {
+  checkVector(checkPairStringInt64)(v, path, errs);
+}
+
+/** validateMapStringInt64 returns the reasons v isn't a valid MapStringInt64 */
+export function validateMapStringInt64(v: unknown): string[] {
+  const errs: string[] = [];
+  checkMapStringInt64(v, "", errs);
+  return errs;
+}
+
+export function isMapStringInt64(v: unknown): v is MapStringInt64 {
+  return validateMapStringInt64(v).length === 0;
+}
+
+export type MyConfig =
+  | { a: A }
+  | { b: B };
+
+const checkMyConfigBranches: [string, Check | null][] = [
+  ["a", (v, path, errs) => checkA(v, path, errs)],
+  ["b", (v, path, errs) => checkB(v, path, errs)],
+];
+
+function checkMyConfig(v: unknown, path: string, errs: string[]): void {
+  checkUnion(v, path, errs, checkMyConfigBranches);
+}
+
+/** validateMyConfig returns the reasons v isn't a valid MyConfig */
+export function validateMyConfig(v: unknown): string[] {
+  const errs: string[] = [];
+  checkMyConfig(v, "", errs);
+  return errs;
+}
+
+export function isMyConfig(v: unknown): v is MyConfig {
+  return validateMyConfig(v).length === 0;
+}
+
+export type MyConfigMap = { [key: string]: MyConfig };
+
+function checkMyConfigMap(v: unknown, path: string, errs: string[]): void {
+  checkStringMap(checkMyConfig)(v, path, errs);
+}
+
+/** validateMyConfigMap returns the reasons v isn't a valid MyConfigMap */
+export function validateMyConfigMap(v: unknown): string[] {
+  const errs: string[] = [];
+  checkMyConfigMap(v, "", errs);
+  return errs;
+}
+
+export function isMyConfigMap(v: unknown): v is MyConfigMap {
+  return validateMyConfigMap(v).length === 0;
+}
+
+/** ADL sys.types.Pair<String,Int64> */
+export interface PairStringInt64 {
+  v1: string;
+  v2: number;
+}
+
+/** makePairStringInt64 fills in the fields with defaults */
+export function makePairStringInt64(input: { v1: string; v2: number }): PairStringInt64 {
+  return {
+    v1: input.v1,
+    v2: input.v2,
+  };
+}
+
+const checkPairStringInt64Fields: [string, Check, boolean][] = [
+  ["v1", (v, path, errs) => checkString(v, path, errs), false],
+  ["v2", (v, path, errs) => checkInt(Number.MIN_SAFE_INTEGER, Number.MAX_SAFE_INTEGER)(v, path, errs), false],
+];
+
+function checkPairStringInt64(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkPairStringInt64Fields);
+}
+
+/** validatePairStringInt64 returns the reasons v isn't a valid PairStringInt64 */
+export function validatePairStringInt64(v: unknown): string[] {
+  const errs: string[] = [];
+  checkPairStringInt64(v, "", errs);
+  return errs;
+}
+
+export function isPairStringInt64(v: unknown): v is PairStringInt64 {
+  return validatePairStringInt64(v).length === 0;
+}
+
+export interface SA {
+  a: string;
+}
+
+/** makeSA fills in the fields with defaults */
+export function makeSA(input: { a: string }): SA {
+  return {
+    a: input.a,
+  };
+}
+
+const checkSAFields: [string, Check, boolean][] = [
+  ["a", (v, path, errs) => checkString(v, path, errs), false],
+];
+
+function checkSA(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkSAFields);
+}
+
+/** validateSA returns the reasons v isn't a valid SA */
+export function validateSA(v: unknown): string[] {
+  const errs: string[] = [];
+  checkSA(v, "", errs);
+  return errs;
+}
+
+export function isSA(v: unknown): v is SA {
+  return validateSA(v).length === 0;
+}
+
+export type StrLiteral = LiteralString;
+
+function checkStrLiteral(v: unknown, path: string, errs: string[]): void {
+  checkLiteralString(v, path, errs);
+}
+
+/** validateStrLiteral returns the reasons v isn't a valid StrLiteral */
+export function validateStrLiteral(v: unknown): string[] {
+  const errs: string[] = [];
+  checkStrLiteral(v, "", errs);
+  return errs;
+}
+
+export function isStrLiteral(v: unknown): v is StrLiteral {
+  return validateStrLiteral(v).length === 0;
+}
+
+/** A type with a single value */
+export interface Unit {}
+
+/** makeUnit fills in the fields with defaults */
+export function makeUnit(input: {}): Unit {
+  return {};
+}
+
+const checkUnitFields: [string, Check, boolean][] = [];
+
+function checkUnit(v: unknown, path: string, errs: string[]): void {
+  checkStruct(v, path, errs, checkUnitFields);
+}
+
+/** validateUnit returns the reasons v isn't a valid Unit */
+export function validateUnit(v: unknown): string[] {
+  const errs: string[] = [];
+  checkUnit(v, "", errs);
+  return errs;
+}
+
+export function isUnit(v: unknown): v is Unit {
+  return validateUnit(v).length === 0;
+}
+
+// validation runtime
+
+type Check = (v: unknown, path: string, errs: string[]) => void;
+
+function fail(path: string, errs: string[], msg: string): void {
+  errs.push(path === "" ? msg : path + ": " + msg);
+}
+
+function sub(path: string, name: string): string {
+  return path === "" ? name : path + "." + name;
+}
+
+function isObject(v: unknown): v is { [key: string]: unknown } {
+  return typeof v === "object" && v !== null && !Array.isArray(v);
+}
+
+function checkNull(v: unknown, path: string, errs: string[]): void {
+  if (v !== null) fail(path, errs, "expected null");
+}
+
+function checkBool(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "boolean") fail(path, errs, "expected boolean");
+}
+
+function checkString(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "string") fail(path, errs, "expected string");
+}
+
+function checkNumber(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "number" || !isFinite(v)) fail(path, errs, "expected number");
+}
+
+function checkJson(v: unknown, path: string, errs: string[]): void {
+  if (v === undefined) fail(path, errs, "expected json value");
+}
+
+function checkInt(min: number, max: number): Check {
+  return (v, path, errs) => {
+    if (typeof v !== "number" || Math.floor(v) !== v || v < min || v > max) {
+      fail(path, errs, "expected integer between " + min + " and " + max);
+    }
+  };
+}
+
+function checkVector(item: Check): Check {
+  return (v, path, errs) => {
+    if (!Array.isArray(v)) {
+      fail(path, errs, "expected array");
+      return;
+    }
+    v.forEach((x, i) => item(x, sub(path, String(i)), errs));
+  };
+}
+
+function checkStringMap(item: Check): Check {
+  return (v, path, errs) => {
+    if (!isObject(v)) {
+      fail(path, errs, "expected object");
+      return;
+    }
+    const obj = v;
+    Object.keys(obj).forEach((k) => item(obj[k], sub(path, k), errs));
+  };
+}
+
+function checkNullable(item: Check): Check {
+  return (v, path, errs) => {
+    if (v !== null) item(v, path, errs);
+  };
+}
+
+function checkStruct(v: unknown, path: string, errs: string[], fields: [string, Check, boolean][]): void {
+  if (!isObject(v)) {
+    fail(path, errs, "expected object");
+    return;
+  }
+  const obj = v;
+  const known: { [key: string]: boolean } = {};
+  fields.forEach(([name, check, optional]) => {
+    known[name] = true;
+    if (!(name in obj)) {
+      if (!optional) fail(sub(path, name), errs, "missing required field");
+      return;
+    }
+    check(obj[name], sub(path, name), errs);
+  });
+  Object.keys(obj).forEach((k) => {
+    if (!known[k]) fail(sub(path, k), errs, "unknown field");
+  });
+}
+
+function checkUnion(v: unknown, path: string, errs: string[], branches: [string, Check | null][]): void {
+  let name: string;
+  let value: unknown = null;
+  if (typeof v === "string") {
+    name = v;
+  } else if (isObject(v)) {
+    const keys = Object.keys(v);
+    if (keys.length !== 1) {
+      fail(path, errs, "union must have exactly one branch, got " + keys.length);
+      return;
+    }
+    name = keys[0];
+    value = v[name];
+  } else {
+    fail(path, errs, "expected union object or branch name");
+    return;
+  }
+  for (const [bname, check] of branches) {
+    if (bname !== name) continue;
+    if (check === null) {
+      if (typeof v !== "string") fail(sub(path, name), errs, "expected the branch name string");
+    } else if (typeof v === "string") {
+      fail(sub(path, name), errs, "expected union object");
+    } else {
+      check(value, sub(path, name), errs);
+    }
+    return;
+  }
+  fail(sub(path, name), errs, "unknown union branch");
+}
@@ -0,0 +1,565 @@
+// Package tsgen generates TypeScript types and runtime JSON validators from a resolved ADL module.
+//
+// The generated types describe the ADL JSON payloads as written by the Go generated code, and use the same names.
+// Generic decls are instantiated for each use, ie LoginResp<LocalDate> becomes LoginRespLocalDate,
+// and decls from other modules are generated into the same file as they are referenced.
+//
+// Structs are interfaces, with a make function applying the field defaults.
+// Unions are discriminated by their single key, or are the branch name string for Void branches.
+// Newtypes are branded so they can't be mixed up with their underlying type.
+// Each type has validate and is functions that check an unknown JSON value.
+package tsgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+type Config struct {
+	// The ADL module to generate
+	Module string
+	// Http generates a Client for the common.http requests of the module
+	Http bool
+}
+
+type generator struct {
+	allmod    map[string]adl.Module
+	cfg       Config
+	insts     *gen.Instances
+	endpoints map[string]bool
+}
+
+// Generate returns the TypeScript source for the module
+func Generate(allmod map[string]adl.Module, cfg Config) ([]byte, error) {
+	mod, ex := allmod[cfg.Module]
+	if !ex {
+		return nil, fmt.Errorf("unknown module '%s'", cfg.Module)
+	}
+	g := &generator{
+		allmod:    allmod,
+		cfg:       cfg,
+		insts:     gen.NewInstances(allmod, cfg.Module, "typescript"),
+		endpoints: map[string]bool{},
+	}
+	var eps []gen.Endpoint
+	if cfg.Http {
+		var err error
+		eps, err = gen.Endpoints(allmod, cfg.Module)
+		if err != nil {
+			return nil, err
+		}
+		for _, ep := range eps {
+			g.endpoints[ep.Name] = true
+		}
+	}
+	for _, name := range mod.DeclNames() {
+		if len(mod.Decls[name].TypeParams()) != 0 || g.endpoints[name] {
+			continue
+		}
+		if _, err := g.insts.Need(adl.ScopedName{ModuleName: cfg.Module, Name: name}, nil); err != nil {
+			return nil, err
+		}
+	}
+	var httpCode bytes.Buffer
+	if cfg.Http && len(eps) != 0 {
+		if err := g.genHttp(&httpCode, eps); err != nil {
+			return nil, err
+		}
+	}
+	insts, err := g.insts.Generate(g.genInstance)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tron-go adl gen ts. DO NOT EDIT.\n")
+	fmt.Fprintf(&out, "// ADL module %s\n\n", cfg.Module)
+	out.Write(httpCode.Bytes())
+	for _, inst := range insts {
+		out.Write(inst.Code.Bytes())
+	}
+	out.WriteString(runtime)
+	return out.Bytes(), nil
+}
+
+// typeOf returns the TypeScript type, and the validator expression, of a fully qualified type expression
+func (g *generator) typeOf(te adl.TypeExpr) (string, string, error) {
+	param := func(i int) (string, string, error) {
+		if len(te.Parameters) <= i {
+			return "", "", fmt.Errorf("%s missing type param", te)
+		}
+		return g.typeOf(te.Parameters[i])
+	}
+	switch {
+	case te.TypeRef.Primitive != nil:
+		switch p := *te.TypeRef.Primitive; p {
+		case "Void", "TypeToken":
+			return "null", "checkNull", nil
+		case "Bool":
+			return "boolean", "checkBool", nil
+		case "Int8":
+			return "number", "checkInt(-128, 127)", nil
+		case "Int16":
+			return "number", "checkInt(-32768, 32767)", nil
+		case "Int32":
+			return "number", "checkInt(-2147483648, 2147483647)", nil
+		case "Word8":
+			return "number", "checkInt(0, 255)", nil
+		case "Word16":
+			return "number", "checkInt(0, 65535)", nil
+		case "Word32":
+			return "number", "checkInt(0, 4294967295)", nil
+		case "Int64":
+			// JSON numbers beyond 2^53 lose precision in javascript
+			return "number", "checkInt(Number.MIN_SAFE_INTEGER, Number.MAX_SAFE_INTEGER)", nil
+		case "Word64":
+			return "number", "checkInt(0, Number.MAX_SAFE_INTEGER)", nil
+		case "Float", "Double":
+			return "number", "checkNumber", nil
+		case "String":
+			return "string", "checkString", nil
+		case "Bytes":
+			// base64
+			return "string", "checkString", nil
+		case "Json":
+			return "unknown", "checkJson", nil
+		case "Vector":
+			t, c, err := param(0)
+			if strings.ContainsAny(t, " |") {
+				t = "(" + t + ")"
+			}
+			return t + "[]", "checkVector(" + c + ")", err
+		case "StringMap":
+			t, c, err := param(0)
+			return "{ [key: string]: " + t + " }", "checkStringMap(" + c + ")", err
+		case "Nullable":
+			t, c, err := param(0)
+			return t + " | null", "checkNullable(" + c + ")", err
+		default:
+			return "", "", fmt.Errorf("unknown primitive '%s'", p)
+		}
+	case te.TypeRef.TypeParam != nil:
+		return "", "", fmt.Errorf("unbound type param '%s'", *te.TypeRef.TypeParam)
+	case te.TypeRef.Reference != nil:
+		name, err := g.insts.Need(*te.TypeRef.Reference, te.Parameters)
+		return name, "check" + name, err
+	}
+	return "", "", fmt.Errorf("empty type expression")
+}
+
+func writeDoc(buf *bytes.Buffer, indent, doc string) {
+	doc = strings.Replace(strings.TrimSpace(doc), "*/", "*\\/", -1)
+	if doc == "" {
+		return
+	}
+	lines := strings.Split(doc, "\n")
+	if len(lines) == 1 {
+		fmt.Fprintf(buf, "%s/** %s */\n", indent, strings.TrimSpace(lines[0]))
+		return
+	}
+	fmt.Fprintf(buf, "%s/**\n", indent)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "%s", strings.TrimRight(indent+" * "+strings.TrimSpace(line), " ")+"\n")
+	}
+	fmt.Fprintf(buf, "%s */\n", indent)
+}
+
+func (g *generator) genInstance(inst *gen.Instance) error {
+	buf := &inst.Code
+	decl := inst.Decl
+	doc := strings.TrimSpace(decl.Doc())
+	if inst.Name != gen.ExportName(decl.Name) || len(inst.Args) != 0 {
+		if doc != "" {
+			doc += "\n\n"
+		}
+		doc += "ADL " + adl.TypeExpr{TypeRef: adl.TypeRef{Reference: &inst.SN}, Parameters: inst.Args}.String()
+	}
+	writeDoc(buf, "", doc)
+	var check string
+	switch {
+	case decl.Type.Struct != nil:
+		if err := g.genStruct(inst); err != nil {
+			return err
+		}
+		check = "checkStruct(v, path, errs, check" + inst.Name + "Fields)"
+	case decl.Type.Union != nil:
+		if err := g.genUnion(inst); err != nil {
+			return err
+		}
+		check = "checkUnion(v, path, errs, check" + inst.Name + "Branches)"
+	case decl.Type.Type != nil:
+		t, c, err := g.typeOf(inst.FieldType(decl.Type.Type.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.SN, err)
+		}
+		fmt.Fprintf(buf, "export type %s = %s;\n\n", inst.Name, t)
+		check = c + "(v, path, errs)"
+	case decl.Type.Newtype != nil:
+		t, c, err := g.typeOf(inst.FieldType(decl.Type.Newtype.TypeExpr))
+		if err != nil {
+			return fmt.Errorf("%s: %v", inst.SN, err)
+		}
+		if strings.Contains(t, " ") {
+			t = "(" + t + ")"
+		}
+		fmt.Fprintf(buf, "export type %s = %s & { readonly __brand: %q };\n\n", inst.Name, t, inst.Name)
+		fmt.Fprintf(buf, "export function make%s(v: %s): %s {\n  return v as %s;\n}\n\n", inst.Name, t, inst.Name, inst.Name)
+		check = c + "(v, path, errs)"
+	default:
+		return fmt.Errorf("%s: empty decl", inst.SN)
+	}
+	fmt.Fprintf(buf, "function check%s(v: unknown, path: string, errs: string[]): void {\n  %s;\n}\n\n", inst.Name, check)
+	fmt.Fprintf(buf, "/** validate%s returns the reasons v isn't a valid %s */\n", inst.Name, inst.Name)
+	fmt.Fprintf(buf, "export function validate%s(v: unknown): string[] {\n", inst.Name)
+	fmt.Fprintf(buf, "  const errs: string[] = [];\n  check%s(v, \"\", errs);\n  return errs;\n}\n\n", inst.Name)
+	fmt.Fprintf(buf, "export function is%s(v: unknown): v is %s {\n  return validate%s(v).length === 0;\n}\n\n", inst.Name, inst.Name, inst.Name)
+	return nil
+}
+
+var identRe = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// property returns the property name for a declaration and its accessor
+func property(name string) (string, string) {
+	if identRe.MatchString(name) {
+		return name, "." + name
+	}
+	q, _ := json.Marshal(name)
+	return string(q), "[" + string(q) + "]"
+}
+
+// hasReference reports whether a type expression refers to a decl, its literals then need a cast
+func hasReference(te adl.TypeExpr) bool {
+	if te.TypeRef.Reference != nil {
+		return true
+	}
+	for _, p := range te.Parameters {
+		if hasReference(p) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) genStruct(inst *gen.Instance) error {
+	buf := &inst.Code
+	fields := inst.Decl.Type.Struct.Field
+	type field struct {
+		prop, access, t, check, def string
+		doc                         string
+	}
+	fs := make([]field, len(fields))
+	for i, f := range fields {
+		te := inst.FieldType(f.TypeExpr)
+		t, c, err := g.typeOf(te)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", inst.SN, f.Name, err)
+		}
+		fs[i] = field{t: t, check: c, doc: f.Doc()}
+		fs[i].prop, fs[i].access = property(serializedName(f))
+		if d, ok := adl.Just(f.Default); ok {
+			by, err := json.Marshal(d)
+			if err != nil {
+				return fmt.Errorf("%s.%s default: %v", inst.SN, f.Name, err)
+			}
+			fs[i].def = string(by)
+			if hasReference(te) {
+				fs[i].def = "(" + fs[i].def + " as " + t + ")"
+			}
+		}
+	}
+	if len(fs) == 0 {
+		fmt.Fprintf(buf, "export interface %s {}\n\n", inst.Name)
+	} else {
+		fmt.Fprintf(buf, "export interface %s {\n", inst.Name)
+	}
+	for _, f := range fs {
+		writeDoc(buf, "  ", f.doc)
+		fmt.Fprintf(buf, "  %s: %s;\n", f.prop, f.t)
+	}
+	if len(fs) != 0 {
+		fmt.Fprintf(buf, "}\n\n")
+	}
+
+	fmt.Fprintf(buf, "/** make%s fills in the fields with defaults */\n", inst.Name)
+	fmt.Fprintf(buf, "export function make%s(input: {", inst.Name)
+	for i, f := range fs {
+		if i != 0 {
+			buf.WriteString(";")
+		}
+		opt := ""
+		if f.def != "" {
+			opt = "?"
+		}
+		fmt.Fprintf(buf, " %s%s: %s", f.prop, opt, f.t)
+	}
+	if len(fs) != 0 {
+		buf.WriteString(" ")
+	}
+	fmt.Fprintf(buf, "}): %s {\n", inst.Name)
+	if len(fs) == 0 {
+		fmt.Fprintf(buf, "  return {};\n}\n\n")
+	} else {
+		fmt.Fprintf(buf, "  return {\n")
+	}
+	for _, f := range fs {
+		if f.def != "" {
+			fmt.Fprintf(buf, "    %s: input%s === undefined ? %s : input%s,\n", f.prop, f.access, f.def, f.access)
+		} else {
+			fmt.Fprintf(buf, "    %s: input%s,\n", f.prop, f.access)
+		}
+	}
+	if len(fs) != 0 {
+		fmt.Fprintf(buf, "  };\n}\n\n")
+	}
+
+	// fields with defaults may be omitted
+	fmt.Fprintf(buf, "const check%sFields: [string, Check, boolean][] = [", inst.Name)
+	for i, f := range fields {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(buf, "\n  [%q, (v, path, errs) => %s(v, path, errs), %v]", serializedName(f), fs[i].check, fs[i].def != "")
+	}
+	if len(fields) != 0 {
+		buf.WriteString(",\n")
+	}
+	fmt.Fprintf(buf, "];\n\n")
+	return nil
+}
+
+func (g *generator) genUnion(inst *gen.Instance) error {
+	buf := &inst.Code
+	fields := inst.Decl.Type.Union.Field
+	fmt.Fprintf(buf, "export type %s =\n", inst.Name)
+	checks := make([]string, len(fields))
+	for i, f := range fields {
+		te := inst.FieldType(f.TypeExpr)
+		name := serializedName(f)
+		writeDoc(buf, "  ", f.Doc())
+		if te.TypeRef.Primitive != nil && *te.TypeRef.Primitive == "Void" {
+			fmt.Fprintf(buf, "  | %q", name)
+			checks[i] = "null"
+		} else {
+			t, c, err := g.typeOf(te)
+			if err != nil {
+				return fmt.Errorf("%s.%s: %v", inst.SN, f.Name, err)
+			}
+			prop, _ := property(name)
+			fmt.Fprintf(buf, "  | { %s: %s }", prop, t)
+			checks[i] = "(v, path, errs) => " + c + "(v, path, errs)"
+		}
+		if i == len(fields)-1 {
+			buf.WriteString(";")
+		}
+		buf.WriteString("\n")
+	}
+	if len(fields) == 0 {
+		fmt.Fprintf(buf, "  never;\n")
+	}
+	buf.WriteString("\n")
+	fmt.Fprintf(buf, "const check%sBranches: [string, Check | null][] = [", inst.Name)
+	for i, f := range fields {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(buf, "\n  [%q, %s]", serializedName(f), checks[i])
+	}
+	if len(fields) != 0 {
+		buf.WriteString(",\n")
+	}
+	fmt.Fprintf(buf, "];\n\n")
+	return nil
+}
+
+func (g *generator) genHttp(buf *bytes.Buffer, eps []gen.Endpoint) error {
+	fmt.Fprintf(buf, "/** HttpError is thrown by the Client for a non 2xx response */\n")
+	fmt.Fprintf(buf, "export class HttpError extends Error {\n")
+	fmt.Fprintf(buf, "  constructor(readonly status: number, message: string) {\n    super(message);\n  }\n}\n\n")
+	fmt.Fprintf(buf, "/**\n * Client makes the %s http requests.\n", g.cfg.Module)
+	fmt.Fprintf(buf, " * Responses are validated before they are returned.\n */\n")
+	fmt.Fprintf(buf, "export class Client {\n")
+	fmt.Fprintf(buf, "  constructor(readonly baseUrl: string, readonly fetchFn: typeof fetch = fetch) {}\n")
+	for _, ep := range eps {
+		respT, respC, err := g.typeOf(ep.Response)
+		if err != nil {
+			return fmt.Errorf("%s response: %v", ep.Name, err)
+		}
+		params, body := "", "undefined"
+		if ep.Request != nil {
+			reqT, _, err := g.typeOf(*ep.Request)
+			if err != nil {
+				return fmt.Errorf("%s request: %v", ep.Name, err)
+			}
+			params, body = "req: "+reqT, "req"
+		}
+		buf.WriteString("\n")
+		doc := ep.Decl.Doc()
+		if strings.TrimSpace(doc) != "" {
+			doc = strings.TrimSpace(doc) + "\n\n"
+		}
+		writeDoc(buf, "  ", doc+ep.Method+" "+ep.Path)
+		fmt.Fprintf(buf, "  %s(%s): Promise<%s> {\n", gen.LowerName(ep.Name), params, respT)
+		fmt.Fprintf(buf, "    return this.call<%s>(%q, %q, %s, %s);\n  }\n", respT, ep.Method, ep.Path, body, respC)
+	}
+	buf.WriteString(`
+  private async call<T>(method: string, path: string, body: unknown, check: Check): Promise<T> {
+    const init: RequestInit = { method, headers: { Accept: "application/json" } };
+    if (body !== undefined) {
+      init.headers = { Accept: "application/json", "Content-Type": "application/json" };
+      init.body = JSON.stringify(body);
+    }
+    const res = await this.fetchFn(this.baseUrl.replace(/\/+$/, "") + path, init);
+    const text = await res.text();
+    if (!res.ok) {
+      throw new HttpError(res.status, text.trim());
+    }
+    const v: unknown = JSON.parse(text);
+    const errs: string[] = [];
+    check(v, "", errs);
+    if (errs.length !== 0) {
+      throw new Error("invalid response: " + errs.join(", "));
+    }
+    return v as T;
+  }
+}
+
+`)
+	return nil
+}
+
+func serializedName(f adl.Field) string {
+	if f.SerializedName != "" {
+		return f.SerializedName
+	}
+	return f.Name
+}
+
+// runtime is appended to every generated file so it has no dependencies
+const runtime = `// validation runtime
+
+type Check = (v: unknown, path: string, errs: string[]) => void;
+
+function fail(path: string, errs: string[], msg: string): void {
+  errs.push(path === "" ? msg : path + ": " + msg);
+}
+
+function sub(path: string, name: string): string {
+  return path === "" ? name : path + "." + name;
+}
+
+function isObject(v: unknown): v is { [key: string]: unknown } {
+  return typeof v === "object" && v !== null && !Array.isArray(v);
+}
+
+function checkNull(v: unknown, path: string, errs: string[]): void {
+  if (v !== null) fail(path, errs, "expected null");
+}
+
+function checkBool(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "boolean") fail(path, errs, "expected boolean");
+}
+
+function checkString(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "string") fail(path, errs, "expected string");
+}
+
+function checkNumber(v: unknown, path: string, errs: string[]): void {
+  if (typeof v !== "number" || !isFinite(v)) fail(path, errs, "expected number");
+}
+
+function checkJson(v: unknown, path: string, errs: string[]): void {
+  if (v === undefined) fail(path, errs, "expected json value");
+}
+
+function checkInt(min: number, max: number): Check {
+  return (v, path, errs) => {
+    if (typeof v !== "number" || Math.floor(v) !== v || v < min || v > max) {
+      fail(path, errs, "expected integer between " + min + " and " + max);
+    }
+  };
+}
+
+function checkVector(item: Check): Check {
+  return (v, path, errs) => {
+    if (!Array.isArray(v)) {
+      fail(path, errs, "expected array");
+      return;
+    }
+    v.forEach((x, i) => item(x, sub(path, String(i)), errs));
+  };
+}
+
+function checkStringMap(item: Check): Check {
+  return (v, path, errs) => {
+    if (!isObject(v)) {
+      fail(path, errs, "expected object");
+      return;
+    }
+    const obj = v;
+    Object.keys(obj).forEach((k) => item(obj[k], sub(path, k), errs));
+  };
+}
+
+function checkNullable(item: Check): Check {
+  return (v, path, errs) => {
+    if (v !== null) item(v, path, errs);
+  };
+}
+
+function checkStruct(v: unknown, path: string, errs: string[], fields: [string, Check, boolean][]): void {
+  if (!isObject(v)) {
+    fail(path, errs, "expected object");
+    return;
+  }
+  const obj = v;
+  const known: { [key: string]: boolean } = {};
+  fields.forEach(([name, check, optional]) => {
+    known[name] = true;
+    if (!(name in obj)) {
+      if (!optional) fail(sub(path, name), errs, "missing required field");
+      return;
+    }
+    check(obj[name], sub(path, name), errs);
+  });
+  Object.keys(obj).forEach((k) => {
+    if (!known[k]) fail(sub(path, k), errs, "unknown field");
+  });
+}
+
+function checkUnion(v: unknown, path: string, errs: string[], branches: [string, Check | null][]): void {
+  let name: string;
+  let value: unknown = null;
+  if (typeof v === "string") {
+    name = v;
+  } else if (isObject(v)) {
+    const keys = Object.keys(v);
+    if (keys.length !== 1) {
+      fail(path, errs, "union must have exactly one branch, got " + keys.length);
+      return;
+    }
+    name = keys[0];
+    value = v[name];
+  } else {
+    fail(path, errs, "expected union object or branch name");
+    return;
+  }
+  for (const [bname, check] of branches) {
+    if (bname !== name) continue;
+    if (check === null) {
+      if (typeof v !== "string") fail(sub(path, name), errs, "expected the branch name string");
+    } else if (typeof v === "string") {
+      fail(sub(path, name), errs, "expected union object");
+    } else {
+      check(value, sub(path, name), errs);
+    }
+    return;
+  }
+  fail(sub(path, name), errs, "unknown union branch");
+}
+`
@@ -0,0 +1,114 @@
+package tsgen
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+var update = flag.Bool("update", false, "rewrite the generated code in testdata")
+
+func TestGenerateRequests(t *testing.T) {
+	src, err := Generate(adltest.Modules(), Config{Module: "helix.protoapp.requests", Http: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "requests.ts")
+	if *update {
+		if err := ioutil.WriteFile(golden, src, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(src, want) {
+		t.Errorf("generated code differs from %s, rerun with -update", golden)
+	}
+}
+
+// TestTypeCheck compiles the generated code when the typescript compiler is installed
+func TestTypeCheck(t *testing.T) {
+	tsc, err := exec.LookPath("tsc")
+	if err != nil {
+		t.Skip("tsc not installed")
+	}
+	out, err := exec.Command(tsc, "--noEmit", "--strict", "--target", "es2017", "--lib", "es2017,dom", filepath.Join("testdata", "requests.ts")).CombinedOutput()
+	if err != nil {
+		t.Errorf("%v\n%s", err, out)
+	}
+}
+
+// TestValidate runs the generated validators when the typescript compiler and node are installed.
+// Fields with defaults may be omitted, as adlrt.DecodeStruct allows.
+func TestValidate(t *testing.T) {
+	tsc, err := exec.LookPath("tsc")
+	if err != nil {
+		t.Skip("tsc not installed")
+	}
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not installed")
+	}
+	dir, err := ioutil.TempDir("", "tsgen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out, err := exec.Command(tsc, "--strict", "--target", "es2017", "--lib", "es2017,dom", "--module", "commonjs", "--outDir", dir, filepath.Join("testdata", "requests.ts")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	script := `const r = require(process.argv[1]);
+console.log(JSON.stringify([
+  r.validateHelloReq({ name: "n", username: "u", createdAt: 0, config: { a: { a: "x" } } }),
+  r.validateHelloReq({ name: "n", createdAt: 0, config: { a: { a: "x" } } }),
+]));
+`
+	out, err = exec.Command(node, "-e", script, filepath.Join(dir, "requests.js")).CombinedOutput()
+	if err != nil {
+		t.Fatalf("%v\n%s", err, out)
+	}
+	want := `[[],["username: missing required field"]]`
+	if got := strings.TrimSpace(string(out)); got != want {
+		t.Errorf("got %s want %s", got, want)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	src, err := Generate(adltest.Modules(), Config{Module: "helix.protoapp.requests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(src)
+	for _, exp := range []string{
+		// same names as the go generator
+		"export type Login = PostLoginReqLoginResult;",
+		"export interface LoginReq {",
+		"  | \"invalidCredentials\"\n",
+		"  | { locked: LoginRespLocalDate };\n",
+		`export type Instant = number & { readonly __brand: "Instant" };`,
+		"  labels: string[];\n",
+		"    count: input.count === undefined ? 1 : input.count,\n",
+		`  ["previous", (v, path, errs) => checkNullable(checkAudit)(v, path, errs), true],`,
+		`  ["username", (v, path, errs) => checkString(v, path, errs), false],`,
+	} {
+		if !strings.Contains(s, exp) {
+			t.Errorf("expected %q", exp)
+		}
+	}
+	if strings.Contains(s, "class Client") {
+		t.Errorf("unexpected http client")
+	}
+	if _, err := Generate(adltest.Modules(), Config{Module: "no.such.module"}); err == nil {
+		t.Errorf("expected unknown module error")
+	}
+}
@@ -0,0 +1,445 @@
+// Package goimport derives ADL modules from Go types.
+//
+// Each package becomes a module named after its import path, ie github.com/a/b-c is github.com.a.b_c, and each exported
+// named type a decl, following the encoding/json mapping of the type:
+//
+//	struct            struct, fields are named after the Go field, the json tag name is the serializedName
+//	[]T, [n]T         Vector<T>
+//	[]byte            Bytes
+//	map[K]V           StringMap<V> for string and integer keys
+//	*T                Nullable<T>
+//	interface{}       Json
+//	time.Time         String
+//	other named types newtype
+//
+// Named types of other packages and unexported types are inlined, where such a type recurs it is Json.
+// Exported fields of embedded structs are promoted, as in encoding/json. Fields tagged omitempty default to their zero value.
+// Interfaces with a doc comment become union skeletons with a branch for each type of the package that implements
+// them, other interfaces are Json. Go doc comments become /// docs.
+package goimport
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"golang.org/x/tools/go/packages"
+)
+
+type Config struct {
+	// Module names the module of a single package, defaults to the import path with / and . as .
+	Module string
+	// Dir is the directory the patterns are resolved in, defaults to the working directory
+	Dir string
+}
+
+// Load converts the packages matching the patterns, ie ./..., references between them are references between their modules
+func Load(cfg Config, patterns ...string) ([]adl.Module, error) {
+	// only the files are listed, the type checking of x/tools' loader doesn't support current go versions
+	pkgs, err := packages.Load(&packages.Config{Mode: packages.LoadFiles, Dir: cfg.Dir}, patterns...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Module != "" && len(pkgs) != 1 {
+		return nil, fmt.Errorf("a module name can only be given for a single package, %d packages match %v", len(pkgs), patterns)
+	}
+	modules := map[string]string{}
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) != 0 {
+			return nil, fmt.Errorf("%s: %v", pkg.PkgPath, pkg.Errors[0])
+		}
+		modules[pkg.PkgPath] = ModuleName(pkg.PkgPath)
+		if cfg.Module != "" {
+			modules[pkg.PkgPath] = cfg.Module
+		}
+	}
+	fset := token.NewFileSet()
+	imp := importer.ForCompiler(fset, "source", nil)
+	mods := []adl.Module{}
+	for _, pkg := range pkgs {
+		files := []*ast.File{}
+		for _, fn := range pkg.GoFiles {
+			f, err := parser.ParseFile(fset, fn, nil, parser.ParseComments)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, f)
+		}
+		tpkg, err := (&types.Config{Importer: imp}).Check(pkg.PkgPath, fset, files, nil)
+		if err != nil {
+			return nil, err
+		}
+		mod, err := Convert(tpkg, files, modules)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+// ModuleName is the default module name of a package
+func ModuleName(pkgPath string) string {
+	parts := []string{}
+	for _, p := range strings.FieldsFunc(pkgPath, func(r rune) bool { return r == '/' || r == '.' }) {
+		rs := []rune(p)
+		for i, r := range rs {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+				rs[i] = '_'
+			}
+		}
+		if unicode.IsDigit(rs[0]) {
+			rs = append([]rune{'_'}, rs...)
+		}
+		parts = append(parts, string(rs))
+	}
+	return strings.Join(parts, ".")
+}
+
+// Convert derives the module of a type checked package, modules maps the import paths of the packages being
+// converted to their module names. Named types of other packages are inlined.
+func Convert(pkg *types.Package, files []*ast.File, modules map[string]string) (adl.Module, error) {
+	module, ex := modules[pkg.Path()]
+	if !ex {
+		module = ModuleName(pkg.Path())
+	}
+	c := &converter{pkg: pkg, modules: modules, docs: docs(files), b: adlbuild.NewModule(module), visiting: map[*types.Named]bool{}}
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !tn.Exported() || tn.IsAlias() {
+			continue
+		}
+		c.decl(tn)
+	}
+	return c.b.Build()
+}
+
+type converter struct {
+	pkg     *types.Package
+	modules map[string]string
+	// docs are the doc comments by the position of the name of the type or field
+	docs map[token.Pos]string
+	b    *adlbuild.ModuleBuilder
+	// fieldNames are the names of the fields of the struct being converted
+	fieldNames map[string]bool
+	// visiting are the named types whose underlying type is being converted, a recursive type refers to itself
+	visiting map[*types.Named]bool
+}
+
+func docs(files []*ast.File) map[token.Pos]string {
+	ds := map[token.Pos]string{}
+	for _, f := range files {
+		ast.Inspect(f, func(n ast.Node) bool {
+			switch n := n.(type) {
+			case *ast.GenDecl:
+				if n.Tok == token.TYPE && len(n.Specs) == 1 && n.Doc != nil {
+					ds[n.Specs[0].(*ast.TypeSpec).Name.Pos()] = n.Doc.Text()
+				}
+			case *ast.TypeSpec:
+				if n.Doc != nil {
+					ds[n.Name.Pos()] = n.Doc.Text()
+				}
+			case *ast.Field:
+				cg := n.Doc
+				if cg == nil {
+					cg = n.Comment
+				}
+				if cg != nil && len(n.Names) != 0 {
+					for _, id := range n.Names {
+						ds[id.Pos()] = cg.Text()
+					}
+				}
+			case *ast.FuncDecl:
+				return false
+			}
+			return true
+		})
+	}
+	return ds
+}
+
+func (c *converter) decl(tn *types.TypeName) {
+	doc := c.docs[tn.Pos()]
+	switch u := tn.Type().Underlying().(type) {
+	case *types.Struct:
+		d := c.b.Struct(tn.Name()).Doc(doc)
+		c.fieldNames = map[string]bool{}
+		c.fields(d, u, map[string]bool{}, map[*types.Struct]bool{u: true})
+	case *types.Interface:
+		if doc == "" {
+			return
+		}
+		d := c.b.Union(tn.Name()).Doc(doc)
+		for _, impl := range c.implementers(tn.Type(), u) {
+			d.Field(lowerCamel(impl), adlbuild.Ref("", impl))
+		}
+	case *types.Signature, *types.Chan:
+	default:
+		te, ok := c.typeExpr(u)
+		if ok {
+			c.b.Newtype(tn.Name(), te).Doc(doc)
+		}
+	}
+}
+
+// implementers are the exported non interface types of the package that implement the interface, or whose pointer does
+func (c *converter) implementers(it types.Type, iface *types.Interface) []string {
+	names := []string{}
+	scope := c.pkg.Scope()
+	for _, name := range scope.Names() {
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !tn.Exported() || types.Identical(tn.Type(), it) {
+			continue
+		}
+		if _, ok := tn.Type().Underlying().(*types.Interface); ok {
+			continue
+		}
+		if types.Implements(tn.Type(), iface) || types.Implements(types.NewPointer(tn.Type()), iface) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// fields adds the fields marshalled by encoding/json. The fields of untagged embedded structs are promoted unless
+// the struct has a field with the same json name, seen are the json names of the fields already added.
+func (c *converter) fields(d *adlbuild.DeclBuilder, st *types.Struct, seen map[string]bool, embedded map[*types.Struct]bool) {
+	direct := map[string]bool{}
+	for i := 0; i < st.NumFields(); i++ {
+		if name, _, ok := jsonField(st, i); ok && promoted(st, i) == nil {
+			direct[name] = true
+		}
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		v := st.Field(i)
+		if est := promoted(st, i); est != nil {
+			if !embedded[est] {
+				embedded[est] = true
+				inner := map[string]bool{}
+				for n := range seen {
+					inner[n] = true
+				}
+				for n := range direct {
+					inner[n] = true
+				}
+				c.fields(d, est, inner, embedded)
+				for n := range inner {
+					seen[n] = seen[n] || !direct[n]
+				}
+			}
+			continue
+		}
+		jsonName, omitEmpty, ok := jsonField(st, i)
+		if !ok || seen[jsonName] {
+			continue
+		}
+		te, ok := c.typeExpr(v.Type())
+		if !ok {
+			continue
+		}
+		seen[jsonName] = true
+		// field names are unique in ADL, a promoted field can have the go name of a field with another json name
+		name := lowerCamel(v.Name())
+		if c.fieldNames[name] {
+			name = lowerCamel(ModuleName(jsonName))
+		}
+		c.fieldNames[name] = true
+		f := d.Field(name, te).Doc(c.docs[v.Pos()])
+		if jsonName != name {
+			f.SerializedName(jsonName)
+		}
+		if omitEmpty {
+			if z, ok := zero(te); ok {
+				f.Default(z)
+			}
+		}
+	}
+}
+
+// jsonField is the json name of an exported field, false for fields that aren't marshalled
+func jsonField(st *types.Struct, i int) (name string, omitEmpty bool, ok bool) {
+	v := st.Field(i)
+	tag := reflect.StructTag(st.Tag(i)).Get("json")
+	if tag == "-" || !v.Exported() && promoted(st, i) == nil {
+		return "", false, false
+	}
+	opts := strings.Split(tag, ",")
+	name = opts[0]
+	if name == "" {
+		name = v.Name()
+	}
+	for _, o := range opts[1:] {
+		omitEmpty = omitEmpty || o == "omitempty"
+	}
+	return name, omitEmpty, true
+}
+
+// promoted is the struct of an untagged embedded field, whose fields are promoted
+func promoted(st *types.Struct, i int) *types.Struct {
+	v := st.Field(i)
+	if !v.Embedded() || strings.Split(reflect.StructTag(st.Tag(i)).Get("json"), ",")[0] != "" {
+		return nil
+	}
+	t := v.Type()
+	if p, ok := t.(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	est, _ := t.Underlying().(*types.Struct)
+	return est
+}
+
+// zero is the json of the zero value of a type omitted when empty
+func zero(te adl.TypeExpr) (interface{}, bool) {
+	if te.TypeRef.Primitive == nil {
+		return nil, false
+	}
+	switch p := *te.TypeRef.Primitive; p {
+	case "Bool":
+		return false, true
+	case "String", "Bytes":
+		return "", true
+	case "Nullable", "Json":
+		return nil, true
+	case "Vector":
+		return []interface{}{}, true
+	case "StringMap":
+		return map[string]interface{}{}, true
+	default:
+		if adl.IsPrimitive(p) && p != "Void" && p != "TypeToken" {
+			return 0, true
+		}
+	}
+	return nil, false
+}
+
+// typeExpr is false for types encoding/json doesn't marshal, ie funcs and chans
+func (c *converter) typeExpr(t types.Type) (adl.TypeExpr, bool) {
+	// by name, some are aliases in newer go versions
+	switch t.String() {
+	case "time.Time":
+		return adlbuild.String, true
+	case "encoding/json.RawMessage", "encoding/json.Number", "error":
+		return adlbuild.Json, true
+	}
+	switch t := t.(type) {
+	case *types.Named:
+		obj := t.Obj()
+		if iface, ok := t.Underlying().(*types.Interface); ok {
+			if obj.Pkg() == c.pkg && obj.Exported() && c.docs[obj.Pos()] != "" {
+				return adlbuild.Ref("", obj.Name()), true
+			}
+			return c.typeExpr(iface)
+		}
+		if c.visiting[t] {
+			if ref, ok := c.declRef(obj); ok {
+				return ref, true
+			}
+			// inlining a recursive type never ends
+			return adlbuild.Json, true
+		}
+		c.visiting[t] = true
+		defer delete(c.visiting, t)
+		te, ok := c.typeExpr(t.Underlying())
+		if !ok {
+			return adl.TypeExpr{}, false
+		}
+		if ref, ok := c.declRef(obj); ok {
+			return ref, true
+		}
+		// named types of other packages and unexported types are inlined, structs can't be
+		if _, ok := t.Underlying().(*types.Struct); ok {
+			return adlbuild.Json, true
+		}
+		return te, true
+	case *types.Basic:
+		switch t.Kind() {
+		case types.Bool:
+			return adlbuild.Bool, true
+		case types.Int, types.Int64:
+			return adlbuild.Int64, true
+		case types.Int8:
+			return adlbuild.Int8, true
+		case types.Int16:
+			return adlbuild.Int16, true
+		case types.Int32:
+			return adlbuild.Int32, true
+		case types.Uint, types.Uint64, types.Uintptr:
+			return adlbuild.Word64, true
+		case types.Uint8:
+			return adlbuild.Word8, true
+		case types.Uint16:
+			return adlbuild.Word16, true
+		case types.Uint32:
+			return adlbuild.Word32, true
+		case types.Float32:
+			return adlbuild.Float, true
+		case types.Float64:
+			return adlbuild.Double, true
+		case types.String:
+			return adlbuild.String, true
+		}
+		return adl.TypeExpr{}, false
+	case *types.Pointer:
+		te, ok := c.typeExpr(t.Elem())
+		return adlbuild.Nullable(te), ok
+	case *types.Slice:
+		if b, ok := t.Elem().(*types.Basic); ok && b.Kind() == types.Uint8 {
+			return adlbuild.Bytes, true
+		}
+		te, ok := c.typeExpr(t.Elem())
+		return adlbuild.Vector(te), ok
+	case *types.Array:
+		te, ok := c.typeExpr(t.Elem())
+		return adlbuild.Vector(te), ok
+	case *types.Map:
+		if k, ok := t.Key().Underlying().(*types.Basic); !ok || k.Info()&(types.IsString|types.IsInteger) == 0 {
+			return adlbuild.Json, true
+		}
+		te, ok := c.typeExpr(t.Elem())
+		return adlbuild.StringMap(te), ok
+	case *types.Interface, *types.Struct:
+		return adlbuild.Json, true
+	case *types.Signature, *types.Chan:
+		return adl.TypeExpr{}, false
+	}
+	// aliases
+	if u := t.Underlying(); u != t {
+		return c.typeExpr(u)
+	}
+	return adl.TypeExpr{}, false
+}
+
+// declRef refers to the decl of an exported type of the packages being converted
+func (c *converter) declRef(obj *types.TypeName) (adl.TypeExpr, bool) {
+	if !obj.Exported() {
+		return adl.TypeExpr{}, false
+	}
+	if obj.Pkg() == c.pkg {
+		return adlbuild.Ref("", obj.Name()), true
+	}
+	if m, ex := c.modules[obj.Pkg().Path()]; ex {
+		return adlbuild.Ref(m, obj.Name()), true
+	}
+	return adl.TypeExpr{}, false
+}
+
+// lowerCamel lower cases the leading upper case run of a Go name, ie ID -> id, URLPath -> urlPath
+func lowerCamel(name string) string {
+	rs := []rune(name)
+	for i := 0; i < len(rs) && unicode.IsUpper(rs[i]); i++ {
+		if i > 0 && i+1 < len(rs) && unicode.IsLower(rs[i+1]) {
+			break
+		}
+		rs[i] = unicode.ToLower(rs[i])
+	}
+	return string(rs)
+}
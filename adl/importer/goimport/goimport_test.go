@@ -0,0 +1,154 @@
+package goimport
+
+import (
+	"testing"
+
+	"github.com/wxio/tron-go/adl/adlbuild"
+)
+
+const petsAdl = `module pets {
+
+/// Base holds the fields common to all pets
+struct Base {
+  Int64 id;
+  String name;
+  /// Born is when the pet was born
+  String born;
+};
+
+struct Cat {
+  String name;
+  /// Born is when the pet was born
+  String born;
+  /// Tag shadows the id of Base
+  @SerializedName "id"
+  String tag;
+  String catName;
+  Vector<Bool> lives;
+};
+
+/// A Dog barks
+struct Dog {
+  Int64 id;
+  String name;
+  /// Born is when the pet was born
+  String born;
+  String breed = "";
+  Vector<String> tricks = [];
+  Nullable<Owner> owner;
+  StringMap<Word16> scores;
+  Json extra;
+  Json noise;
+  Bytes photo;
+  @SerializedName "URLPath"
+  String urlPath;
+};
+
+struct Owner {
+  /// trailing comment
+  String email;
+  Vector<Pet> pets;
+};
+
+/// Pet is an animal in the shop
+union Pet {
+  Cat cat;
+  Dog dog;
+};
+
+/// Status of an order
+newtype Status = String;
+
+};
+`
+
+const clinicAdl = `module github.com.wxio.tron_go.adl.importer.goimport.testdata.pets.clinic {
+
+import github.com.wxio.tron_go.adl.importer.goimport.testdata.pets.Dog;
+import github.com.wxio.tron_go.adl.importer.goimport.testdata.pets.Status;
+
+struct Visit {
+  Dog pet;
+  Status status;
+  StringMap<Int64> ints;
+  @SerializedName "Keys"
+  Json keys;
+};
+
+};
+`
+
+func TestLoad(t *testing.T) {
+	mods, err := Load(Config{}, "./testdata/pets", "./testdata/pets/clinic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 2 {
+		t.Fatalf("expected 2 modules got %d", len(mods))
+	}
+	src, err := adlbuild.Source(mods[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != clinicAdl {
+		t.Errorf("expected\n%s\ngot\n%s", clinicAdl, src)
+	}
+	mods, err = Load(Config{Module: "pets"}, "./testdata/pets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err = adlbuild.Source(mods[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != petsAdl {
+		t.Errorf("expected\n%s\ngot\n%s", petsAdl, src)
+	}
+	if _, err := Load(Config{Module: "pets"}, "./testdata/pets", "./testdata/pets/clinic"); err == nil {
+		t.Errorf("expected an error naming the module of two packages")
+	}
+}
+
+const treeAdl = `module tree {
+
+newtype List = Vector<List>;
+
+struct Node {
+  Vector<Node> kids;
+  Nullable<Node> next;
+  /// Forest is inlined
+  Vector<Json> forest;
+};
+
+newtype Tree = StringMap<Tree>;
+
+};
+`
+
+func TestRecursive(t *testing.T) {
+	mods, err := Load(Config{Module: "tree"}, "./testdata/tree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := adlbuild.Source(mods[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != treeAdl {
+		t.Errorf("expected\n%s\ngot\n%s", treeAdl, src)
+	}
+}
+
+func TestNames(t *testing.T) {
+	for _, tc := range [][2]string{
+		{ModuleName("github.com/a/b-c/1d"), "github.com.a.b_c._1d"},
+		{lowerCamel("ID"), "id"},
+		{lowerCamel("URLPath"), "urlPath"},
+		{lowerCamel("Name"), "name"},
+		{lowerCamel("x"), "x"},
+	} {
+		if tc[0] != tc[1] {
+			t.Errorf("expected %s got %s", tc[1], tc[0])
+		}
+	}
+}
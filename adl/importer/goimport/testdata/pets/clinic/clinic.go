@@ -0,0 +1,10 @@
+package clinic
+
+import "github.com/wxio/tron-go/adl/importer/goimport/testdata/pets"
+
+type Visit struct {
+	Pet    pets.Dog    `json:"pet"`
+	Status pets.Status `json:"status"`
+	Ints   map[int]int `json:"ints"`
+	Keys   map[bool]string
+}
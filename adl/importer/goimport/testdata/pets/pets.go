@@ -0,0 +1,61 @@
+// Package pets is a fixture for goimport
+package pets
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Pet is an animal in the shop
+type Pet interface {
+	Kind() string
+}
+
+type Noisy interface {
+	Noise() string
+}
+
+// Base holds the fields common to all pets
+type Base struct {
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+	// Born is when the pet was born
+	Born time.Time `json:"born"`
+}
+
+// A Dog barks
+type Dog struct {
+	Base
+	Breed    string            `json:"breed,omitempty"`
+	Tricks   []string          `json:"tricks,omitempty"`
+	Owner    *Owner            `json:"owner"`
+	Scores   map[string]uint16 `json:"scores"`
+	Extra    json.RawMessage   `json:"extra"`
+	Noise    Noisy             `json:"noise"`
+	Photo    []byte            `json:"photo"`
+	URLPath  string
+	Internal string `json:"-"`
+	hidden   string
+	Done     chan bool
+}
+
+type Cat struct {
+	Base
+	// Tag shadows the id of Base
+	Tag   string  `json:"id"`
+	Name  string  `json:"catName"`
+	Lives [9]bool `json:"lives"`
+}
+
+type Owner struct {
+	Email string `json:"email"` // trailing comment
+	Pets  []Pet  `json:"pets"`
+}
+
+// Status of an order
+type Status string
+
+type Handler func()
+
+func (d Dog) Kind() string  { return "dog" }
+func (c *Cat) Kind() string { return "cat" }
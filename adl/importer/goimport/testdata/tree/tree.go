@@ -0,0 +1,15 @@
+// Package tree is a fixture for goimport of recursive types
+package tree
+
+type Tree map[string]Tree
+
+type List []List
+
+type Node struct {
+	Kids []Node `json:"kids"`
+	Next *Node  `json:"next"`
+	// Forest is inlined
+	Forest forest `json:"forest"`
+}
+
+type forest []forest
@@ -0,0 +1,541 @@
+// Package jsonschema translates JSON Schema documents into ADL modules.
+//
+// The definitions ($defs and definitions) and the root schema become decls:
+//
+//	object with properties        struct, optional properties are Nullable with a null default unless they have a default
+//	object with additionalProperties StringMap
+//	oneOf with a discriminator    union, branches are named after the discriminator values
+//	enum of strings               union of Void branches
+//	array                         Vector
+//	["T", "null"], nullable       Nullable
+//	string, integer, number, boolean String, Int64, Double, Bool
+//	anything else                 Json
+//
+// Object, enum and union schemas nested in properties become decls named after the decl and property, ie Order.status is
+// OrderStatus. Descriptions become doc comments. Constructs that ADL can't represent, validation keywords, anyOf,
+// allOf, external references and the like, are listed in the report of lossy constructs.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+type Config struct {
+	// Module is the name of the module
+	Module string
+	// Name is the decl name of the root schema, defaults to its title, or Root
+	Name string
+}
+
+// Loss is a construct of the schema that the module doesn't represent
+type Loss struct {
+	// Path is the json pointer of the schema, ie #/properties/name
+	Path    string
+	Message string
+}
+
+func (l Loss) String() string {
+	return l.Path + ": " + l.Message
+}
+
+// Import translates a JSON Schema document
+func Import(schema []byte, cfg Config) (adl.Module, []Loss, error) {
+	if cfg.Module == "" {
+		return adl.Module{}, nil, fmt.Errorf("no module name")
+	}
+	dec := json.NewDecoder(bytes.NewReader(schema))
+	dec.UseNumber()
+	root, err := decode(dec)
+	if err != nil {
+		return adl.Module{}, nil, fmt.Errorf("schema: %v", err)
+	}
+	rs, ok := root.(*object)
+	if !ok {
+		return adl.Module{}, nil, fmt.Errorf("schema: expected an object")
+	}
+	im := &importer{b: adlbuild.NewModule(cfg.Module), root: rs, refs: map[string]string{}, names: map[string]bool{}}
+	type def struct {
+		name, path string
+		schema     interface{}
+	}
+	defs := []def{}
+	for _, key := range []string{"$defs", "definitions"} {
+		ds, ok := rs.get(key).(*object)
+		if !ok {
+			continue
+		}
+		for _, k := range ds.keys {
+			path := "#/" + key + "/" + escape(k)
+			name := im.declName(gen.ExportName(ident(k)))
+			im.refs[path] = name
+			defs = append(defs, def{name, path, ds.vals[k]})
+		}
+	}
+	for _, d := range defs {
+		im.decl(d.name, d.schema, d.path)
+	}
+	if isSchema(rs) {
+		name := cfg.Name
+		if name == "" {
+			name, _ = rs.get("title").(string)
+			name = gen.ExportName(ident(name))
+		}
+		if name == "" || name == "_" {
+			name = "Root"
+		}
+		im.decl(im.declName(name), rs, "#")
+	}
+	mod, err := im.b.Build()
+	return mod, im.lossy, err
+}
+
+type importer struct {
+	b    *adlbuild.ModuleBuilder
+	root *object
+	// refs are the decl names of the definitions by json pointer, names are the decl names in use
+	refs  map[string]string
+	names map[string]bool
+	lossy []Loss
+}
+
+func (im *importer) lose(path, format string, args ...interface{}) {
+	im.lossy = append(im.lossy, Loss{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// declName is a decl name not yet in use
+func (im *importer) declName(name string) string {
+	n := name
+	for i := 2; im.names[n]; i++ {
+		n = fmt.Sprintf("%s%d", name, i)
+	}
+	im.names[n] = true
+	return n
+}
+
+// isSchema is true for a root with more than definitions
+func isSchema(s *object) bool {
+	for _, k := range s.keys {
+		switch k {
+		case "$schema", "$id", "id", "title", "description", "$comment", "$defs", "definitions":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// unrepresented keywords, the rest are translated or are annotations without meaning for the json
+var lossyKeywords = []string{
+	"pattern", "minLength", "maxLength", "format", "contentEncoding", "contentMediaType",
+	"minimum", "maximum", "exclusiveMinimum", "exclusiveMaximum", "multipleOf",
+	"minItems", "maxItems", "uniqueItems", "contains", "additionalItems",
+	"minProperties", "maxProperties", "patternProperties", "propertyNames", "dependencies", "dependentRequired",
+	"dependentSchemas", "allOf", "not", "if", "then", "else", "const", "unevaluatedProperties",
+}
+
+func (im *importer) keywords(s *object, path string) {
+	for _, k := range lossyKeywords {
+		if _, ex := s.vals[k]; ex {
+			im.lose(path, "'%s' isn't represented", k)
+		}
+	}
+}
+
+// decl adds the decl of a schema
+func (im *importer) decl(name string, schema interface{}, path string) {
+	s, ok := schema.(*object)
+	if !ok {
+		im.b.Newtype(name, im.typeExpr(schema, name, path))
+		return
+	}
+	doc, _ := s.get("description").(string)
+	switch {
+	case isStringEnum(s):
+		im.keywords(s, path)
+		d := im.b.Union(name).Doc(doc)
+		used := map[string]bool{}
+		for _, v := range s.get("enum").([]interface{}) {
+			str := v.(string)
+			fname := unique(used, ident(str))
+			f := d.Field(fname, adlbuild.Void)
+			if fname != str {
+				f.SerializedName(str)
+			}
+		}
+	case s.get("properties") != nil && nullable(s) == nil:
+		im.keywords(s, path)
+		im.object(im.b.Struct(name).Doc(doc), name, s, path)
+	case im.discriminator(s) != "" && nullable(s) == nil:
+		im.keywords(s, path)
+		im.union(im.b.Union(name).Doc(doc), name, s, path)
+	default:
+		im.b.Newtype(name, im.typeExpr(s, name, path)).Doc(doc)
+	}
+}
+
+func isStringEnum(s *object) bool {
+	es, ok := s.get("enum").([]interface{})
+	if !ok || len(es) == 0 {
+		return false
+	}
+	for _, e := range es {
+		if _, ok := e.(string); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// object adds the fields of the properties
+func (im *importer) object(d *adlbuild.DeclBuilder, name string, s *object, path string) {
+	required := map[string]bool{}
+	if rs, ok := s.get("required").([]interface{}); ok {
+		for _, r := range rs {
+			if r, ok := r.(string); ok {
+				required[r] = true
+			}
+		}
+	}
+	switch ap := s.get("additionalProperties").(type) {
+	case nil:
+	case bool:
+		if ap {
+			im.lose(path, "additional properties are dropped")
+		}
+	default:
+		im.lose(path, "additional properties are dropped")
+	}
+	props, _ := s.get("properties").(*object)
+	if props == nil {
+		return
+	}
+	used := map[string]bool{}
+	for _, k := range props.keys {
+		ppath := path + "/properties/" + escape(k)
+		ps := props.vals[k]
+		fname := unique(used, ident(k))
+		te := im.typeExpr(ps, name+gen.ExportName(fname), ppath)
+		var def interface{}
+		hasDef := false
+		if po, ok := ps.(*object); ok {
+			if v, ex := po.vals["default"]; ex {
+				def, hasDef = plain(v), true
+			}
+		}
+		if !required[k] && !hasDef {
+			if p := te.TypeRef.Primitive; p == nil || *p != "Nullable" && *p != "Json" {
+				te = adlbuild.Nullable(te)
+			}
+			hasDef = true
+		}
+		f := d.Field(fname, te)
+		if po, ok := ps.(*object); ok {
+			if doc, ok := po.get("description").(string); ok {
+				f.Doc(doc)
+			}
+		}
+		if fname != k {
+			f.SerializedName(k)
+		}
+		if hasDef {
+			f.Default(def)
+		}
+	}
+}
+
+// discriminator is the property naming the branch of a oneOf, either OpenAPI's discriminator.propertyName or the
+// property all the branches have with a distinct constant value
+func (im *importer) discriminator(s *object) string {
+	if d, ok := s.get("discriminator").(*object); ok {
+		if p, ok := d.get("propertyName").(string); ok {
+			return p
+		}
+	}
+	branches, ok := s.get("oneOf").([]interface{})
+	if !ok || len(branches) == 0 {
+		return ""
+	}
+	candidates := map[string]map[string]bool{}
+	for i, b := range branches {
+		props := map[string]string{}
+		if bo, ok := im.resolve(b).(*object); ok {
+			if ps, ok := bo.get("properties").(*object); ok {
+				for _, k := range ps.keys {
+					if v, ok := constant(ps.vals[k]); ok {
+						props[k] = v
+					}
+				}
+			}
+		}
+		for k, v := range props {
+			if i == 0 {
+				candidates[k] = map[string]bool{}
+			}
+			if vs, ex := candidates[k]; ex && !vs[v] {
+				vs[v] = true
+			}
+		}
+		for k, vs := range candidates {
+			if _, ex := props[k]; !ex || len(vs) != i+1 {
+				delete(candidates, k)
+			}
+		}
+	}
+	names := []string{}
+	for k := range candidates {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return ""
+	}
+	return names[0]
+}
+
+// constant is the value of a string const or single valued enum
+func constant(schema interface{}) (string, bool) {
+	s, ok := schema.(*object)
+	if !ok {
+		return "", false
+	}
+	if c, ok := s.get("const").(string); ok {
+		return c, true
+	}
+	if es, ok := s.get("enum").([]interface{}); ok && len(es) == 1 {
+		c, ok := es[0].(string)
+		return c, ok
+	}
+	return "", false
+}
+
+// union adds a branch for each schema of a discriminated oneOf
+func (im *importer) union(d *adlbuild.DeclBuilder, name string, s *object, path string) {
+	prop := im.discriminator(s)
+	im.lose(path, "oneOf discriminated by '%s' is a union, ADL serializes the branch name as the key of an object", prop)
+	mapping := map[string]string{}
+	if dobj, ok := s.get("discriminator").(*object); ok {
+		if m, ok := dobj.get("mapping").(*object); ok {
+			for _, k := range m.keys {
+				if ref, ok := m.vals[k].(string); ok {
+					mapping[ref] = k
+				}
+			}
+		}
+	}
+	used := map[string]bool{}
+	for i, b := range s.get("oneOf").([]interface{}) {
+		bpath := fmt.Sprintf("%s/oneOf/%d", path, i)
+		branch := ""
+		if bo, ok := b.(*object); ok {
+			if ref, ok := bo.get("$ref").(string); ok {
+				branch = mapping[ref]
+				if branch == "" {
+					branch = ref[strings.LastIndex(ref, "/")+1:]
+				}
+			}
+		}
+		if bo, ok := im.resolve(b).(*object); ok {
+			if ps, ok := bo.get("properties").(*object); ok {
+				if v, ok := constant(ps.get(prop)); ok {
+					branch = v
+				}
+			}
+		}
+		if branch == "" {
+			branch = fmt.Sprintf("v%d", i+1)
+		}
+		bname := unique(used, gen.LowerName(ident(branch)))
+		f := d.Field(bname, im.typeExpr(b, name+gen.ExportName(bname), bpath))
+		if bname != branch {
+			f.SerializedName(branch)
+		}
+	}
+}
+
+// resolve follows a local reference
+func (im *importer) resolve(schema interface{}) interface{} {
+	s, ok := schema.(*object)
+	if !ok {
+		return schema
+	}
+	ref, ok := s.get("$ref").(string)
+	if !ok {
+		return schema
+	}
+	parts := strings.Split(strings.TrimPrefix(ref, "#/"), "/")
+	if len(parts) != 2 || !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+	if defs, ok := im.root.get(parts[0]).(*object); ok {
+		return defs.get(unescape(parts[1]))
+	}
+	return nil
+}
+
+// nullable is the schema without null of ["T", "null"], oneOf [T, null] or OpenAPI's nullable, or nil
+func nullable(s *object) interface{} {
+	if n, ok := s.get("nullable").(bool); ok && n {
+		return s.without("nullable")
+	}
+	if ts, ok := s.get("type").([]interface{}); ok && len(ts) == 2 {
+		for i, t := range ts {
+			if t == "null" {
+				ns := s.without("type")
+				ns.set("type", ts[1-i])
+				return ns
+			}
+		}
+	}
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if bs, ok := s.get(key).([]interface{}); ok && len(bs) == 2 {
+			for i, b := range bs {
+				if bo, ok := b.(*object); ok && bo.get("type") == "null" && len(bo.keys) == 1 {
+					return bs[1-i]
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// typeExpr is the type of a schema, object, enum and union schemas become decls named hint
+func (im *importer) typeExpr(schema interface{}, hint, path string) adl.TypeExpr {
+	s, ok := schema.(*object)
+	if !ok {
+		if b, ok := schema.(bool); ok && !b {
+			im.lose(path, "the false schema is Void")
+			return adlbuild.Void
+		}
+		return adlbuild.Json
+	}
+	if ref, ok := s.get("$ref").(string); ok {
+		if name, ex := im.refs[ref]; ex {
+			return adlbuild.Ref("", name)
+		}
+		im.lose(path, "reference '%s' isn't to a definition of the schema, it is Json", ref)
+		return adlbuild.Json
+	}
+	if ns := nullable(s); ns != nil {
+		return adlbuild.Nullable(im.typeExpr(ns, hint, path))
+	}
+	if isStringEnum(s) || s.get("properties") != nil || s.get("oneOf") != nil && im.discriminator(s) != "" {
+		// the description is the doc of the field
+		name := im.declName(hint)
+		im.decl(name, s.without("description"), path)
+		return adlbuild.Ref("", name)
+	}
+	im.keywords(s, path)
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if _, ex := s.vals[key]; ex {
+			im.lose(path, "'%s' without a discriminator is Json", key)
+			return adlbuild.Json
+		}
+	}
+	if _, ex := s.vals["enum"]; ex {
+		im.lose(path, "enum of other than strings is its type")
+	}
+	t := s.get("type")
+	if c, ex := s.vals["const"]; ex && t == nil {
+		switch c.(type) {
+		case string:
+			t = "string"
+		case json.Number:
+			t = "number"
+		case bool:
+			t = "boolean"
+		}
+	}
+	if ts, ok := t.([]interface{}); ok {
+		if len(ts) != 1 {
+			im.lose(path, "multiple types are Json")
+			return adlbuild.Json
+		}
+		t = ts[0]
+	}
+	switch t {
+	case "string":
+		return adlbuild.String
+	case "integer":
+		return adlbuild.Int64
+	case "number":
+		return adlbuild.Double
+	case "boolean":
+		return adlbuild.Bool
+	case "null":
+		return adlbuild.Void
+	case "array":
+		switch items := s.get("items").(type) {
+		case nil:
+			return adlbuild.Vector(adlbuild.Json)
+		case []interface{}:
+			im.lose(path, "tuple items are Json")
+			return adlbuild.Vector(adlbuild.Json)
+		default:
+			return adlbuild.Vector(im.typeExpr(items, hint+"Item", path+"/items"))
+		}
+	case "object":
+		switch ap := s.get("additionalProperties").(type) {
+		case *object:
+			return adlbuild.StringMap(im.typeExpr(ap, hint+"Value", path+"/additionalProperties"))
+		case bool:
+			if !ap {
+				im.lose(path, "an object without properties is Json")
+				return adlbuild.Json
+			}
+		}
+		return adlbuild.StringMap(adlbuild.Json)
+	}
+	return adlbuild.Json
+}
+
+// ident is the ADL identifier of a property or value, ie first-name is firstName
+func ident(s string) string {
+	var buf strings.Builder
+	upper := false
+	for _, r := range s {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_':
+			if buf.Len() == 0 && unicode.IsDigit(r) {
+				buf.WriteRune('_')
+			}
+			if upper && buf.Len() != 0 {
+				r = unicode.ToUpper(r)
+			}
+			buf.WriteRune(r)
+			upper = false
+		default:
+			upper = true
+		}
+	}
+	if buf.Len() == 0 {
+		return "_"
+	}
+	return buf.String()
+}
+
+// unique numbers a field name taken by an earlier field, ie a-b and aB are aB and aB2
+func unique(used map[string]bool, name string) string {
+	u := name
+	for j := 2; used[u]; j++ {
+		u = fmt.Sprintf("%s%d", name, j)
+	}
+	used[u] = true
+	return u
+}
+
+func escape(s string) string {
+	return strings.Replace(strings.Replace(s, "~", "~0", -1), "/", "~1", -1)
+}
+
+func unescape(s string) string {
+	return strings.Replace(strings.Replace(s, "~1", "/", -1), "~0", "~", -1)
+}
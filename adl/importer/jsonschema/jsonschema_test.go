@@ -0,0 +1,156 @@
+package jsonschema
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl/adlbuild"
+)
+
+var update = flag.Bool("update", false, "rewrite the imported adl in testdata")
+
+func TestImportPetstore(t *testing.T) {
+	by, err := ioutil.ReadFile(filepath.Join("testdata", "petstore.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mod, lossy, err := Import(by, Config{Module: "pet.store"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := adlbuild.Source(mod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "petstore.adl")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != string(want) {
+		t.Errorf("imported adl differs from %s, rerun with -update\n%s", golden, src)
+	}
+	report := []string{}
+	for _, l := range lossy {
+		report = append(report, l.String())
+	}
+	for _, expected := range []string{
+		"#/$defs/Pet: oneOf discriminated by 'kind' is a union, ADL serializes the branch name as the key of an object",
+		"#/$defs/Point: tuple items are Json",
+		"#/properties/id: 'minimum' isn't represented",
+		"#/properties/shipping-address/properties/postcode: 'pattern' isn't represented",
+		"#/properties/extra: 'anyOf' without a discriminator is Json",
+		"#/properties/ext: reference 'other.json#/Thing' isn't to a definition of the schema, it is Json",
+	} {
+		if !contains(report, expected) {
+			t.Errorf("expected %q in the report\n%s", expected, strings.Join(report, "\n"))
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	for _, tc := range []struct {
+		name, schema, adl string
+	}{
+		{"openapi discriminator", `{
+  "definitions": {
+    "Shape": {
+      "oneOf": [{ "$ref": "#/definitions/Square" }, { "type": "object", "properties": { "r": { "type": "number" } }, "required": ["r"] }],
+      "discriminator": { "propertyName": "shape", "mapping": { "sq": "#/definitions/Square" } }
+    },
+    "Square": { "type": "object", "properties": { "side": { "type": "number", "nullable": true } }, "required": ["side"] }
+  }
+}`, `module a {
+
+union Shape {
+  Square sq;
+  ShapeV2 v2;
+};
+
+struct ShapeV2 {
+  Double r;
+};
+
+struct Square {
+  Nullable<Double> side;
+};
+
+};
+`},
+		{"root", `{ "type": "object", "additionalProperties": { "type": ["integer", "null"] }, "description": "Counts" }`, `module a {
+
+/// Counts
+newtype Root = StringMap<Nullable<Int64>>;
+
+};
+`},
+		{"enum", `{ "title": "colour", "enum": ["red", "dark green", "2nd"] }`, `module a {
+
+union Colour {
+  Void red;
+  @SerializedName "dark green"
+  Void darkGreen;
+  @SerializedName "2nd"
+  Void _2nd;
+};
+
+};
+`},
+		{"clashing names", `{ "title": "clash", "type": "object", "required": ["a-b", "aB", "kind"], "properties": {
+  "a-b": { "type": "string" }, "aB": { "type": "string" }, "kind": { "enum": ["a-b", "a b", "aB"] } } }`, `module a {
+
+struct Clash {
+  @SerializedName "a-b"
+  String aB;
+  @SerializedName "aB"
+  String aB2;
+  ClashKind kind;
+};
+
+union ClashKind {
+  @SerializedName "a-b"
+  Void aB;
+  @SerializedName "a b"
+  Void aB2;
+  @SerializedName "aB"
+  Void aB3;
+};
+
+};
+`},
+	} {
+		mod, _, err := Import([]byte(tc.schema), Config{Module: "a"})
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		src, err := adlbuild.Source(mod)
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if src != tc.adl {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.name, tc.adl, src)
+		}
+	}
+	if _, _, err := Import([]byte(`[]`), Config{Module: "a"}); err == nil {
+		t.Errorf("expected an error for a schema that isn't an object")
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
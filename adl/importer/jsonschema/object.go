@@ -0,0 +1,100 @@
+package jsonschema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// object is a json object that keeps the order of its keys, properties are translated in order
+type object struct {
+	keys []string
+	vals map[string]interface{}
+}
+
+func (o *object) get(key string) interface{} {
+	if o == nil {
+		return nil
+	}
+	return o.vals[key]
+}
+
+func (o *object) set(key string, val interface{}) {
+	if _, ex := o.vals[key]; !ex {
+		o.keys = append(o.keys, key)
+	}
+	o.vals[key] = val
+}
+
+// without is a copy of the object without key
+func (o *object) without(key string) *object {
+	c := &object{vals: map[string]interface{}{}}
+	for _, k := range o.keys {
+		if k != key {
+			c.set(k, o.vals[k])
+		}
+	}
+	return c
+}
+
+// decode reads a json value with objects as *object and numbers as json.Number
+func decode(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		o := &object{vals: map[string]interface{}{}}
+		for dec.More() {
+			kt, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key, ok := kt.(string)
+			if !ok {
+				return nil, fmt.Errorf("expected a key got %v", kt)
+			}
+			val, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			o.set(key, val)
+		}
+		_, err := dec.Token()
+		return o, err
+	case json.Delim('['):
+		arr := []interface{}{}
+		for dec.More() {
+			val, err := decode(dec)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, val)
+		}
+		_, err := dec.Token()
+		return arr, err
+	}
+	return tok, nil
+}
+
+// plain converts to the encoding/json generic types, as in the adlc ast
+func plain(v interface{}) interface{} {
+	switch v := v.(type) {
+	case *object:
+		m := map[string]interface{}{}
+		for _, k := range v.keys {
+			m[k] = plain(v.vals[k])
+		}
+		return m
+	case []interface{}:
+		arr := make([]interface{}, len(v))
+		for i, e := range v {
+			arr[i] = plain(e)
+		}
+		return arr
+	case json.Number:
+		f, _ := v.Float64()
+		return f
+	}
+	return v
+}
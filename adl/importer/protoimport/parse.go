@@ -0,0 +1,520 @@
+package protoimport
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// File is a parsed .proto file, services, options and extensions are skipped
+type File struct {
+	Name     string
+	Syntax   string
+	Package  string
+	Imports  []string
+	Messages []*Message
+	Enums    []*Enum
+}
+
+type Message struct {
+	Name, Doc string
+	// Fields are in declaration order, a oneof is a field with Oneof set at the position of its first field
+	Fields   []*Field
+	Messages []*Message
+	Enums    []*Enum
+}
+
+type Field struct {
+	Name, Doc string
+	// Label is repeated, optional, required or ""
+	Label string
+	// Type is the value type of a map
+	Type     string
+	MapKey   string
+	Number   int
+	JsonName string
+	Oneof    *Oneof
+}
+
+type Oneof struct {
+	Name, Doc string
+	Fields    []*Field
+}
+
+type Enum struct {
+	Name, Doc string
+	Values    []*EnumValue
+}
+
+type EnumValue struct {
+	Name, Doc string
+	Number    int
+}
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tIdent
+	tInt
+	tFloat
+	tString
+	tSym
+)
+
+type tok struct {
+	kind tokKind
+	text string
+	line int
+	// doc is the comment block right before the token, trailing a comment after it on the same line
+	doc, trailing string
+}
+
+type lexer struct {
+	name string
+	rs   []rune
+	pos  int
+	line int
+}
+
+// lex splits the source into tokens, comments are attached as docs
+func lex(name, src string) ([]*tok, error) {
+	l := &lexer{name: name, rs: []rune(src), line: 1}
+	toks := []*tok{}
+	doc := []string{}
+	blank := false
+	for {
+		// whitespace and comments
+		for l.pos < len(l.rs) {
+			r := l.rs[l.pos]
+			switch {
+			case r == '\n':
+				if l.pos > 0 && l.lineBlank() {
+					blank = true
+				}
+				l.line++
+				l.pos++
+			case unicode.IsSpace(r):
+				l.pos++
+			case l.peek("//"):
+				start := l.pos + 2
+				for l.pos < len(l.rs) && l.rs[l.pos] != '\n' {
+					l.pos++
+				}
+				text := strings.TrimPrefix(strings.TrimLeft(string(l.rs[start:l.pos]), "/"), " ")
+				if len(toks) != 0 && toks[len(toks)-1].line == l.line && len(doc) == 0 {
+					toks[len(toks)-1].trailing = text
+					continue
+				}
+				if blank {
+					doc, blank = nil, false
+				}
+				doc = append(doc, text)
+			case l.peek("/*"):
+				end := strings.Index(string(l.rs[l.pos+2:]), "*/")
+				if end < 0 {
+					return nil, fmt.Errorf("%s:%d: unterminated comment", name, l.line)
+				}
+				text := string(l.rs[l.pos+2 : l.pos+2+end])
+				l.pos += end + 4
+				if blank {
+					doc, blank = nil, false
+				}
+				for _, ln := range strings.Split(text, "\n") {
+					ln = strings.TrimPrefix(strings.TrimSpace(ln), "*")
+					doc = append(doc, strings.TrimPrefix(ln, " "))
+				}
+				l.line += strings.Count(text, "\n")
+			default:
+				goto token
+			}
+		}
+	token:
+		if blank {
+			doc, blank = nil, false
+		}
+		t := &tok{line: l.line, doc: strings.TrimSpace(strings.Join(doc, "\n"))}
+		doc = nil
+		if l.pos >= len(l.rs) {
+			t.kind = tEOF
+			return append(toks, t), nil
+		}
+		start := l.pos
+		r := l.rs[l.pos]
+		switch {
+		case unicode.IsLetter(r) || r == '_' || r == '.' && l.pos+1 < len(l.rs) && (unicode.IsLetter(l.rs[l.pos+1]) || l.rs[l.pos+1] == '_'):
+			t.kind = tIdent
+			l.pos++
+			for l.pos < len(l.rs) && (unicode.IsLetter(l.rs[l.pos]) || unicode.IsDigit(l.rs[l.pos]) || l.rs[l.pos] == '_' || l.rs[l.pos] == '.') {
+				l.pos++
+			}
+		case unicode.IsDigit(r) || r == '.':
+			t.kind = tInt
+			for l.pos < len(l.rs) && (unicode.IsLetter(l.rs[l.pos]) || unicode.IsDigit(l.rs[l.pos]) || l.rs[l.pos] == '.' ||
+				(l.rs[l.pos] == '-' || l.rs[l.pos] == '+') && (l.rs[l.pos-1] == 'e' || l.rs[l.pos-1] == 'E')) {
+				if l.rs[l.pos] == '.' || l.rs[l.pos] == 'e' || l.rs[l.pos] == 'E' {
+					if !strings.HasPrefix(strings.ToLower(string(l.rs[start:l.pos])), "0x") {
+						t.kind = tFloat
+					}
+				}
+				l.pos++
+			}
+		case r == '"' || r == '\'':
+			t.kind = tString
+			l.pos++
+			for l.pos < len(l.rs) && l.rs[l.pos] != r {
+				if l.rs[l.pos] == '\\' {
+					l.pos++
+				}
+				if l.pos < len(l.rs) && l.rs[l.pos] == '\n' {
+					return nil, fmt.Errorf("%s:%d: unterminated string", name, l.line)
+				}
+				l.pos++
+			}
+			if l.pos >= len(l.rs) {
+				return nil, fmt.Errorf("%s:%d: unterminated string", name, l.line)
+			}
+			l.pos++
+		default:
+			t.kind = tSym
+			l.pos++
+		}
+		t.text = string(l.rs[start:l.pos])
+		toks = append(toks, t)
+	}
+}
+
+func (l *lexer) peek(s string) bool {
+	return strings.HasPrefix(string(l.rs[l.pos:min(l.pos+len(s), len(l.rs))]), s)
+}
+
+// lineBlank is true when the line ending at pos is empty
+func (l *lexer) lineBlank() bool {
+	for i := l.pos - 1; i >= 0 && l.rs[i] != '\n'; i-- {
+		if !unicode.IsSpace(l.rs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+type parser struct {
+	name string
+	toks []*tok
+	pos  int
+}
+
+// Parse parses a proto2 or proto3 file
+func Parse(name, src string) (f *File, err error) {
+	toks, err := lex(name, src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{name: name, toks: toks}
+	defer func() {
+		if r := recover(); r != nil {
+			pe, ok := r.(parseError)
+			if !ok {
+				panic(r)
+			}
+			f, err = nil, pe
+		}
+	}()
+	return p.file(), nil
+}
+
+type parseError struct{ error }
+
+func (p *parser) fail(format string, args ...interface{}) {
+	t := p.toks[p.pos]
+	panic(parseError{fmt.Errorf("%s:%d: %s", p.name, t.line, fmt.Sprintf(format, args...))})
+}
+
+func (p *parser) peek() *tok {
+	return p.toks[p.pos]
+}
+
+func (p *parser) next() *tok {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) is(text string) bool {
+	t := p.peek()
+	return t.kind != tString && t.text == text
+}
+
+func (p *parser) expect(text string) *tok {
+	if !p.is(text) {
+		p.fail("expected '%s' got '%s'", text, p.peek().text)
+	}
+	return p.next()
+}
+
+func (p *parser) ident() *tok {
+	if p.peek().kind != tIdent {
+		p.fail("expected an identifier got '%s'", p.peek().text)
+	}
+	return p.next()
+}
+
+func (p *parser) str() string {
+	t := p.next()
+	if t.kind != tString {
+		p.fail("expected a string got '%s'", t.text)
+	}
+	return unquote(t.text)
+}
+
+func (p *parser) integer() int {
+	neg := false
+	if p.is("-") {
+		p.next()
+		neg = true
+	}
+	t := p.next()
+	n, err := strconv.ParseInt(t.text, 0, 64)
+	if t.kind != tInt || err != nil {
+		p.fail("expected an integer got '%s'", t.text)
+	}
+	if neg {
+		n = -n
+	}
+	return int(n)
+}
+
+func unquote(s string) string {
+	if strings.HasPrefix(s, "'") {
+		s = `"` + strings.Replace(strings.Replace(s[1:len(s)-1], `\'`, `'`, -1), `"`, `\"`, -1) + `"`
+	}
+	u, err := strconv.Unquote(s)
+	if err != nil {
+		return s[1 : len(s)-1]
+	}
+	return u
+}
+
+// skip skips a statement up to its ';' or a block, nested blocks included
+func (p *parser) skip() {
+	depth := 0
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tEOF:
+			p.fail("unexpected end of file")
+		case t.kind == tSym && t.text == "{":
+			depth++
+		case t.kind == tSym && t.text == "}":
+			depth--
+			if depth == 0 {
+				if p.is(";") {
+					p.next()
+				}
+				return
+			}
+		case t.kind == tSym && t.text == ";" && depth == 0:
+			return
+		}
+	}
+}
+
+func (p *parser) file() *File {
+	f := &File{Name: p.name, Syntax: "proto2"}
+	for p.peek().kind != tEOF {
+		switch {
+		case p.is(";"):
+			p.next()
+		case p.is("syntax"), p.is("edition"):
+			p.next()
+			p.expect("=")
+			f.Syntax = p.str()
+			p.expect(";")
+		case p.is("package"):
+			p.next()
+			f.Package = p.ident().text
+			p.expect(";")
+		case p.is("import"):
+			p.next()
+			if p.is("public") || p.is("weak") {
+				p.next()
+			}
+			f.Imports = append(f.Imports, p.str())
+			p.expect(";")
+		case p.is("message"):
+			f.Messages = append(f.Messages, p.message())
+		case p.is("enum"):
+			f.Enums = append(f.Enums, p.enum())
+		case p.is("option"), p.is("service"), p.is("extend"):
+			p.skip()
+		default:
+			p.fail("unexpected '%s'", p.peek().text)
+		}
+	}
+	return f
+}
+
+func (p *parser) message() *Message {
+	doc := p.expect("message").doc
+	m := &Message{Name: p.ident().text, Doc: doc}
+	p.expect("{")
+	for !p.is("}") {
+		switch {
+		case p.is(";"):
+			p.next()
+		case p.is("message"):
+			m.Messages = append(m.Messages, p.message())
+		case p.is("enum"):
+			m.Enums = append(m.Enums, p.enum())
+		case p.is("oneof"):
+			t := p.next()
+			o := &Oneof{Name: p.ident().text, Doc: t.doc}
+			p.expect("{")
+			for !p.is("}") {
+				if p.is("option") || p.is(";") {
+					p.skip()
+					continue
+				}
+				o.Fields = append(o.Fields, p.field())
+			}
+			p.expect("}")
+			m.Fields = append(m.Fields, &Field{Name: o.Name, Doc: o.Doc, Oneof: o})
+		case p.is("option"), p.is("reserved"), p.is("extensions"), p.is("extend"):
+			p.skip()
+		case p.is("group"):
+			p.fail("groups aren't supported")
+		case p.peek().kind == tEOF:
+			p.fail("unexpected end of file in message %s", m.Name)
+		default:
+			m.Fields = append(m.Fields, p.field())
+		}
+	}
+	p.expect("}")
+	return m
+}
+
+func (p *parser) field() *Field {
+	first := p.peek()
+	f := &Field{Doc: first.doc}
+	if p.is("repeated") || p.is("optional") || p.is("required") {
+		f.Label = p.next().text
+	}
+	if p.is("map") && p.toks[p.pos+1].text == "<" {
+		p.next()
+		p.expect("<")
+		f.MapKey = p.ident().text
+		p.expect(",")
+		f.Type = p.ident().text
+		p.expect(">")
+	} else {
+		f.Type = p.ident().text
+	}
+	f.Name = p.ident().text
+	p.expect("=")
+	f.Number = p.integer()
+	if p.is("[") {
+		p.options(func(name string) {
+			if name == "json_name" {
+				f.JsonName = p.str()
+				return
+			}
+			p.value()
+		})
+	}
+	end := p.expect(";")
+	if f.Doc == "" {
+		f.Doc = end.trailing
+	}
+	return f
+}
+
+// options parses [name = value, ...], value reads the value of each option
+func (p *parser) options(value func(name string)) {
+	p.expect("[")
+	for {
+		name := ""
+		if p.is("(") {
+			for !p.is(")") {
+				name += p.next().text
+			}
+			name = "(" + name + p.next().text
+		}
+		if p.peek().kind == tIdent {
+			name += p.next().text
+		}
+		p.expect("=")
+		value(name)
+		if !p.is(",") {
+			break
+		}
+		p.next()
+	}
+	p.expect("]")
+}
+
+// value skips an option value, aggregates included
+func (p *parser) value() {
+	if p.is("{") {
+		depth := 0
+		for {
+			t := p.next()
+			if t.kind == tEOF {
+				p.fail("unexpected end of file")
+			}
+			if t.kind == tSym && t.text == "{" {
+				depth++
+			}
+			if t.kind == tSym && t.text == "}" {
+				depth--
+				if depth == 0 {
+					return
+				}
+			}
+		}
+	}
+	if p.is("-") {
+		p.next()
+	}
+	p.next()
+}
+
+func (p *parser) enum() *Enum {
+	doc := p.expect("enum").doc
+	e := &Enum{Name: p.ident().text, Doc: doc}
+	p.expect("{")
+	for !p.is("}") {
+		switch {
+		case p.is(";"):
+			p.next()
+		case p.is("option"), p.is("reserved"):
+			p.skip()
+		case p.peek().kind == tEOF:
+			p.fail("unexpected end of file in enum %s", e.Name)
+		default:
+			t := p.ident()
+			v := &EnumValue{Name: t.text, Doc: t.doc}
+			p.expect("=")
+			v.Number = p.integer()
+			if p.is("[") {
+				p.options(func(string) { p.value() })
+			}
+			end := p.expect(";")
+			if v.Doc == "" {
+				v.Doc = end.trailing
+			}
+			e.Values = append(e.Values, v)
+		}
+	}
+	p.expect("}")
+	return e
+}
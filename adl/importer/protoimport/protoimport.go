@@ -0,0 +1,370 @@
+// Package protoimport translates protobuf definitions into ADL modules, without protoc.
+//
+// Each package becomes a module, messages become structs, enums unions of Void branches and a oneof a union held in a
+// Nullable field of its message. Nested messages and enums are named Outer_Inner, as in Go. Following the proto3 JSON
+// mapping fields are lowerCamelCase, repeated fields are Vector, maps StringMap, message fields and optional fields
+// Nullable, and the other fields default to their zero value. The well known types map to their JSON form, ie
+// google.protobuf.Timestamp is String and google.protobuf.StringValue Nullable<String>.
+//
+// Comments before a definition, or after it on the same line, become docs. Services, options and extensions are skipped.
+package protoimport
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+type Config struct {
+	// Include are the directories imports are searched for in, defaults to the working directory
+	Include []string
+}
+
+// Load converts the files, the files they import are read for their definitions
+func Load(cfg Config, files ...string) ([]adl.Module, error) {
+	include := cfg.Include
+	if len(include) == 0 {
+		include = []string{"."}
+	}
+	parsed := []*File{}
+	deps := []*File{}
+	seen := map[string]bool{}
+	var read func(path string, dep bool) error
+	read = func(path string, dep bool) error {
+		by, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := Parse(path, string(by))
+		if err != nil {
+			return err
+		}
+		if dep {
+			deps = append(deps, f)
+		} else {
+			parsed = append(parsed, f)
+		}
+		for _, im := range f.Imports {
+			if strings.HasPrefix(im, "google/protobuf/") || seen[im] {
+				continue
+			}
+			seen[im] = true
+			found := ""
+			for _, dir := range include {
+				if _, err := os.Stat(filepath.Join(dir, im)); err == nil {
+					found = filepath.Join(dir, im)
+					break
+				}
+			}
+			if found == "" {
+				return fmt.Errorf("%s: import \"%s\" not found in %v", path, im, include)
+			}
+			if err := read(found, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, file := range files {
+		// files that are also imported are read once
+		for _, dir := range include {
+			if rel, err := filepath.Rel(dir, file); err == nil && !strings.HasPrefix(rel, "..") {
+				seen[filepath.ToSlash(rel)] = true
+			}
+		}
+		if err := read(file, false); err != nil {
+			return nil, err
+		}
+	}
+	return Convert(parsed, deps)
+}
+
+// Convert translates the files, types can be references to definitions in deps
+func Convert(files, deps []*File) ([]adl.Module, error) {
+	c := &converter{symbols: map[string]symbol{}, builders: map[string]*adlbuild.ModuleBuilder{}}
+	for _, f := range append(append([]*File{}, files...), deps...) {
+		mod := moduleName(f)
+		prefix := ""
+		if f.Package != "" {
+			prefix = f.Package + "."
+		}
+		c.declare(mod, prefix, "", f.Messages, f.Enums)
+	}
+	modules := []string{}
+	for _, f := range files {
+		mod := moduleName(f)
+		b, ex := c.builders[mod]
+		if !ex {
+			b = adlbuild.NewModule(mod)
+			c.builders[mod] = b
+			modules = append(modules, mod)
+		}
+		scope := strings.TrimSuffix(f.Package, ".")
+		for _, m := range f.Messages {
+			if err := c.message(b, scope, "", m); err != nil {
+				return nil, fmt.Errorf("%s: %v", f.Name, err)
+			}
+		}
+		for _, e := range f.Enums {
+			c.enum(b, "", e)
+		}
+	}
+	sort.Strings(modules)
+	mods := []adl.Module{}
+	for _, name := range modules {
+		mod, err := c.builders[name].Build()
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, mod)
+	}
+	return mods, nil
+}
+
+// moduleName is the package, or the file name without a package
+func moduleName(f *File) string {
+	if f.Package != "" {
+		return f.Package
+	}
+	return ident(strings.TrimSuffix(filepath.Base(f.Name), filepath.Ext(f.Name)))
+}
+
+type symbol struct {
+	sn   adl.ScopedName
+	enum *Enum
+}
+
+type converter struct {
+	// symbols are the decls by full proto name, ie pkg.Outer.Inner
+	symbols  map[string]symbol
+	builders map[string]*adlbuild.ModuleBuilder
+}
+
+func (c *converter) declare(mod, prefix, outer string, msgs []*Message, enums []*Enum) {
+	for _, m := range msgs {
+		c.symbols[prefix+m.Name] = symbol{sn: adl.ScopedName{ModuleName: mod, Name: outer + m.Name}}
+		c.declare(mod, prefix+m.Name+".", outer+m.Name+"_", m.Messages, m.Enums)
+	}
+	for _, e := range enums {
+		c.symbols[prefix+e.Name] = symbol{sn: adl.ScopedName{ModuleName: mod, Name: outer + e.Name}, enum: e}
+	}
+}
+
+// resolve finds a type name from the scope it is used in, searching the enclosing scopes outwards
+func (c *converter) resolve(name, scope string) (symbol, bool) {
+	if strings.HasPrefix(name, ".") {
+		s, ok := c.symbols[name[1:]]
+		return s, ok
+	}
+	for {
+		full := name
+		if scope != "" {
+			full = scope + "." + name
+		}
+		if s, ok := c.symbols[full]; ok {
+			return s, true
+		}
+		if scope == "" {
+			return symbol{}, false
+		}
+		if i := strings.LastIndex(scope, "."); i >= 0 {
+			scope = scope[:i]
+		} else {
+			scope = ""
+		}
+	}
+}
+
+var scalars = map[string]adl.TypeExpr{
+	"double": adlbuild.Double, "float": adlbuild.Float,
+	"int32": adlbuild.Int32, "sint32": adlbuild.Int32, "sfixed32": adlbuild.Int32,
+	"int64": adlbuild.Int64, "sint64": adlbuild.Int64, "sfixed64": adlbuild.Int64,
+	"uint32": adlbuild.Word32, "fixed32": adlbuild.Word32,
+	"uint64": adlbuild.Word64, "fixed64": adlbuild.Word64,
+	"bool": adlbuild.Bool, "string": adlbuild.String, "bytes": adlbuild.Bytes,
+}
+
+// wellKnown are the google.protobuf types in their JSON form
+var wellKnown = map[string]adl.TypeExpr{
+	"Timestamp":   adlbuild.String,
+	"Duration":    adlbuild.String,
+	"FieldMask":   adlbuild.String,
+	"Struct":      adlbuild.StringMap(adlbuild.Json),
+	"Value":       adlbuild.Json,
+	"ListValue":   adlbuild.Vector(adlbuild.Json),
+	"Any":         adlbuild.Json,
+	"Empty":       adlbuild.Json,
+	"DoubleValue": adlbuild.Nullable(adlbuild.Double),
+	"FloatValue":  adlbuild.Nullable(adlbuild.Float),
+	"Int64Value":  adlbuild.Nullable(adlbuild.Int64),
+	"UInt64Value": adlbuild.Nullable(adlbuild.Word64),
+	"Int32Value":  adlbuild.Nullable(adlbuild.Int32),
+	"UInt32Value": adlbuild.Nullable(adlbuild.Word32),
+	"BoolValue":   adlbuild.Nullable(adlbuild.Bool),
+	"StringValue": adlbuild.Nullable(adlbuild.String),
+	"BytesValue":  adlbuild.Nullable(adlbuild.Bytes),
+}
+
+// kind of a field type
+type kind int
+
+const (
+	kScalar kind = iota
+	kEnum
+	kMessage
+	// kWellKnown are messages in their JSON form, the wrappers are already Nullable
+	kWellKnown
+)
+
+func (c *converter) typeExpr(name, scope string) (adl.TypeExpr, kind, *Enum, error) {
+	if te, ok := scalars[name]; ok {
+		return te, kScalar, nil, nil
+	}
+	if wk := strings.TrimPrefix(strings.TrimPrefix(name, "."), "google.protobuf."); wk != strings.TrimPrefix(name, ".") {
+		if te, ok := wellKnown[wk]; ok {
+			return te, kWellKnown, nil, nil
+		}
+	}
+	s, ok := c.resolve(name, scope)
+	if !ok {
+		return adl.TypeExpr{}, 0, nil, fmt.Errorf("unknown type '%s'", name)
+	}
+	if s.enum != nil {
+		return adlbuild.Ref(s.sn.ModuleName, s.sn.Name), kEnum, s.enum, nil
+	}
+	return adlbuild.Ref(s.sn.ModuleName, s.sn.Name), kMessage, nil, nil
+}
+
+func (c *converter) message(b *adlbuild.ModuleBuilder, scope, outer string, m *Message) error {
+	name := outer + m.Name
+	scope = strings.TrimPrefix(scope+"."+m.Name, ".")
+	d := b.Struct(name).Doc(m.Doc)
+	for _, f := range m.Fields {
+		if f.Oneof != nil {
+			union := name + "_" + gen.ExportName(jsonName(f.Name))
+			// the doc is on the field
+			ud := b.Union(union)
+			for _, of := range f.Oneof.Fields {
+				te, _, _, err := c.typeExpr(of.Type, scope)
+				if err != nil {
+					return fmt.Errorf("%s.%s: %v", name, of.Name, err)
+				}
+				field(ud, of, te)
+			}
+			field(d, f, adlbuild.Nullable(adlbuild.Ref("", union))).Default(nil)
+			continue
+		}
+		te, k, enum, err := c.typeExpr(f.Type, scope)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %v", name, f.Name, err)
+		}
+		switch {
+		case f.MapKey != "":
+			field(d, f, adlbuild.StringMap(te)).Default(map[string]interface{}{})
+		case f.Label == "repeated":
+			field(d, f, adlbuild.Vector(te)).Default([]interface{}{})
+		case f.Label == "required":
+			field(d, f, te)
+		case k == kWellKnown && te.TypeRef.Primitive != nil && *te.TypeRef.Primitive == "Nullable":
+			field(d, f, te).Default(nil)
+		case f.Label == "optional" || k == kMessage || k == kWellKnown:
+			field(d, f, adlbuild.Nullable(te)).Default(nil)
+		case k == kEnum:
+			fb := field(d, f, te)
+			if len(enum.Values) != 0 {
+				fb.Default(enum.Values[0].Name)
+			}
+		default:
+			fb := field(d, f, te)
+			if z, ok := zero(f.Type); ok {
+				fb.Default(z)
+			}
+		}
+	}
+	for _, nm := range m.Messages {
+		if err := c.message(b, scope, name+"_", nm); err != nil {
+			return err
+		}
+	}
+	for _, e := range m.Enums {
+		c.enum(b, name+"_", e)
+	}
+	return nil
+}
+
+func field(d *adlbuild.DeclBuilder, f *Field, te adl.TypeExpr) *adlbuild.FieldBuilder {
+	name := jsonName(f.Name)
+	fb := d.Field(name, te).Doc(f.Doc)
+	if f.JsonName != "" && f.JsonName != name {
+		fb.SerializedName(f.JsonName)
+	}
+	return fb
+}
+
+// zero is the default of a scalar
+func zero(scalar string) (interface{}, bool) {
+	switch scalar {
+	case "bool":
+		return false, true
+	case "string", "bytes":
+		return "", true
+	}
+	_, ok := scalars[scalar]
+	return 0, ok
+}
+
+func (c *converter) enum(b *adlbuild.ModuleBuilder, outer string, e *Enum) {
+	d := b.Union(outer + e.Name).Doc(e.Doc)
+	seen := map[string]bool{}
+	for _, v := range e.Values {
+		name := jsonName(strings.ToLower(v.Name))
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		fb := d.Field(name, adlbuild.Void).Doc(v.Doc)
+		if name != v.Name {
+			fb.SerializedName(v.Name)
+		}
+	}
+}
+
+// jsonName is protoc's json name of a field, ie user_id is userId
+func jsonName(name string) string {
+	var buf strings.Builder
+	upper := false
+	for _, r := range name {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+		}
+		buf.WriteRune(r)
+		upper = false
+	}
+	return buf.String()
+}
+
+// ident replaces the characters of a file name that can't be in a module name
+func ident(s string) string {
+	rs := []rune(s)
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			rs[i] = '_'
+		}
+	}
+	if len(rs) == 0 || unicode.IsDigit(rs[0]) {
+		rs = append([]rune{'_'}, rs...)
+	}
+	return string(rs)
+}
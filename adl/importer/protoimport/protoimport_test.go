@@ -0,0 +1,100 @@
+package protoimport
+
+import (
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl/adlbuild"
+)
+
+var update = flag.Bool("update", false, "rewrite the imported adl in testdata")
+
+func TestLoad(t *testing.T) {
+	mods, err := Load(Config{Include: []string{"testdata"}}, filepath.Join("testdata", "shop", "order.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 1 || mods[0].Name != "shop.v1" {
+		t.Fatalf("expected only the shop.v1 module got %d", len(mods))
+	}
+	src, err := adlbuild.Source(mods[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "shop", "v1.adl")
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src != string(want) {
+		t.Errorf("imported adl differs from %s, rerun with -update\n%s", golden, src)
+	}
+	mods, err = Load(Config{Include: []string{"testdata"}}, filepath.Join("testdata", "shop", "order.proto"), filepath.Join("testdata", "common", "money.proto"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 2 || mods[0].Name != "common" {
+		t.Errorf("expected the common and shop.v1 modules got %d", len(mods))
+	}
+	if _, err := Load(Config{}, filepath.Join("testdata", "shop", "order.proto")); err == nil || !strings.Contains(err.Error(), `import "common/money.proto" not found`) {
+		t.Errorf("expected an import error got %v", err)
+	}
+}
+
+func TestParse(t *testing.T) {
+	f, err := Parse("a.proto", `
+// detached
+
+// Doc of A
+message A { // not a doc
+  int32 x = 1; // trailing doc
+  /// leading doc
+  repeated string ys = 2 [packed = true, (x.y).z = -1];
+  map<int32, A> m = 3;
+  enum E { E_ZERO = 0; E_NEG = -1; }
+  oneof o { A a = 4; }
+}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := f.Messages[0]
+	if a.Doc != "Doc of A" || a.Fields[0].Doc != "trailing doc" || a.Fields[1].Doc != "leading doc" {
+		t.Errorf("unexpected docs %q %q %q", a.Doc, a.Fields[0].Doc, a.Fields[1].Doc)
+	}
+	if a.Fields[1].Label != "repeated" || a.Fields[2].MapKey != "int32" || a.Fields[2].Type != "A" || a.Fields[3].Oneof == nil {
+		t.Errorf("unexpected fields %+v", a.Fields)
+	}
+	if a.Enums[0].Values[1].Number != -1 {
+		t.Errorf("expected a negative enum value")
+	}
+	for _, tc := range []struct{ src, err string }{
+		{`message A { int32 x = 1 }`, "a.proto:1: expected ';' got '}'"},
+		{`message A {`, "a.proto:1: unexpected end of file in message A"},
+		{"message A {\n group G = 1 {} }", "a.proto:2: groups aren't supported"},
+		{`message A { string s = "x"; }`, "expected an integer"},
+		{`import "x`, "unterminated string"},
+		{`/* x`, "unterminated comment"},
+	} {
+		if _, err := Parse("a.proto", tc.src); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected %q got %v", tc.src, tc.err, err)
+		}
+	}
+}
+
+func TestConvertErrors(t *testing.T) {
+	f, err := Parse("a.proto", `message A { B b = 1; }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Convert([]*File{f}, nil); err == nil || err.Error() != "a.proto: A.b: unknown type 'B'" {
+		t.Errorf("expected an unknown type error got %v", err)
+	}
+}
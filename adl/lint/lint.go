@@ -0,0 +1,325 @@
+// Package lint checks ADL modules against a set of pluggable rules.
+//
+// A rule looks at a resolved module, the adlc ast form, and reports diagnostics against its decls, fields and
+// imports, which are located in the module's source. The diagnostics are adl.DiagMessages.
+//
+// Each rule has a default severity, which the config overrides or turns off, and may take options.
+// Findings are suppressed with a LintIgnore annotation, of a rule name or a list of them, "*" for all rules,
+// on the field, the decl or the module. LintIgnore is matched by name, declare it where it suits, ie
+//
+//	type LintIgnore = Vector<String>;
+//
+//	@LintIgnore ["missing-doc", "naming"]
+//	struct point3d { ... };
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/usage"
+)
+
+type Severity int
+
+// the values are those of the lsp DiagnosticSeverity
+const (
+	Off Severity = iota
+	Error
+	Warning
+	Info
+	Hint
+)
+
+var severities = []string{"off", "error", "warning", "info", "hint"}
+
+func (s Severity) String() string {
+	return severities[s]
+}
+
+// ParseSeverity parses off, error, warning, info or hint
+func ParseSeverity(s string) (Severity, error) {
+	for i, n := range severities {
+		if n == s {
+			return Severity(i), nil
+		}
+	}
+	return Off, fmt.Errorf("unknown severity '%s', expected one of %s", s, strings.Join(severities, ", "))
+}
+
+// Rule is a named check of a module
+type Rule struct {
+	// Name is how the rule is configured and suppressed, ie missing-doc
+	Name string
+	Doc  string
+	// Severity is the default severity of the rule's diagnostics, Off rules are only run when configured
+	Severity Severity
+	Check    func(p *Pass)
+}
+
+var rules = map[string]Rule{}
+
+// Register adds a rule, replacing a rule of the same name
+func Register(r Rule) {
+	rules[r.Name] = r
+}
+
+// Rules are the registered rules in name order
+func Rules() []Rule {
+	rs := []Rule{}
+	for _, r := range rules {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].Name < rs[j].Name })
+	return rs
+}
+
+// Config configures the rules by name, unconfigured rules run at their default severity
+type Config struct {
+	Rules map[string]RuleConfig `json:"rules"`
+}
+
+type RuleConfig struct {
+	// Severity is off, error, warning, info or hint, empty for the rule's default
+	Severity string                 `json:"severity,omitempty"`
+	Options  map[string]interface{} `json:"options,omitempty"`
+}
+
+// ReadConfig reads a json config
+func ReadConfig(by []byte) (Config, error) {
+	cfg := Config{}
+	if err := json.Unmarshal(by, &cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Check()
+}
+
+// Check reports unknown rules and severities
+func (cfg Config) Check() error {
+	for name, rc := range cfg.Rules {
+		if _, ex := rules[name]; !ex {
+			return fmt.Errorf("unknown lint rule '%s'", name)
+		}
+		if rc.Severity != "" {
+			if _, err := ParseSeverity(rc.Severity); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Diag is a rule's finding, Line and Column are zero based as in adl.DiagMessage
+type Diag struct {
+	Rule     string
+	Severity Severity
+	Msg      string
+	line     int
+	column   int
+	text     string
+}
+
+func (d Diag) Line() int   { return d.line }
+func (d Diag) Column() int { return d.column }
+func (d Diag) Len() int    { return len([]rune(d.text)) }
+
+// Text is the source of the name the diagnostic is on
+func (d Diag) Text() string { return d.text }
+
+// Message is the message with the rule name, ie "'point' isn't UpperCamel (naming)"
+func (d Diag) Message() string { return d.Msg + " (" + d.Rule + ")" }
+
+func (d Diag) String() string {
+	return fmt.Sprintf("%d:%d: %s: %s", d.line+1, d.column+1, d.Severity, d.Message())
+}
+
+// Loc is what a diagnostic is on, the field of a decl, a decl, an import by its source text
+// ie common.* or common.db.DbTable, or the module when empty
+type Loc struct {
+	Decl, Field, Import string
+}
+
+// Pass is a rule checking a module
+type Pass struct {
+	Module adl.Module
+	// AllMod are the resolved modules, Module included
+	AllMod map[string]adl.Module
+	// Usage is what the module's decls and imports are used by
+	Usage   *usage.Usage
+	Options map[string]interface{}
+	rule    Rule
+	sev     Severity
+	names   map[Loc]*name
+	diags   *[]Diag
+}
+
+// name is where a decl, field, import or the module is named in the source
+type name struct {
+	line, column int
+	text         string
+}
+
+// Report adds a diagnostic, unless the rule is suppressed at the loc
+func (p *Pass) Report(at Loc, format string, args ...interface{}) {
+	if p.ignored(at) {
+		return
+	}
+	d := Diag{Rule: p.rule.Name, Severity: p.sev, Msg: fmt.Sprintf(format, args...)}
+	n, ex := p.names[at]
+	if !ex {
+		n = p.names[Loc{Decl: at.Decl}]
+	}
+	if n == nil {
+		n = p.names[Loc{}]
+	}
+	if n != nil {
+		d.line, d.column, d.text = n.line, n.column, n.text
+	}
+	*p.diags = append(*p.diags, d)
+}
+
+// String is the string option, or def when it isn't set
+func (p *Pass) String(option, def string) string {
+	if s, ok := p.Options[option].(string); ok {
+		return s
+	}
+	return def
+}
+
+// IgnoreAnno is the name of the annotation suppressing rules
+const IgnoreAnno = "LintIgnore"
+
+func (p *Pass) ignored(at Loc) bool {
+	annos := []adl.Annotations{p.Module.Annotations}
+	if decl, ex := p.Module.Decls[at.Decl]; ex {
+		annos = append(annos, decl.Annotations)
+		for _, f := range decl.Fields() {
+			if f.Name == at.Field {
+				annos = append(annos, f.Annotations)
+			}
+		}
+	}
+	for _, ans := range annos {
+		for _, an := range ans {
+			if an.Key.Name != IgnoreAnno {
+				continue
+			}
+			vs, ok := an.Val.([]interface{})
+			if !ok {
+				vs = []interface{}{an.Val}
+			}
+			for _, v := range vs {
+				if v == p.rule.Name || v == "*" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// Lint runs the configured rules over the module of src.
+// allmod holds the modules it imports, resolved by adlc or adlbuild.Parse, and may hold the module itself, which is
+// otherwise read from src. Source with syntax errors isn't linted, the errors are returned.
+func Lint(src string, allmod map[string]adl.Module, cfg Config) ([]Diag, error) {
+	if err := cfg.Check(); err != nil {
+		return nil, err
+	}
+	// the names of a module that doesn't parse can't be located
+	if _, _, _, _, errs := adl.BuildAdlAST(src); errs.Error() != nil {
+		return nil, errs.Error()
+	}
+	u, err := usage.Analyze(src, allmod)
+	if err != nil {
+		return nil, err
+	}
+	names := index(u)
+	diags := []Diag{}
+	for _, r := range Rules() {
+		rc := cfg.Rules[r.Name]
+		sev := r.Severity
+		if rc.Severity != "" {
+			sev, _ = ParseSeverity(rc.Severity)
+		}
+		if sev == Off {
+			continue
+		}
+		r.Check(&Pass{
+			Module:  u.Module,
+			AllMod:  u.AllMod,
+			Usage:   u,
+			Options: rc.Options,
+			rule:    r,
+			sev:     sev,
+			names:   names,
+			diags:   &diags,
+		})
+	}
+	sort.SliceStable(diags, func(i, j int) bool {
+		if diags[i].line != diags[j].line {
+			return diags[i].line < diags[j].line
+		}
+		return diags[i].column < diags[j].column
+	})
+	return diags, nil
+}
+
+// index locates the names of the module, its imports, decls and fields
+func index(u *usage.Usage) map[Loc]*name {
+	names := map[Loc]*name{}
+	leaves := func(n *cst.Node) []*cst.Token {
+		ts := []*cst.Token{}
+		for _, c := range n.Children {
+			if c.Token != nil {
+				ts = append(ts, c.Token)
+			}
+		}
+		return ts
+	}
+	span := func(ts []*cst.Token) *name {
+		n := &name{line: ts[0].Line - 1, column: ts[0].Column}
+		for _, t := range ts {
+			n.text += t.Text
+		}
+		return n
+	}
+	ms := usage.ModuleStatement(u.File)
+	if ms == nil {
+		return names
+	}
+	ts := leaves(ms)
+	for i, t := range ts {
+		if t.Text == "{" {
+			if i > 1 {
+				names[Loc{}] = span(ts[1:i])
+			}
+			break
+		}
+	}
+	for _, imp := range u.Imports {
+		names[Loc{Import: imp.Text}] = &name{line: imp.Line, column: imp.Column, text: imp.Text}
+	}
+	for _, n := range ms.Children {
+		if n.Rule != "StructOrUnion" && n.Rule != "TypeOrNewtype" {
+			continue
+		}
+		// error recovery leaves statements without names
+		dts := leaves(n)
+		if len(dts) < 2 {
+			continue
+		}
+		dn := dts[1]
+		names[Loc{Decl: dn.Text}] = span([]*cst.Token{dn})
+		for _, c := range n.Children {
+			if fts := leaves(c); c.Rule == "FieldStatement" && len(fts) != 0 {
+				fn := fts[0]
+				names[Loc{Decl: dn.Text, Field: fn.Text}] = span([]*cst.Token{fn})
+			}
+		}
+	}
+	return names
+}
@@ -0,0 +1,132 @@
+package lint
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+const demoAdl = `module demo {
+
+import common.*;
+import common.db.DbTable;
+import common.http.Get;
+
+type LintIgnore = Vector<String>;
+
+/// A point
+struct point {
+  Int32 X;
+  Nullable<Nullable<String>> label;
+  Instant when;
+};
+
+type MaybeStr = Nullable<String>;
+
+/// Optional
+newtype Opt = Nullable<MaybeStr>;
+
+@LintIgnore ["naming", "missing-doc"]
+struct bad_name {
+  Int32 Y;
+  @LintIgnore "*"
+  Nullable<Opt> ok;
+};
+
+annotation point::X LintIgnore ["naming"];
+
+struct Exported {
+  Int32 x;
+};
+};
+`
+
+func lint(t *testing.T, src string, cfg Config) []string {
+	ds, err := Lint(src, adltest.Modules(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := []string{}
+	for _, d := range ds {
+		got = append(got, d.String()+" "+d.Text())
+	}
+	return got
+}
+
+func TestLint(t *testing.T) {
+	got := lint(t, demoAdl, Config{})
+	want := []string{
+		"3:8: info: common.* imports all of common, import the decls used by name: Instant (wildcard-import) common.*",
+		"4:8: warning: import 'common.db.DbTable' isn't used (unused-import) common.db.DbTable",
+		"5:8: warning: import 'common.http.Get' isn't used (unused-import) common.http.Get",
+		"10:8: warning: decl 'point' doesn't match ^[A-Z][a-zA-Z0-9]*$ (naming) point",
+		"12:30: warning: Nullable<Nullable<String>> nests Nullable, null can't tell the two apart (nullable-nullable) label",
+		"19:9: warning: Nullable<demo.MaybeStr> nests Nullable, null can't tell the two apart (nullable-nullable) Opt",
+		"30:8: info: struct 'Exported' has no doc comment (missing-doc) Exported",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestConfig(t *testing.T) {
+	cfg, err := ReadConfig([]byte(`{"rules": {
+  "naming": {"options": {"decl": "^[a-z_]+$"}},
+  "unused-decl": {"severity": "hint"},
+  "wildcard-import": {"severity": "off"},
+  "unused-import": {"severity": "error"},
+  "missing-doc": {"severity": "off"},
+  "nullable-nullable": {"severity": "off"}
+}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := lint(t, demoAdl, cfg)
+	want := []string{
+		"4:8: error: import 'common.db.DbTable' isn't used (unused-import) common.db.DbTable",
+		"5:8: error: import 'common.http.Get' isn't used (unused-import) common.http.Get",
+		"7:6: warning: decl 'LintIgnore' doesn't match ^[a-z_]+$ (naming) LintIgnore",
+		"10:8: hint: struct 'point' isn't used (unused-decl) point",
+		"16:6: warning: decl 'MaybeStr' doesn't match ^[a-z_]+$ (naming) MaybeStr",
+		"19:9: warning: decl 'Opt' doesn't match ^[a-z_]+$ (naming) Opt",
+		"30:8: warning: decl 'Exported' doesn't match ^[a-z_]+$ (naming) Exported",
+		"30:8: hint: struct 'Exported' isn't used (unused-decl) Exported",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+	for _, bad := range []string{
+		`{"rules": {"no-such-rule": {}}}`,
+		`{"rules": {"naming": {"severity": "loud"}}}`,
+	} {
+		if _, err := ReadConfig([]byte(bad)); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+func TestRegister(t *testing.T) {
+	defer delete(rules, "no-points")
+	Register(Rule{Name: "no-points", Severity: Error, Check: func(p *Pass) {
+		if _, ex := p.Module.Decls["point"]; ex {
+			p.Report(Loc{Decl: "point", Field: "when"}, "no points")
+		}
+	}})
+	got := lint(t, demoAdl, Config{Rules: map[string]RuleConfig{
+		"naming": {Severity: "off"}, "missing-doc": {Severity: "off"}, "nullable-nullable": {Severity: "off"},
+		"unused-import": {Severity: "off"}, "wildcard-import": {Severity: "off"},
+	}})
+	want := "13:11: error: no points (no-points) when"
+	if strings.Join(got, "\n") != want {
+		t.Errorf("expected %s got %v", want, got)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	for _, src := range []string{adltest.OneOfEachAdl[:364], "module demo {\nstruct {\n", ""} {
+		if ds, err := Lint(src, adltest.Modules(), Config{}); err == nil {
+			t.Errorf("%q: expected a syntax error, got %v", src, ds)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package lint
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/usage"
+)
+
+func init() {
+	Register(Rule{
+		Name:     "naming",
+		Doc:      "decls are UpperCamel and fields lowerCamel, the decl and field options are regular expressions replacing these",
+		Severity: Warning,
+		Check:    naming,
+	})
+	Register(Rule{
+		Name:     "missing-doc",
+		Doc:      "public decls, those used by other modules or not at all, have a doc comment",
+		Severity: Info,
+		Check:    missingDoc,
+	})
+	Register(Rule{
+		Name:     "unused-import",
+		Doc:      "imports are used by a type expression or an annotation",
+		Severity: Warning,
+		Check:    unusedImport,
+	})
+	Register(Rule{
+		Name:     "unused-decl",
+		Doc:      "decls are used by a decl or an annotation of the loaded modules, or have an annotation, off by default as generators emit every decl",
+		Severity: Off,
+		Check:    unusedDecl,
+	})
+	Register(Rule{
+		Name:     "unreachable-decl",
+		Doc:      "private decls, those only used in their module, are reached from a public decl or a module annotation",
+		Severity: Warning,
+		Check:    unreachableDecl,
+	})
+	Register(Rule{
+		Name:     "wildcard-import",
+		Doc:      "modules are imported by the decls used rather than with .*",
+		Severity: Info,
+		Check:    wildcardImport,
+	})
+	Register(Rule{
+		Name:     "nullable-nullable",
+		Doc:      "Nullable isn't nested, null can't tell the two apart",
+		Severity: Warning,
+		Check:    nullableNullable,
+	})
+}
+
+func naming(p *Pass) {
+	res := map[string]*regexp.Regexp{}
+	for _, o := range []struct{ option, def string }{
+		{"decl", `^[A-Z][a-zA-Z0-9]*$`},
+		{"field", `^[a-z][a-zA-Z0-9]*$`},
+	} {
+		re, err := regexp.Compile(p.String(o.option, o.def))
+		if err != nil {
+			p.Report(Loc{}, "bad %s option: %v", o.option, err)
+			return
+		}
+		res[o.option] = re
+	}
+	for _, dn := range p.Module.DeclNames() {
+		if !res["decl"].MatchString(dn) {
+			p.Report(Loc{Decl: dn}, "decl '%s' doesn't match %s", dn, res["decl"])
+		}
+		for _, f := range p.Module.Decls[dn].Fields() {
+			if !res["field"].MatchString(f.Name) {
+				p.Report(Loc{Decl: dn, Field: f.Name}, "field '%s' doesn't match %s", f.Name, res["field"])
+			}
+		}
+	}
+}
+
+func missingDoc(p *Pass) {
+	for _, dn := range p.Module.DeclNames() {
+		decl := p.Module.Decls[dn]
+		if decl.Annotations.Doc() == "" && p.Usage.Public(dn) {
+			p.Report(Loc{Decl: dn}, "%s '%s' has no doc comment", decl.Type.Kind(), dn)
+		}
+	}
+}
+
+func unusedImport(p *Pass) {
+	for _, imp := range p.Usage.UnusedImports() {
+		p.Report(Loc{Import: imp.Text}, "import '%s' isn't used", imp.Text)
+	}
+}
+
+func wildcardImport(p *Pass) {
+	for _, imp := range p.Usage.Narrowable() {
+		names := []string{}
+		for _, sn := range imp.Uses {
+			names = append(names, sn.Name)
+		}
+		p.Report(Loc{Import: imp.Text}, "%s imports all of %s, import the decls used by name: %s", imp.Text, imp.Module, strings.Join(names, ", "))
+	}
+}
+
+func unusedDecl(p *Pass) {
+	for _, dn := range p.Module.DeclNames() {
+		decl := p.Module.Decls[dn]
+		annotated := false
+		for _, an := range decl.Annotations {
+			annotated = annotated || an.Key != adl.DocAnno
+		}
+		if !annotated && len(p.Usage.Users[adl.ScopedName{ModuleName: p.Module.Name, Name: dn}]) == 0 {
+			p.Report(Loc{Decl: dn}, "%s '%s' isn't used", decl.Type.Kind(), dn)
+		}
+	}
+}
+
+func unreachableDecl(p *Pass) {
+	for _, dn := range p.Usage.Unreachable() {
+		p.Report(Loc{Decl: dn}, "%s '%s' is only used by decls no public decl leads to", p.Module.Decls[dn].Type.Kind(), dn)
+	}
+}
+
+func nullableNullable(p *Pass) {
+	usage.ForTypeExprs(p.Module, func(decl, field string, te adl.TypeExpr) {
+		var nested func(te adl.TypeExpr) bool
+		nested = func(te adl.TypeExpr) bool {
+			if p.isNullable(te) && len(te.Parameters) == 1 && p.isNullable(te.Parameters[0]) {
+				return true
+			}
+			for _, pt := range te.Parameters {
+				if nested(pt) {
+					return true
+				}
+			}
+			return false
+		}
+		if nested(te) {
+			p.Report(Loc{Decl: decl, Field: field}, "%s nests Nullable, null can't tell the two apart", te)
+		}
+	})
+}
+
+// isNullable is true for Nullable, and type aliases of it
+func (p *Pass) isNullable(te adl.TypeExpr) bool {
+	for i := 0; i < 100; i++ {
+		if te.TypeRef.Primitive != nil {
+			return *te.TypeRef.Primitive == "Nullable"
+		}
+		if te.TypeRef.Reference == nil {
+			return false
+		}
+		decl, _, ok := adl.Lookup(p.AllMod, p.Module.Name, *te.TypeRef.Reference)
+		if !ok || decl.Type.Type == nil {
+			return false
+		}
+		te = decl.Type.Type.TypeExpr
+	}
+	return false
+}
@@ -0,0 +1,572 @@
+package nav
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	antlr "github.com/wxio/goantlr"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+// Completion is a name, keyword or JSON value that can be written at a position
+type Completion struct {
+	// Kind is Keyword, Module, ModuleRef for a part of a module's path, Decl, Primitive, TypeParam, Anno or Field.
+	// JSON keys and union branches are Fields, the default values of structs Decls and of primitives Primitives.
+	Kind  Kind
+	Label string
+	// Keyword declaring the decl, or the decl of the field
+	Keyword string
+	// Detail is the decl's or module's qualified name, a field's signature or the type of a value, Doc is its doc
+	// annotation
+	Detail, Doc string
+	// Insert is the LSP snippet written, the label when it is empty
+	Insert string
+	// Range is the word being written, the completion replaces it
+	Range Range
+}
+
+// topKeywords start the statements of a module
+var topKeywords = []string{"import", "struct", "union", "type", "newtype", "annotation"}
+
+// Complete are the completions of the name or value being written at the position, zero based. They are read from
+// the tokens before the position, so source with syntax errors completes. In JSON values they follow the keys
+// and arrays from the annotation or default to the position. It is false inside strings and other tokens.
+func (ix *Index) Complete(line, column int) ([]Completion, bool) {
+	r, ts, ok := ix.word(line, column)
+	if !ok {
+		return nil, false
+	}
+	cs := ix.complete(ts)
+	// quotes start a value outside JSON objects and arrays, never a name
+	if f := ix.scan(ts); r.StartColumn < column && ix.rune(line, r.StartColumn) == '"' && f.kind != "json" {
+		cs = ix.valueCompletions(ix.expected(f))
+	}
+	for i := range cs {
+		cs[i].Range = r
+	}
+	return cs, true
+}
+
+// word is the range of the word or JSON string being written at the position and the tokens before it
+func (ix *Index) word(line, column int) (Range, []*cst.Token, bool) {
+	ts := []*cst.Token{}
+	for _, t := range ix.File.Tokens {
+		if t.Line-1 > line || t.Line-1 == line && t.Column >= column || t.Type == antlr.TokenEOF {
+			break
+		}
+		ts = append(ts, t)
+	}
+	r := Range{StartLine: line, StartColumn: column, EndLine: line, EndColumn: column}
+	if n := len(ts); n != 0 && ts[n-1].Line-1 == line {
+		t := ts[n-1]
+		end := t.Column + len([]rune(t.Text))
+		switch {
+		case word(t.Text) && end >= column:
+			r.StartColumn = t.Column
+			ts = ts[:n-1]
+		case end > column && strings.HasPrefix(t.Text, `"`):
+			// a key or string in quotes, the editor closes them
+			r.StartColumn, r.EndColumn = t.Column, end
+			return r, ts[:n-1], true
+		case end > column:
+			return r, nil, false
+		}
+	}
+	// the lexer reads the quote of a string that isn't closed as a token
+	if n := len(ts); n != 0 && ts[n-1].Text == `"` && ts[n-1].Line-1 == line && ts[n-1].Column+1 == r.StartColumn {
+		r.StartColumn = ts[n-1].Column
+		ts = ts[:n-1]
+	}
+	return r, ts, true
+}
+
+// rune of the source at the position, zero when there is none
+func (ix *Index) rune(line, column int) rune {
+	lines := strings.Split(ix.File.String(), "\n")
+	if line < 0 || line >= len(lines) || column < 0 || column >= len([]rune(lines[line])) {
+		return 0
+	}
+	return []rune(lines[line])[column]
+}
+
+// complete are the completions after the tokens
+func (ix *Index) complete(ts []*cst.Token) []Completion {
+	f := ix.scan(ts)
+	s := stripAnnos(f.stmt)
+	if n := len(f.stmt); n != 0 && f.stmt[n-1].Text == "@" {
+		return ix.annoCompletions()
+	}
+	last := ""
+	if len(s) != 0 {
+		last = s[len(s)-1].Text
+	}
+	switch f.kind {
+	case "file":
+		if len(s) == 0 {
+			return []Completion{{Kind: Keyword, Label: "module"}}
+		}
+	case "module":
+		if len(s) == 0 {
+			cs := []Completion{}
+			for _, kw := range topKeywords {
+				cs = append(cs, Completion{Kind: Keyword, Label: kw})
+			}
+			return cs
+		}
+		switch s[0].Text {
+		case "import":
+			path := text(s[1:])
+			if path == "" || strings.HasSuffix(path, ".") {
+				return ix.importCompletions(path)
+			}
+		case "type", "newtype":
+			eq := indexOf(s, "=")
+			switch {
+			case eq < 0:
+			case indexOf(s[eq+1:], "=") >= 0:
+				// the default of a newtype
+				if last == "=" {
+					return ix.valueCompletions(ix.expected(f))
+				}
+			case last == "=" || last == "<" || last == ",":
+				return ix.typeCompletions(typeParams(s[:eq]))
+			}
+		case "annotation":
+			switch {
+			case len(s) == 1:
+				return append(ix.localCompletions(), ix.annoCompletions()...)
+			case len(s) == 3 && last == "::":
+				return ix.fieldCompletions(s[1].Text)
+			case len(s) == 2 && ix.local[s[1].Text], len(s) == 4 && s[2].Text == "::":
+				return ix.annoCompletions()
+			case len(s) == 2, len(s) == 3, len(s) == 5 && s[2].Text == "::":
+				// the value of annotation Key, annotation D Key or annotation D::f Key
+				return ix.valueCompletions(ix.expected(f))
+			}
+		}
+	case "decl":
+		switch {
+		case last == "=":
+			return ix.valueCompletions(ix.expected(f))
+		case indexOf(s, "=") >= 0:
+		case len(s) == 0 || last == "<" || last == ",":
+			return ix.typeCompletions(f.params)
+		}
+	case "json":
+		e := entry(f.stmt)
+		switch {
+		case f.open == "{" && len(e) == 0:
+			return ix.keyCompletions(f.te, keys(f.stmt))
+		case f.open == "[" && len(e) == 0, len(e) == 2 && e[1].Text == ":":
+			return ix.valueCompletions(ix.expected(f))
+		}
+	}
+	return []Completion{}
+}
+
+// frame is a module, a decl body or a JSON value, with the statement or the entries being read
+type frame struct {
+	// kind is file, module, decl or json
+	kind string
+	stmt []*cst.Token
+	// params and the decl's name of a decl body
+	params []string
+	decl   string
+	// open is the brace or bracket of a JSON value, te its type when it is known
+	open string
+	te   *adl.TypeExpr
+}
+
+// scan the tokens, the frame of the last token is returned
+func (ix *Index) scan(ts []*cst.Token) frame {
+	stack := []frame{{kind: "file"}}
+	for _, t := range ts {
+		f := &stack[len(stack)-1]
+		switch {
+		case strings.HasPrefix(t.Text, "///"):
+		case t.Text == "{" || t.Text == "[":
+			in := frame{kind: "json", open: t.Text, te: ix.expected(*f)}
+			s := stripAnnos(f.stmt)
+			switch {
+			case t.Text == "[" || f.kind == "json" || len(s) == 0 || s[len(s)-1].Text == "=":
+			case f.kind == "file" && s[0].Text == "module":
+				in = frame{kind: "module"}
+			case f.kind == "module" && len(s) >= 2 && (s[0].Text == "struct" || s[0].Text == "union"):
+				in = frame{kind: "decl", decl: s[1].Text, params: typeParams(s)}
+			}
+			f.stmt = append(f.stmt, t)
+			stack = append(stack, in)
+		case t.Text == "}" || t.Text == "]":
+			if len(stack) > 1 {
+				stack = stack[:len(stack)-1]
+			}
+			f = &stack[len(stack)-1]
+			f.stmt = append(f.stmt, t)
+		case t.Text == ";" && f.kind != "json":
+			f.stmt = nil
+		default:
+			f.stmt = append(f.stmt, t)
+		}
+	}
+	return stack[len(stack)-1]
+}
+
+// expected is the type of the value that starts after the frame's statement, nil when it isn't known
+func (ix *Index) expected(f frame) *adl.TypeExpr {
+	s := f.stmt
+	n := len(s)
+	switch {
+	case f.kind == "json" && f.te == nil:
+	case f.kind == "json" && f.open == "[":
+		if te, ok := ix.Elem(*f.te, "Vector"); ok {
+			return &te
+		}
+	case f.kind == "json":
+		e := entry(s)
+		if len(e) != 2 || e[1].Text != ":" {
+			return nil
+		}
+		key, _ := unquote(e[0].Text)
+		if fd, _, ok := ix.FieldOf(*f.te, key); ok {
+			return &fd.TypeExpr
+		}
+		if te, ok := ix.Elem(*f.te, "StringMap"); ok {
+			return &te
+		}
+	case n >= 2 && s[n-2].Text == "@":
+		return annoType(ix.resolve(s[n-1].Text))
+	case n != 0 && s[n-1].Text == "=":
+		return ix.defaultType(f)
+	default:
+		if st := stripAnnos(s); len(st) >= 2 && st[0].Text == "annotation" {
+			return annoType(ix.resolve(s[n-1].Text))
+		}
+	}
+	return nil
+}
+
+// defaultType is the type of the default being written, of a field or a newtype
+func (ix *Index) defaultType(f frame) *adl.TypeExpr {
+	s := stripAnnos(f.stmt)
+	var te *adl.TypeExpr
+	switch {
+	case f.kind == "decl" && len(s) >= 2:
+		for _, fd := range ix.AllMod[ix.Module].Decls[f.decl].Fields() {
+			if fd.Name == s[len(s)-2].Text {
+				te = &fd.TypeExpr
+			}
+		}
+	case f.kind == "module" && len(s) >= 2 && s[0].Text == "newtype":
+		if nt := ix.AllMod[ix.Module].Decls[s[1].Text].Type.Newtype; nt != nil {
+			te = &nt.TypeExpr
+		}
+	}
+	if te == nil {
+		return nil
+	}
+	qte := gen.Qualify(*te, ix.Module)
+	return &qte
+}
+
+// entry are the tokens of the last entry of a JSON object or array
+func entry(s []*cst.Token) []*cst.Token {
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i].Text == "," {
+			return s[i+1:]
+		}
+	}
+	return s
+}
+
+// keys of the entries of a JSON object
+func keys(s []*cst.Token) map[string]bool {
+	ks := map[string]bool{}
+	for i, t := range s {
+		if i+1 < len(s) && s[i+1].Text == ":" && (i == 0 || s[i-1].Text == ",") {
+			k, _ := unquote(t.Text)
+			ks[k] = true
+		}
+	}
+	return ks
+}
+
+// stripAnnos is the statement without its @ annotations, the values of those are literals or braces, what they
+// enclose is in another frame
+func stripAnnos(s []*cst.Token) []*cst.Token {
+	for len(s) >= 2 && s[0].Text == "@" {
+		s = s[2:]
+		switch {
+		case len(s) >= 2 && (s[0].Text == "{" || s[0].Text == "["):
+			s = s[2:]
+		case len(s) != 0 && literal(s[0].Text):
+			s = s[1:]
+		}
+	}
+	return s
+}
+
+// typeParams are the names between < and > in the decl's statement
+func typeParams(s []*cst.Token) []string {
+	params := []string{}
+	if i := indexOf(s, "<"); i >= 0 {
+		for _, t := range s[i+1:] {
+			if t.Text == ">" {
+				break
+			}
+			if t.Text != "," {
+				params = append(params, t.Text)
+			}
+		}
+	}
+	return params
+}
+
+func indexOf(s []*cst.Token, text string) int {
+	for i, t := range s {
+		if t.Text == text {
+			return i
+		}
+	}
+	return -1
+}
+
+func word(s string) bool {
+	for _, r := range s {
+		return unicode.IsLetter(r) || r == '_'
+	}
+	return false
+}
+
+func literal(s string) bool {
+	if s == "true" || s == "false" || s == "null" {
+		return true
+	}
+	for _, r := range s {
+		return r == '"' || r == '\'' || r == '-' || unicode.IsDigit(r)
+	}
+	return false
+}
+
+// declCompletion of a decl of a loaded module
+func (ix *Index) declCompletion(sn adl.ScopedName) Completion {
+	c := Completion{Kind: Decl, Label: sn.Name, Detail: sn.String()}
+	if sn.ModuleName == ix.Module {
+		c.Keyword = ix.keywords[sn.Name]
+	}
+	if decl, ex := ix.AllMod[sn.ModuleName].Decls[sn.Name]; ex {
+		c.Keyword = decl.Type.Kind()
+		c.Doc = strings.TrimSpace(decl.Annotations.Doc())
+	}
+	return c
+}
+
+// localCompletions are the decls of the module
+func (ix *Index) localCompletions() []Completion {
+	names := []string{}
+	for name := range ix.local {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	cs := []Completion{}
+	for _, name := range names {
+		cs = append(cs, ix.declCompletion(adl.ScopedName{ModuleName: ix.Module, Name: name}))
+	}
+	return cs
+}
+
+// scopeCompletions are the decls the module can use by name, its own and those it imports
+func (ix *Index) scopeCompletions() []Completion {
+	cs := ix.localCompletions()
+	seen := map[string]bool{}
+	for _, c := range cs {
+		seen[c.Label] = true
+	}
+	// single imports before wild card imports, as they resolve
+	sns, wild := []adl.ScopedName{}, []adl.ScopedName{}
+	for _, sn := range ix.scoped {
+		sns = append(sns, sn)
+	}
+	for _, mn := range ix.wild {
+		for name := range ix.AllMod[mn].Decls {
+			wild = append(wild, adl.ScopedName{ModuleName: mn, Name: name})
+		}
+	}
+	for _, ss := range [][]adl.ScopedName{sns, wild} {
+		sort.Slice(ss, func(i, j int) bool { return ss[i].String() < ss[j].String() })
+	}
+	for _, sn := range append(sns, wild...) {
+		if !seen[sn.Name] {
+			seen[sn.Name] = true
+			cs = append(cs, ix.declCompletion(sn))
+		}
+	}
+	return cs
+}
+
+// typeCompletions are the decls in scope, the primitives and the type params
+func (ix *Index) typeCompletions(params []string) []Completion {
+	cs := []Completion{}
+	for _, p := range params {
+		cs = append(cs, Completion{Kind: TypeParam, Label: p})
+	}
+	cs = append(cs, ix.scopeCompletions()...)
+	for _, p := range adl.Primitives {
+		cs = append(cs, Completion{Kind: Primitive, Label: p, Doc: primitives[p]})
+	}
+	return cs
+}
+
+// annoCompletions are the decls in scope and those of sys.annotations, imported implicitly
+func (ix *Index) annoCompletions() []Completion {
+	cs := ix.scopeCompletions()
+	seen := map[string]bool{}
+	for _, c := range cs {
+		seen[c.Label] = true
+	}
+	names := []string{}
+	if mod, ex := ix.AllMod[adlbuild.SysAnnotations]; ex {
+		for name := range mod.Decls {
+			names = append(names, name)
+		}
+	} else {
+		names = append(names, adl.DocAnno.Name, adl.SerializedNameAnno.Name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !seen[name] {
+			cs = append(cs, ix.declCompletion(adl.ScopedName{ModuleName: adlbuild.SysAnnotations, Name: name}))
+		}
+	}
+	for i := range cs {
+		cs[i].Kind = Anno
+	}
+	return cs
+}
+
+// fieldCompletions are the fields of a decl of the module
+func (ix *Index) fieldCompletions(decl string) []Completion {
+	cs := []Completion{}
+	d := ix.AllMod[ix.Module].Decls[decl]
+	for _, f := range d.Fields() {
+		cs = append(cs, Completion{
+			Kind:    Field,
+			Label:   f.Name,
+			Keyword: d.Type.Kind(),
+			Detail:  fieldSignature(f),
+			Doc:     strings.TrimSpace(f.Annotations.Doc()),
+		})
+	}
+	return cs
+}
+
+// importCompletions are the next parts of the paths of the modules with the prefix, and the decls of the module
+// the prefix names
+func (ix *Index) importCompletions(prefix string) []Completion {
+	cs := []Completion{}
+	seen := map[string]bool{}
+	mns := []string{}
+	for mn := range ix.AllMod {
+		mns = append(mns, mn)
+	}
+	sort.Strings(mns)
+	for _, mn := range mns {
+		if !strings.HasPrefix(mn, prefix) || mn == ix.Module {
+			continue
+		}
+		part := strings.SplitN(mn[len(prefix):], ".", 2)[0]
+		if seen[part] {
+			continue
+		}
+		seen[part] = true
+		c := Completion{Kind: ModuleRef, Label: part, Detail: prefix + part}
+		if prefix+part == mn {
+			c.Kind = Module
+			c.Doc = strings.TrimSpace(ix.AllMod[mn].Annotations.Doc())
+		}
+		cs = append(cs, c)
+	}
+	mn := strings.TrimSuffix(prefix, ".")
+	if mod, ex := ix.AllMod[mn]; ex && mn != "" {
+		names := []string{}
+		for name := range mod.Decls {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			cs = append(cs, ix.declCompletion(adl.ScopedName{ModuleName: mn, Name: name}))
+		}
+		cs = append(cs, Completion{Kind: Module, Label: "*", Detail: mn})
+	}
+	return cs
+}
+
+// keyCompletions are the keys of a JSON object of the type, the fields of a struct that aren't present or the
+// branches of a union when none is
+func (ix *Index) keyCompletions(te *adl.TypeExpr, present map[string]bool) []Completion {
+	cs := []Completion{}
+	if te == nil {
+		return cs
+	}
+	fs, d, _, ok := ix.Fields(*te)
+	if !ok || d.Type.Union != nil && len(present) != 0 {
+		return cs
+	}
+	for _, f := range fs {
+		if present[f.SerializedName] {
+			continue
+		}
+		cs = append(cs, Completion{
+			Kind:    Field,
+			Label:   `"` + f.SerializedName + `"`,
+			Keyword: d.Type.Kind(),
+			Detail:  fieldSignature(f),
+			Doc:     strings.TrimSpace(f.Annotations.Doc()),
+			Insert:  `"` + f.SerializedName + `" : ` + ix.Snippet(f.TypeExpr),
+		})
+	}
+	return cs
+}
+
+// valueCompletions are the JSON values of the type, null for a Nullable, true and false, the branches of a union
+// or else the type's placeholder
+func (ix *Index) valueCompletions(te *adl.TypeExpr) []Completion {
+	cs := []Completion{}
+	if te == nil {
+		return cs
+	}
+	if p := te.TypeRef.Primitive; p != nil && *p == "Nullable" {
+		cs = append(cs, Completion{Kind: Keyword, Label: "null"})
+	}
+	ete, d, ok := ix.Expand(*te)
+	if !ok {
+		return cs
+	}
+	switch {
+	case ete.TypeRef.Primitive != nil && *ete.TypeRef.Primitive == "Bool":
+		return append(cs, Completion{Kind: Keyword, Label: "true"}, Completion{Kind: Keyword, Label: "false"})
+	case d.Type.Union != nil:
+		fs, _, _, _ := ix.Fields(ete)
+		for _, f := range fs {
+			tab := 0
+			cs = append(cs, Completion{
+				Kind:    Field,
+				Label:   `"` + f.SerializedName + `"`,
+				Keyword: "union",
+				Detail:  fieldSignature(f),
+				Doc:     strings.TrimSpace(f.Annotations.Doc()),
+				Insert:  ix.branch(f, 0, &tab),
+			})
+		}
+		return cs
+	}
+	c := Completion{Kind: Primitive, Label: ix.Placeholder(ete), Detail: TypeString(*te), Insert: ix.Snippet(ete)}
+	if d.Type.Struct != nil {
+		c.Kind, c.Keyword = Decl, "struct"
+		c.Doc = strings.TrimSpace(d.Annotations.Doc())
+	}
+	return append(cs, c)
+}
@@ -0,0 +1,174 @@
+package nav
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+)
+
+var primitives = map[string]string{
+	"Void":      "The type with only the value null",
+	"Bool":      "true or false",
+	"Int8":      "A signed 8 bit integer",
+	"Int16":     "A signed 16 bit integer",
+	"Int32":     "A signed 32 bit integer",
+	"Int64":     "A signed 64 bit integer",
+	"Word8":     "An unsigned 8 bit integer",
+	"Word16":    "An unsigned 16 bit integer",
+	"Word32":    "An unsigned 32 bit integer",
+	"Word64":    "An unsigned 64 bit integer",
+	"Float":     "A single precision floating point number",
+	"Double":    "A double precision floating point number",
+	"Json":      "Any JSON value",
+	"Bytes":     "A sequence of bytes, base64 encoded in JSON",
+	"String":    "A unicode string",
+	"Vector":    "`Vector<T>` is a sequence of T, a JSON array",
+	"StringMap": "`StringMap<T>` maps strings to T, a JSON object",
+	"Nullable":  "`Nullable<T>` is a T or null",
+	"TypeToken": "`TypeToken<T>` is a value standing for the type T",
+}
+
+// Describe is the markdown hover text of the name, the signature of the decl or field it refers to, its doc and
+// module. It is empty for names that don't resolve.
+func (ix *Index) Describe(n Name) string {
+	var buf strings.Builder
+	code := func(s string) {
+		buf.WriteString("```adl\n" + s + "\n```\n")
+	}
+	switch n.Kind {
+	case Module, ModuleRef:
+		mod, ex := ix.AllMod[n.Ref.ModuleName]
+		code("module " + n.Ref.ModuleName)
+		if !ex {
+			break
+		}
+		docs(&buf, mod.Annotations)
+		fmt.Fprintf(&buf, "\n%d decls\n", len(mod.Decls))
+	case Primitive:
+		code(n.Text)
+		buf.WriteString("\n" + primitives[n.Text] + "\n")
+	case TypeParam:
+		code(n.Field)
+		fmt.Fprintf(&buf, "\ntype parameter of `%s`\n", n.Ref.Name)
+	case Field, JsonKey:
+		return ix.describeField(n.Ref, n.Field, n.Kind == JsonKey)
+	case AnnoTarget:
+		if n.Field != "" {
+			return ix.describeField(n.Ref, n.Field, false)
+		}
+		return ix.describeDecl(n.Ref)
+	default:
+		return ix.describeDecl(n.Ref)
+	}
+	return buf.String()
+}
+
+func docs(buf *strings.Builder, ans adl.Annotations) {
+	if doc := strings.TrimSpace(ans.Doc()); doc != "" {
+		buf.WriteString("\n" + doc + "\n")
+	}
+}
+
+func (ix *Index) describeDecl(sn adl.ScopedName) string {
+	decl, sn, ok := adl.Lookup(ix.AllMod, "", sn)
+	if !ok {
+		return ""
+	}
+	var buf strings.Builder
+	buf.WriteString("```adl\n" + Signature(decl) + "\n```\n")
+	docs(&buf, decl.Annotations)
+	fmt.Fprintf(&buf, "\ndefined in `%s`\n", sn.ModuleName)
+	return buf.String()
+}
+
+// describeField describes a field, for a json key with its serialized name
+func (ix *Index) describeField(sn adl.ScopedName, name string, json bool) string {
+	decl, sn, ok := adl.Lookup(ix.AllMod, "", sn)
+	if !ok {
+		return ""
+	}
+	for _, f := range decl.Fields() {
+		if f.Name != name {
+			continue
+		}
+		var buf strings.Builder
+		buf.WriteString("```adl\n" + fieldSignature(f) + "\n```\n")
+		docs(&buf, f.Annotations)
+		if json && f.SerializedName != "" && f.SerializedName != f.Name {
+			fmt.Fprintf(&buf, "\nserialized as `%s`\n", f.SerializedName)
+		}
+		if _, ok := adl.Just(f.Default); !ok && decl.Type.Struct != nil {
+			buf.WriteString("\nrequired\n")
+		}
+		fmt.Fprintf(&buf, "\n%s of `%s` in `%s`\n", kindOfField(decl), decl.Name, sn.ModuleName)
+		return buf.String()
+	}
+	return ""
+}
+
+func kindOfField(decl adl.Decl) string {
+	if decl.Type.Union != nil {
+		return "branch"
+	}
+	return "field"
+}
+
+// Signature is the decl as ADL source, without annotations, references are by name
+func Signature(decl adl.Decl) string {
+	name := decl.Name
+	if tps := decl.TypeParams(); len(tps) != 0 {
+		name += "<" + strings.Join(tps, ", ") + ">"
+	}
+	switch {
+	case decl.Type.Type != nil:
+		return "type " + name + " = " + TypeString(decl.Type.Type.TypeExpr) + ";"
+	case decl.Type.Newtype != nil:
+		s := "newtype " + name + " = " + TypeString(decl.Type.Newtype.TypeExpr)
+		if def, ok := adl.Just(decl.Type.Newtype.Default); ok {
+			if v, err := adlbuild.Value(def); err == nil {
+				s += " = " + v
+			}
+		}
+		return s + ";"
+	}
+	var buf strings.Builder
+	buf.WriteString(decl.Type.Kind() + " " + name + " {\n")
+	for _, f := range decl.Fields() {
+		buf.WriteString("  " + fieldSignature(f) + "\n")
+	}
+	buf.WriteString("};")
+	return buf.String()
+}
+
+func fieldSignature(f adl.Field) string {
+	s := TypeString(f.TypeExpr) + " " + f.Name
+	if def, ok := adl.Just(f.Default); ok {
+		if v, err := adlbuild.Value(def); err == nil {
+			s += " = " + v
+		}
+	}
+	return s + ";"
+}
+
+// TypeString is the type expression as written in source, references by name
+func TypeString(te adl.TypeExpr) string {
+	name := ""
+	switch {
+	case te.TypeRef.Primitive != nil:
+		name = *te.TypeRef.Primitive
+	case te.TypeRef.TypeParam != nil:
+		name = *te.TypeRef.TypeParam
+	case te.TypeRef.Reference != nil:
+		name = te.TypeRef.Reference.Name
+	}
+	if len(te.Parameters) == 0 {
+		return name
+	}
+	ps := make([]string, len(te.Parameters))
+	for i, p := range te.Parameters {
+		ps[i] = TypeString(p)
+	}
+	return name + "<" + strings.Join(ps, ", ") + ">"
+}
@@ -0,0 +1,82 @@
+package nav
+
+import (
+	"sort"
+
+	"github.com/wxio/tron-go/adl"
+	parser "github.com/wxio/tron-go/internal/adllp"
+	"github.com/wxio/tron-go/internal/ctree"
+)
+
+// Fold kinds, as in the LSP
+const (
+	FoldComment = "comment"
+	FoldImports = "imports"
+	FoldRegion  = "region"
+)
+
+// Fold is a range of lines that can be folded, zero based. The last line of a body, its closing brace, isn't
+// folded.
+type Fold struct {
+	StartLine, EndLine int
+	Kind               string
+}
+
+// Folds are the module, struct and union bodies, multi-line JSON values, runs of /// doc comments and import
+// blocks of src. They are read from the start and stop tokens of the ctree nodes, there are none when src
+// doesn't parse.
+func Folds(src string) []Fold {
+	tr, _, _, _, _ := adl.BuildAdlAST(src)
+	folds := []Fold{}
+	if tr == nil {
+		return folds
+	}
+	// the run of imports or doc comments being read
+	var run *Fold
+	end := func() {
+		if run != nil && run.EndLine > run.StartLine {
+			folds = append(folds, *run)
+		}
+		run = nil
+	}
+	tr.Walk(func(_ int, in ctree.INode) bool {
+		tn, ok := in.(ctree.TreeNode)
+		if !ok || tn.StopToken() == nil {
+			return true
+		}
+		start, stop := tn.StartToken().GetLine()-1, tn.StopToken().GetLine()-1
+		kind := ""
+		switch tn.GetTokenType() {
+		case parser.AdlPImportModule, parser.AdlPImportScopedName:
+			kind = FoldImports
+		case parser.AdlPAnnotation:
+			if an, ok := tn.Val().(adl.Annotation); ok && an.Key == adl.DocAnno {
+				kind = FoldComment
+			}
+		}
+		if run != nil && (run.Kind != kind || start > run.EndLine+1) {
+			end()
+		}
+		switch {
+		case kind == "":
+		case run == nil:
+			run = &Fold{StartLine: start, EndLine: stop, Kind: kind}
+			return true
+		default:
+			run.EndLine = stop
+			return true
+		}
+		end()
+		switch tn.GetTokenType() {
+		case parser.AdlPModule, parser.AdlPStruct, parser.AdlPUnion, parser.AdlPJsonObj, parser.AdlPJsonArray:
+			if stop-1 > start {
+				folds = append(folds, Fold{StartLine: start, EndLine: stop - 1, Kind: FoldRegion})
+			}
+		}
+		return true
+	})
+	end()
+	// decls are read before their annotations
+	sort.SliceStable(folds, func(i, j int) bool { return folds[i].StartLine < folds[j].StartLine })
+	return folds
+}
@@ -0,0 +1,552 @@
+// Package nav indexes the names of ADL source, what they declare or refer to, for editor navigation.
+//
+// The index is built from the cst, so it has source positions and works on source with errors, where the parser's
+// error recovery keeps what it can. Names are resolved as adlc does, against the module's decls, its imports and
+// the implicitly imported sys.annotations. The keys of JSON annotation values and field defaults are resolved
+// to the fields of the value's type, through type aliases, newtypes, generic instantiations, Vectors, StringMaps and
+// Nullables.
+package nav
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+type Kind int
+
+const (
+	// Module is the name of the module statement
+	Module Kind = iota
+	// ModuleRef is an imported module, the module part of an import
+	ModuleRef
+	// Import is the decl of an import of a single decl, ie DbTable in import common.db.DbTable
+	Import
+	// Decl is the name of a decl where it is declared
+	Decl
+	// Field is the name of a field where it is declared
+	Field
+	// TypeParam is a type parameter where it is declared or used
+	TypeParam
+	// TypeRef is a decl used in a type expression
+	TypeRef
+	// Primitive is a primitive used in a type expression
+	Primitive
+	// Anno is an annotation key, of an @ annotation or an annotation statement
+	Anno
+	// AnnoTarget is the decl or field an annotation statement annotates, ie HelloReq or name in
+	// annotation HelloReq::name Path "/";
+	AnnoTarget
+	// JsonKey is the key of a JSON object that is a struct or a union, the key is a field's serialized name
+	JsonKey
+	// Keyword is a keyword of ADL, it is completed and never indexed
+	Keyword
+)
+
+var kinds = []string{"module", "module ref", "import", "decl", "field", "type param", "type ref", "primitive",
+	"annotation", "annotation target", "json key", "keyword"}
+
+func (k Kind) String() string {
+	return kinds[k]
+}
+
+// Name is a name in the source
+type Name struct {
+	Kind Kind
+	// Line and Column are zero based, Text is the source of the name, a dotted name for modules
+	Line, Column int
+	Text         string
+	// Ref is the decl the name declares or refers to, or the module for Module and ModuleRef.
+	// Its module is empty when the name doesn't resolve.
+	Ref adl.ScopedName
+	// Field is the field of Ref for Field, JsonKey and the field of an AnnoTarget, the type param for TypeParam
+	Field string
+	// Decl is the decl of the module the name is in, empty outside decls
+	Decl string
+	// Declares is true where the module, decl, field or type param is declared
+	Declares bool
+}
+
+// Target is what a name declares or refers to, a module, a decl, a field of a decl or a type param of a decl
+type Target struct {
+	Ref       adl.ScopedName
+	Field     string
+	TypeParam bool
+}
+
+// Target of the name, false for primitives and names that don't resolve
+func (n Name) Target() (Target, bool) {
+	if n.Kind == Primitive || n.Ref.ModuleName == "" {
+		return Target{}, false
+	}
+	return Target{Ref: n.Ref, Field: n.Field, TypeParam: n.Kind == TypeParam}, true
+}
+
+// Len is the length of the name in runes
+func (n Name) Len() int {
+	return len([]rune(n.Text))
+}
+
+// Contains is true when the position is in the name or just after it
+func (n Name) Contains(line, column int) bool {
+	return line == n.Line && column >= n.Column && column <= n.Column+n.Len()
+}
+
+// Index of the names of a module's source
+type Index struct {
+	File *cst.File
+	// Module is the module's name, empty when the source has no module statement
+	Module string
+	// Names in source order
+	Names []Name
+	// AllMod are the modules the names are resolved against, with the decls of the source that read, see
+	// adlbuild.ParsePartial
+	AllMod map[string]adl.Module
+
+	local    map[string]bool
+	scoped   map[string]adl.ScopedName
+	wild     []string
+	keywords map[string]string
+	values   map[*cst.Node]adl.TypeExpr
+}
+
+// Parse indexes src, allmod holds the modules it imports, see adlbuild.Parse
+func Parse(src string, allmod map[string]adl.Module) *Index {
+	ix := &Index{
+		File:     cst.Parse(src),
+		AllMod:   map[string]adl.Module{},
+		local:    map[string]bool{},
+		scoped:   map[string]adl.ScopedName{},
+		keywords: map[string]string{},
+		values:   map[*cst.Node]adl.TypeExpr{},
+	}
+	for k, v := range allmod {
+		ix.AllMod[k] = v
+	}
+	var ms *cst.Node
+	for _, c := range ix.File.Root.Children {
+		if c.Rule == "ModuleStatement" {
+			ms = c
+		}
+	}
+	if ms == nil {
+		return ix
+	}
+	// the decls that resolve, the module in allmod when the source has syntax errors
+	if mod, _ := adlbuild.ParsePartial(src, allmod); mod.Name != "" {
+		ix.AllMod[mod.Name] = mod
+	}
+	ix.index(ms)
+	sort.SliceStable(ix.Names, func(i, j int) bool {
+		if ix.Names[i].Line != ix.Names[j].Line {
+			return ix.Names[i].Line < ix.Names[j].Line
+		}
+		return ix.Names[i].Column < ix.Names[j].Column
+	})
+	return ix
+}
+
+// At is the name at the position, zero based
+func (ix *Index) At(line, column int) (Name, bool) {
+	for _, n := range ix.Names {
+		if n.Contains(line, column) {
+			return n, true
+		}
+	}
+	return Name{}, false
+}
+
+// Find is the first name of the kind that refers to ref and field
+func (ix *Index) Find(kind Kind, ref adl.ScopedName, field string) (Name, bool) {
+	for _, n := range ix.Names {
+		if n.Kind == kind && n.Ref == ref && n.Field == field {
+			return n, true
+		}
+	}
+	return Name{}, false
+}
+
+// Keyword declaring the decl of the source, struct, union, type or newtype, empty when there is no such decl
+func (ix *Index) Keyword(decl string) string {
+	return ix.keywords[decl]
+}
+
+// leaves are the direct leaves of the node
+func leaves(n *cst.Node) []*cst.Token {
+	ts := []*cst.Token{}
+	for _, c := range n.Children {
+		if c.Token != nil {
+			ts = append(ts, c.Token)
+		}
+	}
+	return ts
+}
+
+// rules are the direct rule children of the node
+func rules(n *cst.Node) []*cst.Node {
+	rs := []*cst.Node{}
+	for _, c := range n.Children {
+		if c.Token == nil {
+			rs = append(rs, c)
+		}
+	}
+	return rs
+}
+
+func (ix *Index) add(kind Kind, ts []*cst.Token, ref adl.ScopedName, field, decl string) {
+	if len(ts) == 0 {
+		return
+	}
+	n := Name{Kind: kind, Line: ts[0].Line - 1, Column: ts[0].Column, Ref: ref, Field: field, Decl: decl,
+		Declares: kind == Module || kind == Decl || kind == Field}
+	for _, t := range ts {
+		n.Text += t.Text
+	}
+	ix.Names = append(ix.Names, n)
+}
+
+// dotted are the tokens of a dotted name, up to the first token that isn't an identifier or a dot
+func dotted(ts []*cst.Token) []*cst.Token {
+	for i, t := range ts {
+		if t.Text == "{" || t.Text == "*" || t.Text == ";" || i%2 == 1 && t.Text != "." {
+			ts = ts[:i]
+			break
+		}
+	}
+	// no trailing dot
+	if n := len(ts); n != 0 && ts[n-1].Text == "." {
+		ts = ts[:n-1]
+	}
+	return ts
+}
+
+func text(ts []*cst.Token) string {
+	s := ""
+	for _, t := range ts {
+		s += t.Text
+	}
+	return s
+}
+
+func (ix *Index) index(ms *cst.Node) {
+	ts := leaves(ms)
+	if len(ts) < 2 {
+		// a partial module header, the module is unnamed
+		return
+	}
+	name := dotted(ts[1:])
+	ix.Module = text(name)
+	ix.add(Module, name, adl.ScopedName{ModuleName: ix.Module}, "", "")
+	// decl names and imports first, decls may be used before they are declared
+	for _, n := range rules(ms) {
+		ts := leaves(n)
+		switch n.Rule {
+		case "StructOrUnion", "TypeOrNewtype":
+			if len(ts) > 1 {
+				ix.local[ts[1].Text] = true
+			}
+		case "ImportModuleName":
+			mts := dotted(ts[1:])
+			ix.wild = append(ix.wild, text(mts))
+			ix.add(ModuleRef, mts, adl.ScopedName{ModuleName: text(mts)}, "", "")
+		case "ImportScopedName":
+			mts := dotted(ts[1:])
+			if len(mts) < 3 {
+				continue
+			}
+			sn := adl.ScopedName{ModuleName: text(mts[:len(mts)-2]), Name: mts[len(mts)-1].Text}
+			ix.scoped[sn.Name] = sn
+			ix.add(ModuleRef, mts[:len(mts)-2], adl.ScopedName{ModuleName: sn.ModuleName}, "", "")
+			ix.add(Import, mts[len(mts)-1:], sn, "", "")
+		}
+	}
+	for _, n := range rules(ms) {
+		switch n.Rule {
+		case "DocAnno", "LocalAnno":
+			ix.anno(n, "")
+		case "StructOrUnion", "TypeOrNewtype":
+			ix.decl(n)
+		case "ModuleAnnotation", "DeclAnnotation", "FieldAnnotation":
+			ix.annoStmt(n)
+		}
+	}
+}
+
+// resolve a decl name used in the module, the module is empty when it doesn't resolve
+func (ix *Index) resolve(name string) adl.ScopedName {
+	if ix.local[name] {
+		return adl.ScopedName{ModuleName: ix.Module, Name: name}
+	}
+	if sn, ex := ix.scoped[name]; ex {
+		return sn
+	}
+	found := []adl.ScopedName{}
+	for _, mn := range append([]string{adlbuild.SysAnnotations}, ix.wild...) {
+		mod, ex := ix.AllMod[mn]
+		_, decl := mod.Decls[name]
+		if decl || !ex && mn == adlbuild.SysAnnotations && (name == adl.DocAnno.Name || name == adl.SerializedNameAnno.Name) {
+			found = append(found, adl.ScopedName{ModuleName: mn, Name: name})
+		}
+	}
+	if len(found) == 1 {
+		return found[0]
+	}
+	return adl.ScopedName{Name: name}
+}
+
+// anno indexes a prefix annotation, the value is checked against the annotation's type
+func (ix *Index) anno(n *cst.Node, decl string) {
+	ts := leaves(n)
+	if n.Rule != "LocalAnno" || len(ts) < 2 {
+		return
+	}
+	key := ix.resolve(ts[1].Text)
+	ix.add(Anno, ts[1:2], key, "", decl)
+	if vs := rules(n); len(vs) != 0 {
+		ix.json(vs[0], annoType(key), decl)
+	}
+}
+
+// annoType is the type of an annotation's value, the annotation's decl
+func annoType(key adl.ScopedName) *adl.TypeExpr {
+	if key.ModuleName == "" {
+		return nil
+	}
+	return &adl.TypeExpr{TypeRef: adl.TypeRef{Reference: &key}, Parameters: []adl.TypeExpr{}}
+}
+
+func (ix *Index) decl(n *cst.Node) {
+	ts := leaves(n)
+	if len(ts) < 2 {
+		return
+	}
+	name := ts[1].Text
+	sn := adl.ScopedName{ModuleName: ix.Module, Name: name}
+	ix.keywords[name] = ts[0].Text
+	ix.add(Decl, ts[1:2], sn, "", name)
+	params := []string{}
+	d := ix.AllMod[ix.Module].Decls[name]
+	d.Name = name
+	for _, c := range rules(n) {
+		switch {
+		case c.Rule == "DocAnno" || c.Rule == "LocalAnno":
+			ix.anno(c, name)
+		case c.Rule == "TypeParameter":
+			for _, t := range leaves(c) {
+				if t.Text != "<" && t.Text != ">" && t.Text != "," {
+					params = append(params, t.Text)
+					ix.add(TypeParam, []*cst.Token{t}, sn, t.Text, name)
+					ix.Names[len(ix.Names)-1].Declares = true
+				}
+			}
+		case c.Rule == "FieldStatement":
+			ix.field(c, d, params)
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			ix.typeExpr(c, sn, params)
+		default:
+			// the default of a newtype
+			if d.Type.Newtype != nil {
+				te := gen.Qualify(d.Type.Newtype.TypeExpr, ix.Module)
+				ix.json(c, &te, name)
+			}
+		}
+	}
+}
+
+func (ix *Index) field(n *cst.Node, d adl.Decl, params []string) {
+	ts := leaves(n)
+	if len(ts) == 0 {
+		return
+	}
+	sn := adl.ScopedName{ModuleName: ix.Module, Name: d.Name}
+	ix.add(Field, ts[:1], sn, ts[0].Text, d.Name)
+	for _, c := range rules(n) {
+		switch {
+		case c.Rule == "DocAnno" || c.Rule == "LocalAnno":
+			ix.anno(c, d.Name)
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			ix.typeExpr(c, sn, params)
+		default:
+			for _, f := range d.Fields() {
+				if f.Name == ts[0].Text {
+					te := gen.Qualify(f.TypeExpr, ix.Module)
+					ix.json(c, &te, d.Name)
+				}
+			}
+		}
+	}
+}
+
+func (ix *Index) typeExpr(n *cst.Node, decl adl.ScopedName, params []string) {
+	ts := leaves(n)
+	if len(ts) == 0 {
+		return
+	}
+	name := ts[0].Text
+	switch {
+	case contains(params, name):
+		ix.add(TypeParam, ts[:1], decl, name, decl.Name)
+	case adl.IsPrimitive(name):
+		ix.add(Primitive, ts[:1], adl.ScopedName{}, "", decl.Name)
+	default:
+		ix.add(TypeRef, ts[:1], ix.resolve(name), "", decl.Name)
+	}
+	for _, c := range rules(n) {
+		ix.typeExpr(c, decl, params)
+	}
+}
+
+func contains(ss []string, s string) bool {
+	for _, e := range ss {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// annoStmt indexes annotation X::f Key value; annotation X Key value; or annotation Key value;
+func (ix *Index) annoStmt(n *cst.Node) {
+	ts := leaves(n)
+	vs := rules(n)
+	decl := ""
+	switch {
+	case n.Rule == "DeclAnnotation" && len(ts) >= 3:
+		decl = ts[1].Text
+		ix.add(AnnoTarget, ts[1:2], ix.resolve(decl), "", decl)
+		ts = ts[2:]
+	case n.Rule == "FieldAnnotation" && len(ts) >= 5:
+		decl = ts[1].Text
+		sn := ix.resolve(decl)
+		ix.add(AnnoTarget, ts[1:2], sn, "", decl)
+		ix.add(AnnoTarget, ts[3:4], sn, ts[3].Text, decl)
+		ts = ts[4:]
+	case n.Rule == "ModuleAnnotation" && len(ts) >= 2:
+		ts = ts[1:]
+	default:
+		return
+	}
+	key := ix.resolve(ts[0].Text)
+	ix.add(Anno, ts[:1], key, "", decl)
+	if len(vs) != 0 {
+		ix.json(vs[0], annoType(key), decl)
+	}
+}
+
+// json indexes the keys of a value of type te, te is fully qualified and may be nil when not known
+func (ix *Index) json(n *cst.Node, te *adl.TypeExpr, decl string) {
+	if te == nil {
+		return
+	}
+	ix.values[n] = *te
+	switch n.Rule {
+	case "ObjStatement":
+		for _, kv := range rules(n) {
+			ts := leaves(kv)
+			if len(ts) == 0 {
+				continue
+			}
+			key, _ := unquote(ts[0].Text)
+			var vte *adl.TypeExpr
+			if f, sn, ok := ix.FieldOf(*te, key); ok {
+				ix.add(JsonKey, ts[:1], sn, f.Name, decl)
+				vte = &f.TypeExpr
+			} else if ete, ok := ix.Elem(*te, "StringMap"); ok {
+				vte = &ete
+			}
+			if vs := rules(kv); len(vs) != 0 {
+				ix.json(vs[0], vte, decl)
+			}
+		}
+	case "ArrayStatement":
+		if ete, ok := ix.Elem(*te, "Vector"); ok {
+			for _, c := range rules(n) {
+				ix.json(c, &ete, decl)
+			}
+		}
+	}
+}
+
+// unquote a double or single quoted ADL string
+func unquote(s string) (string, bool) {
+	if len(s) < 2 {
+		return s, false
+	}
+	return s[1 : len(s)-1], true
+}
+
+// Expand resolves type aliases, newtypes and Nullables, the result is a primitive, a struct or a union.
+// The decl's type params are substituted with the type's args.
+func (ix *Index) Expand(te adl.TypeExpr) (adl.TypeExpr, adl.Decl, bool) {
+	for i := 0; i < 100; i++ {
+		switch {
+		case te.TypeRef.Primitive != nil:
+			if *te.TypeRef.Primitive == "Nullable" && len(te.Parameters) == 1 {
+				te = te.Parameters[0]
+				continue
+			}
+			return te, adl.Decl{}, true
+		case te.TypeRef.Reference != nil:
+			d, sn, ok := adl.Lookup(ix.AllMod, "", *te.TypeRef.Reference)
+			if !ok {
+				return te, d, false
+			}
+			var ate *adl.TypeExpr
+			switch {
+			case d.Type.Type != nil:
+				ate = &d.Type.Type.TypeExpr
+			case d.Type.Newtype != nil:
+				ate = &d.Type.Newtype.TypeExpr
+			default:
+				return te, d, true
+			}
+			te = gen.Subst(gen.Qualify(*ate, sn.ModuleName), d.TypeParams(), te.Parameters)
+		default:
+			return te, adl.Decl{}, false
+		}
+	}
+	return te, adl.Decl{}, false
+}
+
+// FieldOf is the field of a struct or union type with the serialized name, its type is substituted and fully
+// qualified. The scoped name is the decl's.
+func (ix *Index) FieldOf(te adl.TypeExpr, serializedName string) (adl.Field, adl.ScopedName, bool) {
+	fs, _, sn, ok := ix.Fields(te)
+	if !ok {
+		return adl.Field{}, sn, false
+	}
+	for _, f := range fs {
+		if f.SerializedName == serializedName || f.SerializedName == "" && f.Name == serializedName {
+			return f, sn, true
+		}
+	}
+	return adl.Field{}, sn, false
+}
+
+// Fields of a struct or union type, their types are substituted and fully qualified. The scoped name is the decl's.
+func (ix *Index) Fields(te adl.TypeExpr) ([]adl.Field, adl.Decl, adl.ScopedName, bool) {
+	ete, d, ok := ix.Expand(te)
+	if !ok || ete.TypeRef.Reference == nil {
+		return nil, d, adl.ScopedName{}, false
+	}
+	sn := *ete.TypeRef.Reference
+	fs := []adl.Field{}
+	for _, f := range d.Fields() {
+		f.TypeExpr = gen.Subst(gen.Qualify(f.TypeExpr, sn.ModuleName), d.TypeParams(), ete.Parameters)
+		fs = append(fs, f)
+	}
+	return fs, d, sn, true
+}
+
+// Elem is the type of the elements of a Vector or the values of a StringMap
+func (ix *Index) Elem(te adl.TypeExpr, prim string) (adl.TypeExpr, bool) {
+	ete, _, ok := ix.Expand(te)
+	if !ok || ete.TypeRef.Primitive == nil || *ete.TypeRef.Primitive != prim || len(ete.Parameters) != 1 {
+		return adl.TypeExpr{}, false
+	}
+	return ete.Parameters[0], true
+}
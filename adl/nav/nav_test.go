@@ -0,0 +1,547 @@
+package nav
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+// pos is the position of the first occurrence of marker in src, plus offset columns
+func pos(t *testing.T, src, marker string, offset int) (int, int) {
+	i := strings.Index(src, marker)
+	if i < 0 {
+		t.Fatalf("no %q", marker)
+	}
+	before := []rune(src[:i])
+	line, col := 0, 0
+	for _, r := range before {
+		col++
+		if r == '\n' {
+			line++
+			col = 0
+		}
+	}
+	return line, col + offset
+}
+
+func TestAt(t *testing.T) {
+	src := adltest.OneOfEachAdl
+	ix := Parse(src, adltest.Modules())
+	for _, tc := range []struct {
+		marker string
+		offset int
+		want   string
+	}{
+		{"module helix", 10, "module helix.protoapp.requests."},
+		{"import common.db.DbTable", 8, "module ref common.db."},
+		{"import common.db.DbTable", 20, "import common.db.DbTable"},
+		{"type Hello<A>", 6, "decl helix.protoapp.requests.Hello"},
+		{"type Hello<A>", 11, "type param helix.protoapp.requests.Hello A"},
+		{"Vector<A>>>", 7, "type param helix.protoapp.requests.Hello A"},
+		{"Post<HelloReq", 0, "type ref common.http.Post"},
+		{"Post<HelloReq", 4, "type ref common.http.Post"},
+		{"Post<HelloReq", 5, "type ref helix.protoapp.requests.HelloReq"},
+		{"Int32,Float", 0, "primitive "},
+		{"Instant createdAt", 8, "field helix.protoapp.requests.HelloReq createdAt"},
+		{"@SerializedName", 1, "annotation sys.annotations.SerializedName"},
+		{`@SA { "a"`, 7, "json key helix.protoapp.requests.SA a"},
+		{`"a b" : { "b" : { "b"`, 11, "json key helix.protoapp.requests.MyConfig b"},
+		{`"a b" : { "b" : { "b"`, 19, "json key helix.protoapp.requests.B b"},
+		{`"indexes" : [["who"]`, 1, "json key common.db.DbTable indexes"},
+		{"annotation HelloReq::name", 11, "annotation target helix.protoapp.requests.HelloReq"},
+		{"annotation HelloReq::name", 21, "annotation target helix.protoapp.requests.HelloReq name"},
+		{"annotation HelloReq::name Path", 26, "annotation common.http.Path"},
+		{"annotation Path", 11, "annotation common.http.Path"},
+		{"Map<String, Int64>", 0, "type ref sys.types.Map"},
+	} {
+		line, col := pos(t, src, tc.marker, tc.offset)
+		n, ok := ix.At(line, col)
+		got := n.Kind.String() + " " + n.Ref.String()
+		if n.Field != "" {
+			got += " " + n.Field
+		}
+		if !ok || got != tc.want {
+			t.Errorf("%s+%d: got %q want %q", tc.marker, tc.offset, got, tc.want)
+		}
+	}
+	for _, tc := range []struct {
+		marker string
+		offset int
+	}{
+		{"struct HelloReq", 2},
+		{"{\n  String name;", 0},
+		{"// comment", 3},
+	} {
+		line, col := pos(t, src, tc.marker, tc.offset)
+		if n, ok := ix.At(line, col); ok {
+			t.Errorf("%s+%d: unexpected %v", tc.marker, tc.offset, n)
+		}
+	}
+}
+
+const jsonAdl = `module test {
+import common.LocalDate;
+
+struct P<T> {
+  T v;
+  Int32 n = 0;
+};
+struct S {
+  Int32 x;
+};
+type PS = P<Vector<S>>;
+struct Q {
+  StringMap<Nullable<S>> m = { "k" : { "x" : 1 } };
+  @SerializedName "local"
+  LocalDate day;
+};
+
+@PS { "v" : [{ "x" : 1 }, { "x" : 2 }], "n" : 3 }
+@Q { "m" : { "x" : null }, "local" : "2019-01-01" }
+struct R {
+  Int32 r;
+  Unknown u;
+};
+};
+`
+
+func TestJson(t *testing.T) {
+	ix := Parse(jsonAdl, adltest.Modules())
+	got := []string{}
+	for _, n := range ix.Names {
+		if n.Kind == JsonKey {
+			got = append(got, n.Text+" "+n.Ref.String()+"::"+n.Field)
+		}
+	}
+	want := []string{
+		`"x" test.S::x`,
+		`"v" test.P::v`,
+		`"x" test.S::x`,
+		`"x" test.S::x`,
+		`"n" test.P::n`,
+		`"m" test.Q::m`,
+		`"local" test.Q::day`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if n, _ := ix.At(pos(t, jsonAdl, "Unknown u", 0)); n.Kind != TypeRef || n.Ref.ModuleName != "" {
+		t.Errorf("unknown type resolved %v", n)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	src := "module test {\nstruct S {\n  Int32 x;\n};\nstruct T {\n  S s\n  Int32 y;\n};\n"
+	ix := Parse(src, nil)
+	for _, tc := range []struct {
+		marker string
+		want   string
+	}{
+		{"S {", "decl test.S"},
+		{"x;", "field test.S"},
+		{"S s", "type ref test.S"},
+	} {
+		n, ok := ix.At(pos(t, src, tc.marker, 0))
+		if got := n.Kind.String() + " " + n.Ref.String(); !ok || got != tc.want {
+			t.Errorf("%s: got %q want %q", tc.marker, got, tc.want)
+		}
+	}
+	// new files and partial module headers
+	for src, want := range map[string]string{"": "", "module": "", "module {": "", "module a.": "a"} {
+		if ix := Parse(src, nil); ix.Module != want {
+			t.Errorf("%q: got module %q want %q", src, ix.Module, want)
+		}
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	src := adltest.OneOfEachAdl
+	ix := Parse(src, adltest.Modules())
+	for _, tc := range []struct {
+		marker string
+		offset int
+		want   string
+	}{
+		{"Post<LoginReq, LoginResult>", 15, "```adl\n" +
+			"union LoginResult {\n" +
+			"  String accessToken;\n" +
+			"  Void invalidCredentials;\n" +
+			"  LoginResp<LocalDate> locked;\n" +
+			"};\n" +
+			"```\n\n" +
+			"The outcome of a login attempt\n\n" +
+			"defined in `helix.protoapp.requests`\n"},
+		{"Instant createdAt", 0, "```adl\nnewtype Instant = Int64;\n```\n\n" +
+			"Milliseconds since the epoch\n\n" +
+			"defined in `common`\n"},
+		{"String username", 8, "```adl\nString username;\n```\n\n" +
+			"Unique login name\n\n" +
+			"required\n\n" +
+			"field of `HelloReq` in `helix.protoapp.requests`\n"},
+		{`"withIdPrimaryKey"`, 1, "```adl\nBool withIdPrimaryKey = false;\n```\n\n" +
+			"field of `DbTable` in `common.db`\n"},
+		{"Int32,Float", 0, "```adl\nInt32\n```\n\nA signed 32 bit integer\n"},
+		{"struct HelloResp<A,B,C>", 19, "```adl\nB\n```\n\ntype parameter of `HelloResp`\n"},
+	} {
+		line, col := pos(t, src, tc.marker, tc.offset)
+		n, _ := ix.At(line, col)
+		if got := ix.Describe(n); got != tc.want {
+			t.Errorf("%s+%d: got\n%s\nwant\n%s", tc.marker, tc.offset, got, tc.want)
+		}
+	}
+}
+
+func TestWorkspace(t *testing.T) {
+	a := "module a {\nstruct A {\n  Int32 x;\n};\n};\n"
+	b := "module b {\nimport a.A;\n\n@A { \"x\" : 1 }\nstruct B {\n  A a;\n};\nannotation B::a A { \"x\" : 2 };\n};\n"
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{"file:///b.adl": b, "file:///a.adl": a})
+	refs := func(tg Target) string {
+		got := []string{}
+		for _, o := range ws.Refs(tg) {
+			got = append(got, fmt.Sprintf("%s:%d:%d %s %v", o.URI, o.Line, o.Column, o.Kind, o.Declares))
+		}
+		return strings.Join(got, "\n")
+	}
+	want := "file:///a.adl:1:7 decl true\n" +
+		"file:///b.adl:1:9 import false\n" +
+		"file:///b.adl:3:1 annotation false\n" +
+		"file:///b.adl:5:2 type ref false\n" +
+		"file:///b.adl:7:16 annotation false"
+	if got := refs(Target{Ref: adl.ScopedName{ModuleName: "a", Name: "A"}}); got != want {
+		t.Errorf("decl got\n%s\nwant\n%s", got, want)
+	}
+	want = "file:///a.adl:2:8 field true\n" +
+		"file:///b.adl:3:5 json key false\n" +
+		"file:///b.adl:7:20 json key false"
+	if got := refs(Target{Ref: adl.ScopedName{ModuleName: "a", Name: "A"}, Field: "x"}); got != want {
+		t.Errorf("field got\n%s\nwant\n%s", got, want)
+	}
+	// renaming the field in a re-indexes b
+	ws.Update("file:///a.adl", strings.Replace(a, "x;", "y;", 1))
+	if got := refs(Target{Ref: adl.ScopedName{ModuleName: "a", Name: "A"}, Field: "x"}); got != "" {
+		t.Errorf("stale refs\n%s", got)
+	}
+	ws.Remove("file:///b.adl")
+	if uris := ws.URIs(); len(uris) != 1 {
+		t.Errorf("got %v", uris)
+	}
+}
+
+func TestDeclaration(t *testing.T) {
+	a := "module a {\nstruct A<T> {\n  T x;\n};\n};\n"
+	b := "module b {\nimport a.A;\n\n@A { \"x\" : 1 }\nstruct B {\n  A<Int32> a;\n  @Doc \"b\"\n  Int32 y;\n};\n};\n"
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{"file:///b.adl": b, "file:///a.adl": a})
+	for _, tc := range []struct {
+		uri, src, marker string
+		offset           int
+		want             string
+	}{
+		{"file:///b.adl", b, "a.A", 0, "file:///a.adl:0:7 module"},
+		{"file:///b.adl", b, "a.A", 2, "file:///a.adl:1:7 decl"},
+		{"file:///b.adl", b, "A<Int32>", 0, "file:///a.adl:1:7 decl"},
+		{"file:///b.adl", b, "\"x\"", 1, "file:///a.adl:2:4 field"},
+		{"file:///b.adl", b, "B {", 0, "file:///b.adl:4:7 decl"},
+		{"file:///a.adl", a, "T x", 0, "file:///a.adl:1:9 type param"},
+		// declared outside the workspace
+		{"file:///b.adl", b, "Doc", 0, ""},
+		{"file:///b.adl", b, "Int32", 0, ""},
+	} {
+		ix, _ := ws.File(tc.uri)
+		line, col := pos(t, tc.src, tc.marker, tc.offset)
+		got := ""
+		if n, ok := ix.At(line, col); ok {
+			if tg, ok := n.Target(); ok {
+				if o, ok := ws.Declaration(tg); ok {
+					got = fmt.Sprintf("%s:%d:%d %s", o.URI, o.Line, o.Column, o.Kind)
+				}
+			}
+		}
+		if got != tc.want {
+			t.Errorf("%s %q: got %q want %q", tc.uri, tc.marker, got, tc.want)
+		}
+	}
+}
+
+func TestRename(t *testing.T) {
+	a := "module a {\nstruct A<T> {\n  T x;\n  @SerializedName \"why\"\n  Int32 y;\n};\nstruct Z {\n  Int32 z;\n};\n};\n"
+	b := "module b {\nimport a.A;\n\n@A { \"x\" : 1, \"why\" : 2 }\nstruct B<X> {\n  A<X> a;\n};\nannotation B::a A { \"x\" : 2 };\n};\n"
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{"file:///b.adl": b, "file:///a.adl": a})
+	sn := adl.ScopedName{ModuleName: "a", Name: "A"}
+	for _, tc := range []struct {
+		target     Target
+		name       string
+		serialized bool
+		want       string
+	}{
+		{Target{Ref: sn}, "AA", false, "file:///a.adl 1:7:1 AA|" +
+			"file:///b.adl 1:9:1 AA|file:///b.adl 3:1:1 AA|file:///b.adl 5:2:1 AA|file:///b.adl 7:16:1 AA"},
+		{Target{Ref: sn}, "Z", false, "a already declares Z"},
+		{Target{Ref: sn}, "X", false, "X would refer to the type parameter X of B"},
+		{Target{Ref: sn}, "String", false, "'String' is a primitive type"},
+		{Target{Ref: sn}, "a b", false, "'a b' isn't a valid name"},
+		{Target{Ref: sn, Field: "x"}, "xx", false, "renaming A::x changes how it is serialized, annotate it with @SerializedName \"x\" first"},
+		{Target{Ref: sn, Field: "x"}, "xx", true, "file:///a.adl 2:4:1 xx|file:///b.adl 3:6:1 xx|file:///b.adl 7:21:1 xx"},
+		{Target{Ref: sn, Field: "x"}, "why", true, "A::y is already serialized as why"},
+		{Target{Ref: sn, Field: "x"}, "y", true, "A already has a field y"},
+		{Target{Ref: sn, Field: "y"}, "yy", false, "file:///a.adl 4:8:1 yy"},
+		{Target{Ref: sn, Field: "T", TypeParam: true}, "U", false, "file:///a.adl 1:9:1 U|file:///a.adl 2:2:1 U"},
+		{Target{Ref: adl.ScopedName{ModuleName: "b", Name: "B"}, Field: "X", TypeParam: true}, "A", false, "B would no longer refer to a.A"},
+		{Target{Ref: adl.ScopedName{ModuleName: "a"}}, "c", false, "module a can't be renamed"},
+	} {
+		edits, err := ws.Rename(tc.target, tc.name, tc.serialized)
+		got := []string{}
+		if err != nil {
+			got = append(got, err.Error())
+		}
+		for _, uri := range []string{"file:///a.adl", "file:///b.adl"} {
+			for _, ed := range edits[uri] {
+				got = append(got, fmt.Sprintf("%s %d:%d:%d %s", uri, ed.Line, ed.Column, ed.Len, ed.Text))
+			}
+		}
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("%v %s: got\n%s\nwant\n%s", tc.target, tc.name, strings.Join(got, "|"), tc.want)
+		}
+	}
+}
+
+func TestFolds(t *testing.T) {
+	src := `module test {
+import a.A;
+import a.B;
+
+/// one
+/// two
+struct S {
+  /// x
+  Int32 x;
+  Vector<Int32> v = [
+    1,
+    2
+  ];
+};
+/// z
+union Z {
+  Int32 z;
+};
+};
+`
+	got := []string{}
+	for _, f := range Folds(src) {
+		got = append(got, fmt.Sprintf("%d-%d %s", f.StartLine, f.EndLine, f.Kind))
+	}
+	want := "0-17 region|1-2 imports|4-5 comment|6-12 region|9-11 region|15-16 region"
+	if strings.Join(got, "|") != want {
+		t.Errorf("got %s want %s", strings.Join(got, "|"), want)
+	}
+	if fs := Folds(src[:40]); len(fs) != 0 {
+		t.Errorf("got %v for a syntax error", fs)
+	}
+}
+
+func TestSymbols(t *testing.T) {
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{
+		"file:///r.adl":  adltest.OneOfEachAdl,
+		"file:///ab.adl": "module a.b {\nstruct HelloRequest<T> {\n  T req;\n};\nunion Hr {\n  Void h;\n};\n};\n",
+	})
+	for _, tc := range []struct {
+		query string
+		want  string
+	}{
+		{"hreq", "HelloReq struct helix.protoapp.requests|HelloRequest struct a.b|helix.protoapp.requests  helix.protoapp.requests"},
+		{"hr", "Hr union a.b|HelloReq struct helix.protoapp.requests|HelloResp struct helix.protoapp.requests"},
+		{"a.b", "a.b  a.b"},
+		{"zzz", ""},
+	} {
+		got := []string{}
+		for _, s := range ws.Symbols(tc.query, 3) {
+			got = append(got, s.Text+" "+s.Keyword+" "+s.Module)
+		}
+		if strings.Join(got, "|") != tc.want {
+			t.Errorf("%s: got %s want %s", tc.query, strings.Join(got, "|"), tc.want)
+		}
+	}
+	if syms := ws.Symbols("", 1000); len(syms) < 50 {
+		t.Errorf("expected every symbol for an empty query, got %d", len(syms))
+	}
+}
+
+func TestOutlines(t *testing.T) {
+	src := `module test {
+import a.*;
+import b.C;
+
+/// doc
+@A 1
+union U<T> {
+  T t;
+  @B { "x" : 1 }
+  Vector<T> v;
+};
+struct S {
+  Int32 x
+  Vector<
+};
+newtype N = Int32;
+annotation U::v C 2;
+annotation U C 3;
+annotation X::y C 4;
+annotation M 5;
+};
+`
+	got := []string{}
+	var walk func(depth int, os []Outline)
+	walk = func(depth int, os []Outline) {
+		for _, o := range os {
+			s := o.Selection
+			got = append(got, fmt.Sprintf("%s%s %s %q %s %d:%d-%d:%d", strings.Repeat(" ", depth), o.Kind, o.Name,
+				o.Detail, o.Keyword, s.StartLine, s.StartColumn, s.EndLine, s.EndColumn))
+			walk(depth+1, o.Children)
+		}
+	}
+	walk(0, Outlines(cst.Parse(src)))
+	want := []string{
+		`module test ""  0:7-0:11`,
+		` import a.* ""  1:7-1:8`,
+		` import b.C ""  2:7-2:10`,
+		` decl U "" union 6:6-6:7`,
+		`  annotation @A "1"  5:1-5:2`,
+		`  type param T ""  6:8-6:9`,
+		`  field t "T" union 7:4-7:5`,
+		`  field v "Vector<T>" union 9:12-9:13`,
+		`   annotation @B "{ \"x\" : 1 }"  8:3-8:4`,
+		`   annotation @C "2"  16:16-16:17`,
+		`  annotation @C "3"  17:13-17:14`,
+		` decl S "" struct 11:7-11:8`,
+		`  field x "Int32" struct 12:8-12:9`,
+		` decl N "Int32" newtype 15:8-15:9`,
+		` annotation @C "4"  18:16-18:17`,
+		` annotation @M "5"  19:11-19:12`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestComplete(t *testing.T) {
+	a := "/// The b\nmodule a.b {\n/// The x\nstruct X<T> {\n  T x;\n};\nunion Y {\n  Void y;\n};\n};\n"
+	src := "module c {\nimport a.b.X;\n\n@Doc \"s\"\nstruct S<P> {\n  @Doc \"f\" Vector<P> f;\n  X<Int32> g = {};\n};\nnewtype N = Int32 = 1;\n};\n"
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{"file:///a.adl": a, "file:///c.adl": src})
+	ix, _ := ws.File("file:///c.adl")
+	for _, tc := range []struct {
+		// | is the position in the text of src
+		marker string
+		want   string
+	}{
+		{"module c {\n|", "keyword import|keyword struct|keyword union|keyword type|keyword newtype|keyword annotation"},
+		{"|module", "keyword module"},
+		{"import |a", "module ref a"},
+		{"import a|", "module ref a"},
+		{"import a.|b", "module b a.b The b"},
+		{"import a.b.|X", "decl X a.b.X The x|decl Y a.b.Y|module * a.b"},
+		{"import a.b.X|", "decl X a.b.X The x|decl Y a.b.Y|module * a.b"},
+		{"@|Doc \"s\"", "annotation N c.N|annotation S c.S s|annotation X a.b.X The x|annotation Doc sys.annotations.Doc"},
+		{"  |@Doc \"f\"", "type param P|decl N c.N|decl S c.S s|decl X a.b.X The x|primitive Void"},
+		{"\"f\" Vector<|P", "type param P|decl N c.N|decl S c.S s"},
+		{"\"f\" |Vector", "type param P|decl N c.N|decl S c.S s"},
+		{"Vector<P> |f", ""},
+		{"X<Int32> g = {|", `field "x" Int32 x;`},
+		{"newtype N = |Int32", "decl N c.N|decl S c.S s"},
+		{"newtype N = Int32 = |1", "primitive 0 Int32"},
+		{"\"|s\"", `primitive "" Doc`},
+		{"S<P> |{", ""},
+	} {
+		i := strings.Index(tc.marker, "|")
+		line, col := pos(t, src, tc.marker[:i]+tc.marker[i+1:], i)
+		cs, ok := ix.Complete(line, col)
+		got := []string{}
+		for _, c := range cs {
+			got = append(got, strings.TrimSpace(c.Kind.String()+" "+c.Label+" "+c.Detail+" "+c.Doc))
+		}
+		if !ok {
+			got = append(got, "!")
+		}
+		if g := strings.Join(got, "|"); !strings.HasPrefix(g, tc.want) || tc.want == "" && g != "" {
+			t.Errorf("%q: got %s want %s", tc.marker, g, tc.want)
+		}
+	}
+	// an unterminated file
+	broken := Parse("module c {\nimport a.b.X;\nstruct S {\n  Int32 x;\n  Vec", ws.AllMod())
+	if cs, _ := broken.Complete(4, 5); len(cs) == 0 || cs[0].Label != "S" {
+		t.Errorf("got %v after a syntax error", cs)
+	}
+}
+
+func TestCompleteJson(t *testing.T) {
+	a := "module a.b {\n/// The x\nstruct X<T> {\n  T x;\n};\nunion Y {\n  Void y;\n  X<String> z;\n};\n" +
+		"struct Z {\n  Vector<Y> ys;\n  StringMap<X<Bool>> m;\n  Nullable<Int32> n;\n  Bool b = true;\n};\n};\n"
+	src := `module c {
+import a.b.*;
+
+@Z { "ys" : [ { "z" : { "x" : "" } } ], "m" : { "k" : { "x" : true } }, "n" : null }
+struct S {
+  X<Int32> g = {};
+};
+annotation S::g Z {};
+annotation S Y "y";
+};
+`
+	ws := NewWorkspace()
+	ws.SetBase(adltest.Modules())
+	ws.Load(map[string]string{"file:///a.adl": a, "file:///c.adl": src})
+	ix, _ := ws.File("file:///c.adl")
+	for _, tc := range []struct {
+		marker string
+		want   string
+	}{
+		{`@Z { "ys" : [ { "z" : { "x" : "" } } ], |`, `field "m" StringMap<X<Bool>> m; "m" : ${1:{\}}|field "n" Nullable<Int32> n; "n" : ${1:null}|field "b"`},
+		{`"ys" : [ |{`, `field "y" Void y; "y"|field "z" X<String> z; { "z" : { "x" : ${1:""} } }`},
+		{`{ "z" : { |"x"`, `field "x" String x; "x" : ${1:""}`},
+		{`"x" : |""`, `primitive "" String ${1:""}`},
+		{`"x" : "|"`, `primitive "" String ${1:""}`},
+		{`"m" : { |"k"`, ``},
+		{`"k" : { "x" : |true`, `keyword true|keyword false`},
+		{`"k" : { "x" : t|rue`, `keyword true|keyword false`},
+		{`"n" : |null`, `keyword null|primitive 0 Nullable<Int32> ${1:0}`},
+		{`"n" : null |}`, ``},
+		{`annotation S::g Z {|}`, `field "ys" Vector<Y> ys; "ys" : ${1:[]}|field "m" StringMap<X<Bool>> m; "m" : ${1:{\}}|field "n"`},
+		{`annotation S Y |"y"`, `field "y" Void y; "y"|field "z"`},
+		{`annotation S Y "|y"`, `field "y" Void y; "y"|field "z"`},
+		{`g = {|`, `field "x" Int32 x; "x" : ${1:0}`},
+	} {
+		i := strings.Index(tc.marker, "|")
+		line, col := pos(t, src, tc.marker[:i]+tc.marker[i+1:], i)
+		cs, ok := ix.Complete(line, col)
+		got := []string{}
+		for _, c := range cs {
+			got = append(got, strings.TrimSpace(c.Kind.String()+" "+c.Label+" "+c.Detail+" "+c.Insert))
+		}
+		if g := strings.Join(got, "|"); !ok || !strings.HasPrefix(g, tc.want) || tc.want == "" && g != "" {
+			t.Errorf("%q: got %s want %s", tc.marker, g, tc.want)
+		}
+	}
+	// a key being written, the quote isn't closed
+	ix = Parse("module c {\nimport a.b.*;\n@Z { \"y", ws.AllMod())
+	if cs, _ := ix.Complete(2, 7); len(cs) == 0 || cs[0].Label != `"ys"` || cs[0].Range.StartColumn != 5 {
+		t.Errorf("got %v", cs)
+	}
+	ix, _ = ws.File("file:///c.adl")
+	// the quotes are replaced
+	line, col := pos(t, src, `annotation S Y "y`, 16)
+	if cs, _ := ix.Complete(line, col); len(cs) == 0 || cs[0].Range != (Range{line, col - 1, line, col + 2}) {
+		t.Errorf("got %v", cs)
+	}
+}
@@ -0,0 +1,200 @@
+package nav
+
+import (
+	"strings"
+
+	"github.com/wxio/tron-go/adl/cst"
+)
+
+// Range of source, zero based, the end is exclusive
+type Range struct {
+	StartLine, StartColumn, EndLine, EndColumn int
+}
+
+// Outline is a symbol of a file's outline, the module, an import, a decl, type param, field or annotation
+type Outline struct {
+	// Kind is Module, Import, Decl, TypeParam, Field or Anno
+	Kind Kind
+	Name string
+	// Keyword declaring the decl, or the decl of the field
+	Keyword string
+	// Detail is the type of a field, the type of a type or newtype decl, or the value of an annotation
+	Detail string
+	// Range is the statement's, Selection the name's
+	Range, Selection Range
+	Children         []Outline
+}
+
+// Outlines of the file, a syntax error only loses the statements it breaks. Out-of-line annotations are
+// children of the decl or field they annotate, or of the module.
+func Outlines(f *cst.File) []Outline {
+	outs := []Outline{}
+	for _, ms := range rules(f.Root) {
+		if ms.Rule != "ModuleStatement" {
+			continue
+		}
+		ts := leaves(ms)
+		name := dotted(from(ts, 1))
+		if len(name) == 0 {
+			continue
+		}
+		mo := Outline{Kind: Module, Name: text(name), Range: span(ms.Tokens()), Selection: span(name)}
+		stmts := []*cst.Node{}
+		for _, n := range rules(ms) {
+			switch n.Rule {
+			case "ImportModuleName", "ImportScopedName":
+				its := dotted(from(leaves(n), 1))
+				if len(its) == 0 {
+					continue
+				}
+				io := Outline{Kind: Import, Name: text(its), Range: span(n.Tokens()), Selection: span(its)}
+				if n.Rule == "ImportModuleName" {
+					io.Name += ".*"
+				}
+				mo.Children = append(mo.Children, io)
+			case "LocalAnno":
+				if o, ok := annoOutline(n, from(leaves(n), 1)); ok {
+					mo.Children = append(mo.Children, o)
+				}
+			case "StructOrUnion", "TypeOrNewtype":
+				if o, ok := declOutline(n); ok {
+					mo.Children = append(mo.Children, o)
+				}
+			case "ModuleAnnotation", "DeclAnnotation", "FieldAnnotation":
+				stmts = append(stmts, n)
+			}
+		}
+		for _, n := range stmts {
+			ts := leaves(n)
+			parent := &mo
+			switch {
+			case n.Rule == "DeclAnnotation" && len(ts) >= 3:
+				parent = child(parent, Decl, ts[1].Text)
+				ts = ts[2:]
+			case n.Rule == "FieldAnnotation" && len(ts) >= 5:
+				parent = child(child(parent, Decl, ts[1].Text), Field, ts[3].Text)
+				ts = ts[4:]
+			case n.Rule == "ModuleAnnotation" && len(ts) >= 2:
+				ts = ts[1:]
+			default:
+				continue
+			}
+			if o, ok := annoOutline(n, ts); ok {
+				parent.Children = append(parent.Children, o)
+			}
+		}
+		outs = append(outs, mo)
+	}
+	return outs
+}
+
+// from are the tokens from i on
+func from(ts []*cst.Token, i int) []*cst.Token {
+	if len(ts) < i {
+		return nil
+	}
+	return ts[i:]
+}
+
+// child is the outline's child of the kind and name, or the outline when there is none
+func child(o *Outline, kind Kind, name string) *Outline {
+	for i, c := range o.Children {
+		if c.Kind == kind && c.Name == name {
+			return &o.Children[i]
+		}
+	}
+	return o
+}
+
+func declOutline(n *cst.Node) (Outline, bool) {
+	ts := leaves(n)
+	if len(ts) < 2 {
+		return Outline{}, false
+	}
+	o := Outline{Kind: Decl, Name: ts[1].Text, Keyword: ts[0].Text, Range: span(n.Tokens()), Selection: span(ts[1:2])}
+	for _, c := range rules(n) {
+		switch {
+		case c.Rule == "LocalAnno":
+			if ao, ok := annoOutline(c, from(leaves(c), 1)); ok {
+				o.Children = append(o.Children, ao)
+			}
+		case c.Rule == "TypeParameter":
+			for _, t := range leaves(c) {
+				if t.Text != "<" && t.Text != ">" && t.Text != "," {
+					r := span([]*cst.Token{t})
+					o.Children = append(o.Children, Outline{Kind: TypeParam, Name: t.Text, Range: r, Selection: r})
+				}
+			}
+		case c.Rule == "FieldStatement":
+			if fo, ok := fieldOutline(c, o.Keyword); ok {
+				o.Children = append(o.Children, fo)
+			}
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			o.Detail = compact(c.Tokens())
+		}
+	}
+	return o, true
+}
+
+func fieldOutline(n *cst.Node, keyword string) (Outline, bool) {
+	ts := leaves(n)
+	if len(ts) == 0 {
+		return Outline{}, false
+	}
+	o := Outline{Kind: Field, Name: ts[0].Text, Keyword: keyword, Range: span(n.Tokens()), Selection: span(ts[:1])}
+	for _, c := range rules(n) {
+		switch {
+		case c.Rule == "LocalAnno":
+			if ao, ok := annoOutline(c, from(leaves(c), 1)); ok {
+				o.Children = append(o.Children, ao)
+			}
+		case strings.HasPrefix(c.Rule, "TypeExpr"):
+			o.Detail = compact(c.Tokens())
+		}
+	}
+	return o, true
+}
+
+// annoOutline of a prefix annotation or annotation statement, ts start with the annotation's name
+func annoOutline(n *cst.Node, ts []*cst.Token) (Outline, bool) {
+	if len(ts) == 0 {
+		return Outline{}, false
+	}
+	o := Outline{Kind: Anno, Name: "@" + ts[0].Text, Range: span(n.Tokens()), Selection: span(ts[:1])}
+	if vs := rules(n); len(vs) != 0 {
+		o.Detail = compact(vs[0].Tokens())
+	}
+	return o, true
+}
+
+// maxDetail is the most runes of a detail shown
+const maxDetail = 60
+
+// compact is the text of the tokens on one line
+func compact(ts []*cst.Token) string {
+	s := ""
+	for i, t := range ts {
+		if i != 0 && (len(t.Leading) != 0 || len(ts[i-1].Trailing) != 0) {
+			s += " "
+		}
+		s += t.Text
+	}
+	if rs := []rune(s); len(rs) > maxDetail {
+		s = string(rs[:maxDetail]) + "..."
+	}
+	return s
+}
+
+// span is the range of the tokens
+func span(ts []*cst.Token) Range {
+	if len(ts) == 0 {
+		return Range{}
+	}
+	first, last := ts[0], ts[len(ts)-1]
+	return Range{
+		StartLine:   first.Line - 1,
+		StartColumn: first.Column,
+		EndLine:     last.Line - 1,
+		EndColumn:   last.Column + len([]rune(last.Text)),
+	}
+}
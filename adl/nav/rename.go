@@ -0,0 +1,145 @@
+package nav
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Edit replaces Len runes of a line from Column with Text, zero based
+type Edit struct {
+	Line   int
+	Column int
+	Len    int
+	Text   string
+}
+
+var ident = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// Rename the decl, field or type param of the target to name, the edits are by uri and in source order.
+// Renaming a field without a SerializedName annotation changes its serialized name, which is refused unless
+// serialized is set, the keys of JSON values in the workspace are then renamed too.
+func (ws *Workspace) Rename(t Target, name string, serialized bool) (map[string][]Edit, error) {
+	if t.Ref.Name == "" {
+		return nil, fmt.Errorf("module %s can't be renamed", t.Ref.ModuleName)
+	}
+	if !ident.MatchString(name) {
+		return nil, fmt.Errorf("'%s' isn't a valid name", name)
+	}
+	if adl.IsPrimitive(name) {
+		return nil, fmt.Errorf("'%s' is a primitive type", name)
+	}
+	occs := ws.Refs(t)
+	declared := false
+	for _, o := range occs {
+		declared = declared || o.Declares
+	}
+	if !declared {
+		return nil, fmt.Errorf("%s isn't declared in the workspace", t.Ref)
+	}
+	old := t.Ref.Name
+	jsonKeys := false
+	var err error
+	switch {
+	case t.TypeParam:
+		old = t.Field
+		err = ws.renameTypeParam(t, name)
+	case t.Field != "":
+		old = t.Field
+		jsonKeys, err = ws.renameField(t, name, serialized)
+	default:
+		err = ws.renameDecl(t, name, occs)
+	}
+	if err != nil || old == name {
+		return nil, err
+	}
+	edits := map[string][]Edit{}
+	for _, o := range occs {
+		ed := Edit{Line: o.Line, Column: o.Column + o.Len() - len([]rune(old)), Len: len([]rune(old)), Text: name}
+		if o.Kind == JsonKey {
+			if !jsonKeys {
+				continue
+			}
+			// inside the quotes
+			ed = Edit{Line: o.Line, Column: o.Column + 1, Len: o.Len() - 2, Text: name}
+		}
+		edits[o.URI] = append(edits[o.URI], ed)
+	}
+	return edits, nil
+}
+
+// renameDecl checks the decl's new name is free in its module and in the files referring to it
+func (ws *Workspace) renameDecl(t Target, name string, occs []Occurrence) error {
+	_, ix, ok := ws.Module(t.Ref.ModuleName)
+	if !ok {
+		return fmt.Errorf("no module %s in the workspace", t.Ref.ModuleName)
+	}
+	if _, ex := ix.AllMod[ix.Module].Decls[name]; ex || ix.local[name] {
+		return fmt.Errorf("%s already declares %s", ix.Module, name)
+	}
+	checked := map[string]bool{}
+	for _, o := range occs {
+		fix, _ := ws.File(o.URI)
+		// a type param of the decl the name is used in would shadow it
+		if o.Decl != "" && !o.Declares {
+			if _, ex := fix.Find(TypeParam, adl.ScopedName{ModuleName: fix.Module, Name: o.Decl}, name); ex {
+				return fmt.Errorf("%s would refer to the type parameter %s of %s", name, name, o.Decl)
+			}
+		}
+		if checked[o.URI] {
+			continue
+		}
+		checked[o.URI] = true
+		if sn := fix.resolve(name); sn.ModuleName != "" {
+			return fmt.Errorf("%s already refers to %s in %s", name, sn, fix.Module)
+		}
+	}
+	return nil
+}
+
+// renameField checks the decl has no field with the new name, and whether the JSON keys are renamed
+func (ws *Workspace) renameField(t Target, name string, serialized bool) (bool, error) {
+	_, ix, ok := ws.Module(t.Ref.ModuleName)
+	if !ok {
+		return false, fmt.Errorf("no module %s in the workspace", t.Ref.ModuleName)
+	}
+	if _, ex := ix.Find(Field, t.Ref, name); ex {
+		return false, fmt.Errorf("%s already has a field %s", t.Ref.Name, name)
+	}
+	d := ix.AllMod[t.Ref.ModuleName].Decls[t.Ref.Name]
+	for _, f := range d.Fields() {
+		if _, ok := f.Annotations.Find(adl.SerializedNameAnno); ok && f.Name == t.Field {
+			return false, nil
+		}
+	}
+	if !serialized {
+		return false, fmt.Errorf("renaming %s::%s changes how it is serialized, annotate it with @SerializedName \"%s\" first", t.Ref.Name, t.Field, t.Field)
+	}
+	for _, f := range d.Fields() {
+		if f.Name != t.Field && f.SerializedName == name {
+			return false, fmt.Errorf("%s::%s is already serialized as %s", t.Ref.Name, f.Name, name)
+		}
+	}
+	return true, nil
+}
+
+// renameTypeParam checks the decl has no type param with the new name and doesn't refer to a decl by it
+func (ws *Workspace) renameTypeParam(t Target, name string) error {
+	_, ix, ok := ws.Module(t.Ref.ModuleName)
+	if !ok {
+		return fmt.Errorf("no module %s in the workspace", t.Ref.ModuleName)
+	}
+	for _, n := range ix.Names {
+		if n.Decl != t.Ref.Name {
+			continue
+		}
+		if n.Kind == TypeParam && n.Field == name {
+			return fmt.Errorf("%s already has a type parameter %s", t.Ref.Name, name)
+		}
+		if n.Kind == TypeRef && n.Text == name {
+			return fmt.Errorf("%s would no longer refer to %s", t.Ref.Name, n.Ref)
+		}
+	}
+	return nil
+}
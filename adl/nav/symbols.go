@@ -0,0 +1,79 @@
+package nav
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Symbol is a module, decl or field declared in the workspace
+type Symbol struct {
+	Occurrence
+	// Keyword declaring the decl, or the decl of the field, empty for modules
+	Keyword string
+	// Module the symbol is declared in
+	Module string
+	score  int
+}
+
+// Symbols are the modules, decls and fields whose names match the query, best first and at most max of them.
+// The query matches the names that have its runes in order ignoring case, ie "hreq" matches HelloReq.
+func (ws *Workspace) Symbols(query string, max int) []Symbol {
+	syms := []Symbol{}
+	for _, uri := range ws.URIs() {
+		ix, _ := ws.File(uri)
+		for _, n := range ix.Names {
+			if !n.Declares || n.Kind == TypeParam {
+				continue
+			}
+			score, ok := fuzzy(query, n.Text)
+			if !ok {
+				continue
+			}
+			syms = append(syms, Symbol{
+				Occurrence: Occurrence{URI: uri, Name: n},
+				Keyword:    ix.Keyword(n.Decl),
+				Module:     ix.Module,
+				score:      score,
+			})
+		}
+	}
+	sort.SliceStable(syms, func(i, j int) bool {
+		if syms[i].score != syms[j].score {
+			return syms[i].score > syms[j].score
+		}
+		return syms[i].Text < syms[j].Text
+	})
+	if len(syms) > max {
+		syms = syms[:max]
+	}
+	return syms
+}
+
+// fuzzy scores how well the pattern matches s, false when the runes of the pattern aren't in s in order ignoring
+// case. Matches at the start of s or of a word and runs of matches score more, as do shorter names.
+func fuzzy(pattern, s string) (int, bool) {
+	pr, sr := []rune(strings.ToLower(pattern)), []rune(s)
+	score, j, prev := 0, 0, -2
+	for i := 0; i < len(sr) && j < len(pr); i++ {
+		if unicode.ToLower(sr[i]) != pr[j] {
+			continue
+		}
+		switch {
+		case i == 0:
+			score += 8
+		case i == prev+1:
+			score += 5
+		case unicode.IsUpper(sr[i]) && !unicode.IsUpper(sr[i-1]) || sr[i-1] == '.' || sr[i-1] == '_':
+			score += 4
+		default:
+			score++
+		}
+		prev = i
+		j++
+	}
+	if j < len(pr) {
+		return 0, false
+	}
+	return score*100 - len(sr), true
+}
@@ -0,0 +1,98 @@
+package nav
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/cst"
+)
+
+// ValueType is the type of a JSON value of the source, an annotation's value, a default or a value in them
+func (ix *Index) ValueType(n *cst.Node) (adl.TypeExpr, bool) {
+	te, ok := ix.values[n]
+	return te, ok
+}
+
+// maxPlaceholder is how deep placeholders nest, deeper structs are empty
+const maxPlaceholder = 4
+
+// Placeholder is the least JSON value of the type, structs have their required fields, unions their first branch
+// and numbers are zero
+func (ix *Index) Placeholder(te adl.TypeExpr) string {
+	return ix.placeholder(te, 0, nil)
+}
+
+// Snippet is the placeholder as an LSP snippet, its scalars and empty values are tab stops
+func (ix *Index) Snippet(te adl.TypeExpr) string {
+	tab := 0
+	return ix.placeholder(te, 0, &tab)
+}
+
+// placeholder numbers the tab stops with tab, unless it is nil
+func (ix *Index) placeholder(te adl.TypeExpr, depth int, tab *int) string {
+	stop := func(v string) string {
+		if tab == nil {
+			return v
+		}
+		*tab++
+		return fmt.Sprintf("${%d:%s}", *tab, snippetEscaper.Replace(v))
+	}
+	if p := te.TypeRef.Primitive; p != nil && *p == "Nullable" {
+		return stop("null")
+	}
+	ete, d, ok := ix.Expand(te)
+	switch {
+	case !ok:
+		return stop("null")
+	case ete.TypeRef.Primitive != nil:
+		switch *ete.TypeRef.Primitive {
+		case "String", "Bytes":
+			return stop(`""`)
+		case "Bool":
+			return stop("false")
+		case "Vector":
+			return stop("[]")
+		case "StringMap":
+			return stop("{}")
+		case "Void", "Json":
+			return stop("null")
+		}
+		return stop("0")
+	}
+	fs, _, _, _ := ix.Fields(ete)
+	if d.Type.Union != nil {
+		if len(fs) == 0 {
+			return stop("null")
+		}
+		return ix.branch(fs[0], depth, tab)
+	}
+	if depth >= maxPlaceholder {
+		return stop("{}")
+	}
+	kvs := []string{}
+	for _, f := range fs {
+		if _, ok := adl.Just(f.Default); !ok {
+			kvs = append(kvs, `"`+f.SerializedName+`" : `+ix.placeholder(f.TypeExpr, depth+1, tab))
+		}
+	}
+	if len(kvs) == 0 {
+		return stop("{}")
+	}
+	return "{ " + strings.Join(kvs, ", ") + " }"
+}
+
+// branch is the value of a union with the branch, its name for Void
+func (ix *Index) branch(f adl.Field, depth int, tab *int) string {
+	if isVoid(f.TypeExpr) {
+		return `"` + f.SerializedName + `"`
+	}
+	return `{ "` + f.SerializedName + `" : ` + ix.placeholder(f.TypeExpr, depth+1, tab) + " }"
+}
+
+// snippetEscaper escapes the text of a snippet's placeholder
+var snippetEscaper = strings.NewReplacer(`\`, `\\`, `$`, `\$`, `}`, `\}`)
+
+func isVoid(te adl.TypeExpr) bool {
+	return te.TypeRef.Primitive != nil && *te.TypeRef.Primitive == "Void"
+}
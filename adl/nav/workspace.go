@@ -0,0 +1,175 @@
+package nav
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+)
+
+// Workspace indexes the ADL files of a workspace, by uri, and is kept up to date as they change.
+// The files are resolved against each other and the base modules, ie the include paths as resolved by adlc.
+type Workspace struct {
+	mutex sync.RWMutex
+	base  map[string]adl.Module
+	files map[string]*Index
+}
+
+// Occurrence is a name of a file
+type Occurrence struct {
+	URI string
+	Name
+}
+
+func NewWorkspace() *Workspace {
+	return &Workspace{base: map[string]adl.Module{}, files: map[string]*Index{}}
+}
+
+// SetBase sets the modules the files are resolved against along with each other, modules of the files take
+// precedence. The files aren't re-indexed.
+func (ws *Workspace) SetBase(allmod map[string]adl.Module) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ws.base = allmod
+}
+
+// Base are the modules set by SetBase
+func (ws *Workspace) Base() map[string]adl.Module {
+	ws.mutex.RLock()
+	defer ws.mutex.RUnlock()
+	return ws.base
+}
+
+// Load indexes the files, by uri, replacing those indexed with the same uris
+func (ws *Workspace) Load(srcs map[string]string) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	allmod := ws.allMod()
+	// read the modules until no more decls resolve, the files may import each other in any order
+	for count := -1; ; {
+		n := 0
+		for _, src := range srcs {
+			mod, _ := adlbuild.ParsePartial(src, allmod)
+			if mod.Name != "" {
+				allmod[mod.Name] = mod
+				n += len(mod.Decls)
+			}
+		}
+		if n == count {
+			break
+		}
+		count = n
+	}
+	for uri, src := range srcs {
+		ws.files[uri] = Parse(src, allmod)
+	}
+}
+
+// Update indexes a file, the files importing its module are re-indexed as its decls may have changed
+func (ws *Workspace) Update(uri, src string) *Index {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	ix := Parse(src, ws.allMod())
+	prev, ex := ws.files[uri]
+	ws.files[uri] = ix
+	modules := map[string]bool{ix.Module: true}
+	if ex {
+		modules[prev.Module] = true
+	}
+	allmod := ws.allMod()
+	for furi, fix := range ws.files {
+		if furi == uri {
+			continue
+		}
+		for _, n := range fix.Names {
+			if n.Kind == ModuleRef && modules[n.Ref.ModuleName] {
+				ws.files[furi] = Parse(fix.File.String(), allmod)
+				break
+			}
+		}
+	}
+	return ix
+}
+
+// Remove a file from the index
+func (ws *Workspace) Remove(uri string) {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+	delete(ws.files, uri)
+}
+
+// allMod are the base modules and the modules of the files, the lock is held
+func (ws *Workspace) allMod() map[string]adl.Module {
+	allmod := map[string]adl.Module{}
+	for k, v := range ws.base {
+		allmod[k] = v
+	}
+	for _, ix := range ws.files {
+		if mod, ex := ix.AllMod[ix.Module]; ex {
+			allmod[ix.Module] = mod
+		}
+	}
+	return allmod
+}
+
+// AllMod are the base modules and the modules of the files
+func (ws *Workspace) AllMod() map[string]adl.Module {
+	ws.mutex.RLock()
+	defer ws.mutex.RUnlock()
+	return ws.allMod()
+}
+
+// File is the index of a file
+func (ws *Workspace) File(uri string) (*Index, bool) {
+	ws.mutex.RLock()
+	defer ws.mutex.RUnlock()
+	ix, ex := ws.files[uri]
+	return ix, ex
+}
+
+// URIs are the files of the workspace, sorted
+func (ws *Workspace) URIs() []string {
+	ws.mutex.RLock()
+	defer ws.mutex.RUnlock()
+	uris := []string{}
+	for uri := range ws.files {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+	return uris
+}
+
+// Module is the file declaring the module, the first by uri when more than one does
+func (ws *Workspace) Module(name string) (string, *Index, bool) {
+	for _, uri := range ws.URIs() {
+		if ix, ex := ws.File(uri); ex && ix.Module == name {
+			return uri, ix, true
+		}
+	}
+	return "", nil, false
+}
+
+// Refs are the names that declare or refer to the target, by uri and then position
+func (ws *Workspace) Refs(t Target) []Occurrence {
+	occs := []Occurrence{}
+	for _, uri := range ws.URIs() {
+		ix, _ := ws.File(uri)
+		for _, n := range ix.Names {
+			if nt, ok := n.Target(); ok && nt == t {
+				occs = append(occs, Occurrence{URI: uri, Name: n})
+			}
+		}
+	}
+	return occs
+}
+
+// Declaration is where the target is declared, the first declaring name by uri
+func (ws *Workspace) Declaration(t Target) (Occurrence, bool) {
+	for _, o := range ws.Refs(t) {
+		if o.Declares {
+			return o, true
+		}
+	}
+	return Occurrence{}, false
+}
@@ -0,0 +1,345 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tWord
+	tString
+	tSym
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	src string
+	pos int
+}
+
+func isWord(r byte) bool {
+	return r < unicode.MaxASCII && (unicode.IsLetter(rune(r)) || unicode.IsDigit(rune(r))) || strings.IndexByte("_.*?[]^!-", r) >= 0
+}
+
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.src) && unicode.IsSpace(rune(l.src[l.pos])) {
+		l.pos++
+	}
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipSpace()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tEOF, pos: start}, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("()<>,:=~@", c) >= 0:
+		l.pos++
+		return token{kind: tSym, text: string(c), pos: start}, nil
+	case c == '"':
+		s, err := l.quoted()
+		if err != nil {
+			return token{}, err
+		}
+		v, err := strconv.Unquote(s)
+		if err != nil {
+			return token{}, fmt.Errorf("%d: bad string %s", start+1, s)
+		}
+		return token{kind: tString, text: v, pos: start}, nil
+	case isWord(c):
+		for l.pos < len(l.src) && isWord(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tWord, text: l.src[start:l.pos], pos: start}, nil
+	}
+	return token{}, fmt.Errorf("%d: unexpected '%c'", start+1, c)
+}
+
+// quoted scans a double quoted string, returning it with the quotes
+func (l *lexer) quoted() (string, error) {
+	start := l.pos
+	l.pos++
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case '\\':
+			l.pos += 2
+			continue
+		case '"':
+			l.pos++
+			return l.src[start:l.pos], nil
+		}
+		l.pos++
+	}
+	return "", fmt.Errorf("%d: unterminated string", start+1)
+}
+
+// json scans a json value, an array or object by its balanced brackets, a string, or a number, true, false or null
+func (l *lexer) json() (interface{}, error) {
+	l.skipSpace()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return nil, fmt.Errorf("%d: expected a json value", start+1)
+	}
+	switch l.src[l.pos] {
+	case '"':
+		if _, err := l.quoted(); err != nil {
+			return nil, err
+		}
+	case '[', '{':
+		depth := 0
+	scan:
+		for l.pos < len(l.src) {
+			switch l.src[l.pos] {
+			case '"':
+				if _, err := l.quoted(); err != nil {
+					return nil, err
+				}
+				continue
+			case '[', '{':
+				depth++
+			case ']', '}':
+				depth--
+				if depth == 0 {
+					l.pos++
+					break scan
+				}
+			}
+			l.pos++
+		}
+	default:
+		for l.pos < len(l.src) && !unicode.IsSpace(rune(l.src[l.pos])) && l.src[l.pos] != ')' {
+			l.pos++
+		}
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(l.src[start:l.pos]), &v); err != nil {
+		return nil, fmt.Errorf("%d: bad json value '%s'", start+1, l.src[start:l.pos])
+	}
+	return v, nil
+}
+
+type parser struct {
+	lex *lexer
+	tok token
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lex.next()
+	if err != nil {
+		return err
+	}
+	p.tok = tok
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%d: %s", p.tok.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) isSym(s string) bool {
+	return p.tok.kind == tSym && p.tok.text == s
+}
+
+func (p *parser) isKeyword(s string) bool {
+	return p.tok.kind == tWord && p.tok.text == s
+}
+
+func (p *parser) expect(s string) error {
+	if !p.isSym(s) {
+		return p.errorf("expected '%s'", s)
+	}
+	return p.advance()
+}
+
+func (p *parser) or() (node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword("or") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = or{l, r}
+	}
+	return l, nil
+}
+
+func (p *parser) and() (node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.isKeyword("and"):
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+		case p.tok.kind == tWord && !p.isKeyword("or"), p.isSym("("), p.isSym("@"):
+		default:
+			return l, nil
+		}
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = and{l, r}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	switch {
+	case p.isKeyword("not"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{n}, nil
+	case p.isSym("("):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		return n, p.expect(")")
+	case p.isSym("@"):
+		return p.anno()
+	case p.tok.kind == tWord:
+		return p.predicate()
+	case p.tok.kind == tEOF:
+		return nil, p.errorf("unexpected end of query")
+	}
+	return nil, p.errorf("unexpected '%s'", p.tok.text)
+}
+
+func (p *parser) anno() (node, error) {
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tWord {
+		return nil, p.errorf("expected an annotation name after '@'")
+	}
+	a := anno{key: p.tok.text}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.isSym("="):
+		v, err := p.lex.json()
+		if err != nil {
+			return nil, err
+		}
+		a.op, a.val = "=", v
+	case p.isSym("~"):
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tString && p.tok.kind != tWord {
+			return nil, p.errorf("expected a glob after '~'")
+		}
+		if _, err := path.Match(p.tok.text, ""); err != nil {
+			return nil, p.errorf("bad glob '%s'", p.tok.text)
+		}
+		a.op, a.val = "~", p.tok.text
+	default:
+		return a, nil
+	}
+	return a, p.advance()
+}
+
+func (p *parser) predicate() (node, error) {
+	what := p.tok.text
+	switch what {
+	case "kind", "name", "decl", "module", "type", "refs":
+	default:
+		return nil, p.errorf("unknown predicate '%s', expected kind, name, decl, module, type, refs or @annotation", what)
+	}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	if what == "type" || what == "refs" {
+		tp, err := p.typePattern()
+		if err != nil {
+			return nil, err
+		}
+		return typePred{refs: what == "refs", pattern: tp}, nil
+	}
+	if p.tok.kind != tWord && p.tok.kind != tString {
+		return nil, p.errorf("expected a glob after '%s:'", what)
+	}
+	g := glob{what: what, pattern: p.tok.text}
+	if _, err := path.Match(g.pattern, ""); err != nil {
+		return nil, p.errorf("bad glob '%s'", g.pattern)
+	}
+	if what == "kind" && !matchesKind(g.pattern) {
+		return nil, p.errorf("kind '%s' isn't struct, union, type or newtype", g.pattern)
+	}
+	return g, p.advance()
+}
+
+func matchesKind(pattern string) bool {
+	for _, k := range []string{"struct", "union", "type", "newtype"} {
+		if ok, _ := path.Match(pattern, k); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) typePattern() (typePattern, error) {
+	if p.tok.kind != tWord {
+		return typePattern{}, p.errorf("expected a type pattern")
+	}
+	tp := typePattern{name: p.tok.text}
+	if _, err := path.Match(tp.name, ""); err != nil {
+		return tp, p.errorf("bad glob '%s'", tp.name)
+	}
+	if err := p.advance(); err != nil {
+		return tp, err
+	}
+	if !p.isSym("<") {
+		return tp, nil
+	}
+	tp.params = []typePattern{}
+	for {
+		if err := p.advance(); err != nil {
+			return tp, err
+		}
+		pp, err := p.typePattern()
+		if err != nil {
+			return tp, err
+		}
+		tp.params = append(tp.params, pp)
+		if !p.isSym(",") {
+			break
+		}
+	}
+	return tp, p.expect(">")
+}
@@ -0,0 +1,328 @@
+// Package query selects ADL decls and fields with a small query language.
+//
+// A query is an optional subject, decls (the default) or fields, and a boolean expression of predicates combined
+// with and, or, not and parentheses. Adjacent predicates are and-ed.
+//
+//	kind:struct            the kind of the decl, or of the decl of the field: struct, union, type or newtype
+//	name:Hello*            the decl or field name matches a glob
+//	decl:Hello*            the decl name, for fields the name of their decl, matches a glob
+//	module:common.*        the module name matches a glob
+//	@DbTable               has the annotation, named by its name or scoped name
+//	@Path="/login"         has the annotation with the json value
+//	@Path~"/debug/*"       has the annotation with a string value matching a glob
+//	type:Nullable<_>       the type of the field, or of the type or newtype, matches a type pattern
+//	refs:common.Instant    a type expression of the decl or field has a part matching a type pattern
+//
+// In type patterns _ matches any type and a name without parameters matches any parameters, names are primitives,
+// type parameters, scoped names or decl names in any module, globs included. For example
+//
+//	kind:struct @DbTable
+//	refs:common.Instant
+//	fields type:Nullable<_>
+//	fields not module:sys.* (refs:Vector<String> or @SerializedName)
+package query
+
+import (
+	"encoding/json"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Query is a parsed query
+type Query struct {
+	fields bool
+	expr   node
+}
+
+// Result is a decl or a field
+type Result struct {
+	// Kind is struct, union, type, newtype or field
+	Kind   string `json:"kind"`
+	Module string `json:"module"`
+	Decl   string `json:"decl"`
+	Field  string `json:"field,omitempty"`
+	// Type is the type of a field or the type expression of a type or newtype
+	Type string `json:"type,omitempty"`
+}
+
+// String is the kind and the scoped name, ie field a.b.Foo::x Int32
+func (r Result) String() string {
+	s := r.Kind + " " + r.Module + "." + r.Decl
+	if r.Field != "" {
+		s += "::" + r.Field
+	}
+	if r.Type != "" {
+		s += " " + r.Type
+	}
+	return s
+}
+
+// item is what the predicates are evaluated on, a decl or a field of a decl
+type item struct {
+	module string
+	decl   adl.Decl
+	field  *adl.Field
+}
+
+type node interface {
+	eval(it item) bool
+}
+
+// Run returns the matching decls or fields, in module, decl and field order
+func (q *Query) Run(allmod map[string]adl.Module) []Result {
+	rs := []Result{}
+	for _, mn := range adl.ModuleNames(allmod) {
+		mod := allmod[mn]
+		for _, dn := range mod.DeclNames() {
+			decl := mod.Decls[dn]
+			if !q.fields {
+				if q.expr == nil || q.expr.eval(item{module: mn, decl: decl}) {
+					r := Result{Kind: decl.Type.Kind(), Module: mn, Decl: dn}
+					if te, ok := declTypeExpr(decl); ok {
+						r.Type = te.String()
+					}
+					rs = append(rs, r)
+				}
+				continue
+			}
+			fs := decl.Fields()
+			for i := range fs {
+				if q.expr == nil || q.expr.eval(item{module: mn, decl: decl, field: &fs[i]}) {
+					rs = append(rs, Result{Kind: "field", Module: mn, Decl: dn, Field: fs[i].Name, Type: fs[i].TypeExpr.String()})
+				}
+			}
+		}
+	}
+	return rs
+}
+
+func declTypeExpr(decl adl.Decl) (adl.TypeExpr, bool) {
+	switch {
+	case decl.Type.Type != nil:
+		return decl.Type.Type.TypeExpr, true
+	case decl.Type.Newtype != nil:
+		return decl.Type.Newtype.TypeExpr, true
+	}
+	return adl.TypeExpr{}, false
+}
+
+// typeExprs are the type expressions of the item
+func (it item) typeExprs() []adl.TypeExpr {
+	if it.field != nil {
+		return []adl.TypeExpr{it.field.TypeExpr}
+	}
+	if te, ok := declTypeExpr(it.decl); ok {
+		return []adl.TypeExpr{te}
+	}
+	tes := []adl.TypeExpr{}
+	for _, f := range it.decl.Fields() {
+		tes = append(tes, f.TypeExpr)
+	}
+	return tes
+}
+
+func (it item) annotations() adl.Annotations {
+	if it.field != nil {
+		return it.field.Annotations
+	}
+	return it.decl.Annotations
+}
+
+type and struct{ l, r node }
+type or struct{ l, r node }
+type not struct{ n node }
+
+func (n and) eval(it item) bool { return n.l.eval(it) && n.r.eval(it) }
+func (n or) eval(it item) bool  { return n.l.eval(it) || n.r.eval(it) }
+func (n not) eval(it item) bool { return !n.n.eval(it) }
+
+// glob predicates, what selects the name matched
+type glob struct {
+	what, pattern string
+}
+
+func (g glob) eval(it item) bool {
+	var s string
+	switch g.what {
+	case "kind":
+		s = it.decl.Type.Kind()
+	case "name":
+		s = it.decl.Name
+		if it.field != nil {
+			s = it.field.Name
+		}
+	case "decl":
+		s = it.decl.Name
+	case "module":
+		s = it.module
+	}
+	ok, _ := path.Match(g.pattern, s)
+	return ok
+}
+
+type anno struct {
+	key string
+	// op is "", = or ~
+	op  string
+	val interface{}
+}
+
+func (a anno) eval(it item) bool {
+	for _, an := range it.annotations() {
+		sn := an.Key
+		if sn.ModuleName == "" {
+			sn.ModuleName = it.module
+		}
+		if !matchName(a.key, sn) {
+			continue
+		}
+		switch a.op {
+		case "":
+			return true
+		case "=":
+			if reflect.DeepEqual(normalize(an.Val), a.val) {
+				return true
+			}
+		case "~":
+			if s, ok := an.Val.(string); ok {
+				if m, _ := path.Match(a.val.(string), s); m {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// normalize converts values to the encoding/json generic types, so values built in code compare with parsed ones
+func normalize(v interface{}) interface{} {
+	by, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var n interface{}
+	if err := json.Unmarshal(by, &n); err != nil {
+		return v
+	}
+	return n
+}
+
+// matchName matches a scoped name by its name or its scoped name
+func matchName(pattern string, sn adl.ScopedName) bool {
+	if ok, _ := path.Match(pattern, sn.String()); ok {
+		return true
+	}
+	ok, _ := path.Match(pattern, sn.Name)
+	return ok && !strings.Contains(pattern, ".")
+}
+
+// typePattern is a pattern of a type expression
+type typePattern struct {
+	// name is _ for any type
+	name   string
+	params []typePattern
+}
+
+func (p typePattern) String() string {
+	if p.params == nil {
+		return p.name
+	}
+	ps := make([]string, len(p.params))
+	for i, pp := range p.params {
+		ps[i] = pp.String()
+	}
+	return p.name + "<" + strings.Join(ps, ",") + ">"
+}
+
+func (p typePattern) match(module string, te adl.TypeExpr) bool {
+	if p.name == "_" {
+		return true
+	}
+	switch {
+	case te.TypeRef.Reference != nil:
+		sn := *te.TypeRef.Reference
+		if sn.ModuleName == "" {
+			sn.ModuleName = module
+		}
+		if !matchName(p.name, sn) {
+			return false
+		}
+	default:
+		if ok, _ := path.Match(p.name, te.TypeRef.String()); !ok {
+			return false
+		}
+	}
+	if p.params == nil {
+		return true
+	}
+	if len(p.params) != len(te.Parameters) {
+		return false
+	}
+	for i, pp := range p.params {
+		if !pp.match(module, te.Parameters[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// contains is true when the type expression or one of its parameters matches
+func (p typePattern) contains(module string, te adl.TypeExpr) bool {
+	if p.match(module, te) {
+		return true
+	}
+	for _, pt := range te.Parameters {
+		if p.contains(module, pt) {
+			return true
+		}
+	}
+	return false
+}
+
+type typePred struct {
+	refs    bool
+	pattern typePattern
+}
+
+func (t typePred) eval(it item) bool {
+	tes := it.typeExprs()
+	if !t.refs && it.field == nil && it.decl.Type.Type == nil && it.decl.Type.Newtype == nil {
+		return false
+	}
+	for _, te := range tes {
+		if t.refs && t.pattern.contains(it.module, te) || !t.refs && t.pattern.match(it.module, te) {
+			return true
+		}
+	}
+	return false
+}
+
+// Parse parses a query
+func Parse(q string) (*Query, error) {
+	p := &parser{lex: &lexer{src: q}}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	query := &Query{}
+	if p.tok.kind == tWord && (p.tok.text == "decls" || p.tok.text == "fields") {
+		query.fields = p.tok.text == "fields"
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+	if p.tok.kind == tEOF {
+		return query, nil
+	}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tEOF {
+		return nil, p.errorf("unexpected '%s'", p.tok.text)
+	}
+	query.expr = expr
+	return query, nil
+}
@@ -0,0 +1,85 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+func TestRun(t *testing.T) {
+	allmod := adltest.Modules()
+	for _, tc := range []struct {
+		query string
+		want  []string
+	}{
+		{"kind:struct and @DbTable", []string{
+			"struct helix.protoapp.requests.Audit",
+			"struct helix.protoapp.requests.HelloReq",
+		}},
+		{"refs:common.Instant", []string{
+			"struct helix.protoapp.requests.Audit",
+			"type helix.protoapp.requests.CurrentTime common.http.Get<common.Instant>",
+			"struct helix.protoapp.requests.HelloReq",
+		}},
+		{"fields type:Nullable<_>", []string{
+			"field helix.protoapp.requests.Audit::previous Nullable<helix.protoapp.requests.Audit>",
+			"field helix.protoapp.requests.HelloReq::email Nullable<String>",
+			"field helix.protoapp.requests.LoginReq::otp Nullable<String>",
+		}},
+		{`@Path="/login"`, []string{
+			"type helix.protoapp.requests.Login common.http.Post<helix.protoapp.requests.LoginReq,helix.protoapp.requests.LoginResult>",
+		}},
+		{`module:helix.* @Path~"/debug/*"`, []string{
+			"type helix.protoapp.requests.CurrentTime common.http.Get<common.Instant>",
+			"newtype helix.protoapp.requests.DummyException common.http.Post<String,common.Unit>",
+		}},
+		{`@DbTable={"tableName":"audit_log","indexes":[["who"],["when","who"]],"uniquenessConstraints":[["who","when"]]}`, []string{
+			"struct helix.protoapp.requests.Audit",
+		}},
+		{"fields decl:HelloReq (@sys.annotations.SerializedName or name:e*)", []string{
+			"field helix.protoapp.requests.HelloReq::email Nullable<String>",
+			"field helix.protoapp.requests.HelloReq::tags Vector<String>",
+		}},
+		{"refs:Post<_,LoginResult> or type:StringMap<Literal>", []string{
+			"type helix.protoapp.requests.Login common.http.Post<helix.protoapp.requests.LoginReq,helix.protoapp.requests.LoginResult>",
+		}},
+		{"module:helix.* not kind:struct not kind:union not refs:common.http.*", []string{
+			"type helix.protoapp.requests.Literal StringMap<T>",
+			"type helix.protoapp.requests.MyConfigMap StringMap<helix.protoapp.requests.MyConfig>",
+			"type helix.protoapp.requests.StrLiteral helix.protoapp.requests.Literal<String>",
+		}},
+	} {
+		q, err := Parse(tc.query)
+		if err != nil {
+			t.Errorf("%s: %v", tc.query, err)
+			continue
+		}
+		got := []string{}
+		for _, r := range q.Run(allmod) {
+			got = append(got, r.String())
+		}
+		if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: expected\n%s\ngot\n%s", tc.query, strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		query, err string
+	}{
+		{"kind:record", "6: kind 'record' isn't struct, union, type or newtype"},
+		{"size:3", "1: unknown predicate 'size', expected kind, name, decl, module, type, refs or @annotation"},
+		{"(kind:struct", "13: expected ')'"},
+		{"type:Vector<", "13: expected a type pattern"},
+		{"@Path=/x", "7: bad json value '/x'"},
+		{"kind:struct or", "15: unexpected end of query"},
+		{`name:"abc`, "6: unterminated string"},
+	} {
+		_, err := Parse(tc.query)
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("%s: expected error %q got %v", tc.query, tc.err, err)
+		}
+	}
+}
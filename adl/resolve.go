@@ -0,0 +1,174 @@
+package adl
+
+import (
+	"sort"
+	"strings"
+)
+
+// Primitives are the builtin ADL types, these are never module qualified.
+var Primitives = []string{
+	"Void", "Bool",
+	"Int8", "Int16", "Int32", "Int64",
+	"Word8", "Word16", "Word32", "Word64",
+	"Float", "Double",
+	"String", "Bytes", "Json",
+	"Vector", "StringMap", "Nullable", "TypeToken",
+}
+
+// Well known annotation keys
+var (
+	DocAnno            = ScopedName{ModuleName: "sys.annotations", Name: "Doc"}
+	SerializedNameAnno = ScopedName{ModuleName: "sys.annotations", Name: "SerializedName"}
+)
+
+func IsPrimitive(name string) bool {
+	for _, p := range Primitives {
+		if p == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (sn ScopedName) String() string {
+	if sn.ModuleName == "" {
+		return sn.Name
+	}
+	return sn.ModuleName + "." + sn.Name
+}
+
+func (tr TypeRef) String() string {
+	switch {
+	case tr.Primitive != nil:
+		return *tr.Primitive
+	case tr.TypeParam != nil:
+		return *tr.TypeParam
+	case tr.Reference != nil:
+		return tr.Reference.String()
+	}
+	return "<nil>"
+}
+
+func (te TypeExpr) String() string {
+	if len(te.Parameters) == 0 {
+		return te.TypeRef.String()
+	}
+	ps := make([]string, len(te.Parameters))
+	for i, p := range te.Parameters {
+		ps[i] = p.String()
+	}
+	return te.TypeRef.String() + "<" + strings.Join(ps, ",") + ">"
+}
+
+// Kind is one of struct, union, type or newtype
+func (dt DeclType) Kind() string {
+	switch {
+	case dt.Struct != nil:
+		return "struct"
+	case dt.Union != nil:
+		return "union"
+	case dt.Type != nil:
+		return "type"
+	case dt.Newtype != nil:
+		return "newtype"
+	}
+	return ""
+}
+
+func (d Decl) TypeParams() []string {
+	switch {
+	case d.Type.Struct != nil:
+		return d.Type.Struct.TypeParams
+	case d.Type.Union != nil:
+		return d.Type.Union.TypeParams
+	case d.Type.Type != nil:
+		return d.Type.Type.TypeParams
+	case d.Type.Newtype != nil:
+		return d.Type.Newtype.TypeParams
+	}
+	return nil
+}
+
+// Fields of a struct or union, nil for type and newtype
+func (d Decl) Fields() []Field {
+	switch {
+	case d.Type.Struct != nil:
+		return d.Type.Struct.Field
+	case d.Type.Union != nil:
+		return d.Type.Union.Field
+	}
+	return nil
+}
+
+// Find the value of the first annotation with the key
+func (ans Annotations) Find(key ScopedName) (interface{}, bool) {
+	for _, an := range ans {
+		if an.Key == key {
+			return an.Val, true
+		}
+	}
+	return nil, false
+}
+
+// Doc returns the doc comment annotation, "" if there is none
+func (ans Annotations) Doc() string {
+	if v, ok := ans.Find(DocAnno); ok {
+		if s, ok := v.(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// Just decodes an adlc Maybe, ie "nothing" or {"just": val}.
+// Values not in this form are returned as is.
+func Just(m interface{}) (interface{}, bool) {
+	switch m := m.(type) {
+	case nil:
+		return nil, false
+	case string:
+		if m == "nothing" {
+			return nil, false
+		}
+	case map[string]interface{}:
+		if v, ex := m["just"]; ex && len(m) == 1 {
+			return v, true
+		}
+	}
+	return m, true
+}
+
+// DeclNames returns the names of the module's decls in sorted order
+func (m Module) DeclNames() []string {
+	names := make([]string, 0, len(m.Decls))
+	for n := range m.Decls {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ModuleNames returns the names of all modules in sorted order
+func ModuleNames(allmod map[string]Module) []string {
+	names := make([]string, 0, len(allmod))
+	for n := range allmod {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup the decl a reference refers to.
+// An empty module name is resolved relative to the module 'from'.
+// The returned scoped name is fully qualified.
+func Lookup(allmod map[string]Module, from string, sn ScopedName) (Decl, ScopedName, bool) {
+	if sn.ModuleName == "" {
+		sn.ModuleName = from
+	}
+	mod, ex := allmod[sn.ModuleName]
+	if !ex {
+		return Decl{}, sn, false
+	}
+	decl, ex := mod.Decls[sn.Name]
+	return decl, sn, ex
+}
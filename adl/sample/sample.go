@@ -0,0 +1,452 @@
+// Package sample generates random values of resolved ADL types, in ADL's JSON serialization.
+//
+// Numbers are within the bounds of their primitive, Int64 and Word64 within the integers a JSON number holds
+// exactly, and are often their bounds or zero. Structs are objects of their serialized field names, a field with a
+// default is left out, set to its default or random. Unions are objects of one branch, Void branches their name.
+// Strings, Bytes, Vectors and StringMaps are at most MaxLen long, Nullables are null half the time.
+// After MaxDepth nested decls values are kept as small as the types allow, recursive types end there.
+package sample
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen"
+)
+
+type Config struct {
+	// MaxLen bounds the length of Strings, Bytes, Vectors and StringMaps, 5 when 0
+	MaxLen int
+	// MaxDepth is the number of nested decls after which values are as small as they can be, 4 when 0
+	MaxDepth int
+}
+
+// Generator generates values of a type, it is a testing/quick Generator of them
+type Generator struct {
+	allmod map[string]adl.Module
+	te     adl.TypeExpr
+	cfg    Config
+	// decls are the instances of decls the type uses, by their type expression
+	decls map[string]decl
+	// depths are the least number of nested decls in a value of each of the decls
+	depths map[string]int
+}
+
+// infinite is the depth of types without finite values
+const infinite = math.MaxInt32
+
+// New checks that the type resolves and has finite values, its references are fully qualified
+func New(allmod map[string]adl.Module, te adl.TypeExpr, cfg Config) (*Generator, error) {
+	if cfg.MaxLen == 0 {
+		cfg.MaxLen = 5
+	}
+	if cfg.MaxDepth == 0 {
+		cfg.MaxDepth = 4
+	}
+	g := &Generator{allmod: allmod, te: te, cfg: cfg, decls: map[string]decl{}, depths: map[string]int{}}
+	if err := g.check(te); err != nil {
+		return nil, err
+	}
+	g.measure()
+	if g.depth(te) == infinite {
+		return nil, fmt.Errorf("%s has no finite value", te)
+	}
+	return g, nil
+}
+
+// Value is a random value
+func (g *Generator) Value(r *rand.Rand) interface{} {
+	return g.value(r, g.te, 0, g.cfg.MaxLen)
+}
+
+// Generate is a random value, size bounds lengths when it is less than MaxLen
+func (g *Generator) Generate(r *rand.Rand, size int) reflect.Value {
+	maxLen := g.cfg.MaxLen
+	if size < maxLen {
+		maxLen = size
+	}
+	v := g.value(r, g.te, 0, maxLen)
+	return reflect.ValueOf(&v).Elem()
+}
+
+// Values sets the args to random values, it is a testing/quick Config.Values for functions of interface{} args
+func (g *Generator) Values(args []reflect.Value, r *rand.Rand) {
+	for i := range args {
+		args[i] = g.Generate(r, g.cfg.MaxLen)
+	}
+}
+
+// decl is a referenced decl with the type args substituted in its fields or type expression
+type decl struct {
+	adl.Decl
+	name   adl.ScopedName
+	fields []adl.Field
+	te     adl.TypeExpr
+}
+
+func (g *Generator) lookup(te adl.TypeExpr) (decl, error) {
+	sn := *te.TypeRef.Reference
+	d, sn, ex := adl.Lookup(g.allmod, "", sn)
+	if !ex {
+		return decl{}, fmt.Errorf("unknown type %s", sn)
+	}
+	params := d.TypeParams()
+	if len(params) != len(te.Parameters) {
+		return decl{}, fmt.Errorf("%s takes %d type params, not %d", sn, len(params), len(te.Parameters))
+	}
+	subst := func(pte adl.TypeExpr) adl.TypeExpr {
+		return gen.Subst(gen.Qualify(pte, sn.ModuleName), params, te.Parameters)
+	}
+	res := decl{Decl: d, name: sn}
+	for _, f := range d.Fields() {
+		f.TypeExpr = subst(f.TypeExpr)
+		res.fields = append(res.fields, f)
+	}
+	switch {
+	case d.Type.Type != nil:
+		res.te = subst(d.Type.Type.TypeExpr)
+	case d.Type.Newtype != nil:
+		res.te = subst(d.Type.Newtype.TypeExpr)
+	}
+	return res, nil
+}
+
+var primParams = map[string]int{"Vector": 1, "StringMap": 1, "Nullable": 1}
+
+// check the type expression resolves, recording the instances of decls it uses
+func (g *Generator) check(te adl.TypeExpr) error {
+	switch {
+	case te.TypeRef.TypeParam != nil:
+		return fmt.Errorf("unbound type param %s", *te.TypeRef.TypeParam)
+	case te.TypeRef.Primitive != nil:
+		p := *te.TypeRef.Primitive
+		if !adl.IsPrimitive(p) {
+			return fmt.Errorf("unknown primitive %s", p)
+		}
+		if primParams[p] != len(te.Parameters) {
+			return fmt.Errorf("%s takes %d type params, not %d", p, primParams[p], len(te.Parameters))
+		}
+		for _, pt := range te.Parameters {
+			if err := g.check(pt); err != nil {
+				return err
+			}
+		}
+		return nil
+	case te.TypeRef.Reference == nil:
+		return fmt.Errorf("empty type expression")
+	}
+	key := te.String()
+	if _, ex := g.decls[key]; ex {
+		return nil
+	}
+	if len(g.decls) > 1000 {
+		return fmt.Errorf("%s expands to too many types", te)
+	}
+	d, err := g.lookup(te)
+	if err != nil {
+		return err
+	}
+	g.decls[key] = d
+	for _, f := range d.fields {
+		if err := g.check(f.TypeExpr); err != nil {
+			return fmt.Errorf("%s::%s: %v", d.name, f.Name, err)
+		}
+	}
+	if d.Type.Type != nil || d.Type.Newtype != nil {
+		if err := g.check(d.te); err != nil {
+			return fmt.Errorf("%s: %v", d.name, err)
+		}
+	}
+	return nil
+}
+
+// measure the depths of the decls, starting from infinite and lowering them until none change
+func (g *Generator) measure() {
+	for key := range g.decls {
+		g.depths[key] = infinite
+	}
+	for changed := true; changed; {
+		changed = false
+		for key, d := range g.decls {
+			depth := 0
+			switch {
+			case d.Type.Struct != nil:
+				for _, f := range d.fields {
+					if _, def := adl.Just(f.Default); !def && g.depth(f.TypeExpr) > depth {
+						depth = g.depth(f.TypeExpr)
+					}
+				}
+			case d.Type.Union != nil:
+				depth = infinite
+				for _, f := range d.fields {
+					if g.depth(f.TypeExpr) < depth {
+						depth = g.depth(f.TypeExpr)
+					}
+				}
+			default:
+				depth = g.depth(d.te)
+			}
+			if depth != infinite {
+				depth++
+			}
+			if depth < g.depths[key] {
+				g.depths[key] = depth
+				changed = true
+			}
+		}
+	}
+}
+
+// depth is the least number of nested decls in a value of the type, infinite when it has no finite value
+func (g *Generator) depth(te adl.TypeExpr) int {
+	if te.TypeRef.Reference != nil {
+		return g.depths[te.String()]
+	}
+	return 0
+}
+
+func (g *Generator) value(r *rand.Rand, te adl.TypeExpr, depth, maxLen int) interface{} {
+	small := depth > g.cfg.MaxDepth
+	if te.TypeRef.Primitive != nil {
+		return g.primitive(r, *te.TypeRef.Primitive, te.Parameters, depth, maxLen)
+	}
+	d := g.decls[te.String()]
+	switch {
+	case d.Type.Struct != nil:
+		obj := map[string]interface{}{}
+		for _, f := range d.fields {
+			if def, ok := adl.Just(f.Default); ok {
+				n := r.Intn(3)
+				if g.depth(f.TypeExpr) == infinite {
+					n = r.Intn(2)
+				}
+				if small || n == 0 {
+					continue
+				}
+				if n == 1 {
+					obj[f.SerializedName] = def
+					continue
+				}
+			}
+			obj[f.SerializedName] = g.value(r, f.TypeExpr, depth+1, maxLen)
+		}
+		return obj
+	case d.Type.Union != nil:
+		// past MaxDepth only the shallowest branches are taken
+		least := infinite - 1
+		if small {
+			least = g.depths[te.String()] - 1
+		}
+		branches := []adl.Field{}
+		for _, f := range d.fields {
+			if g.depth(f.TypeExpr) <= least {
+				branches = append(branches, f)
+			}
+		}
+		f := branches[r.Intn(len(branches))]
+		if f.TypeExpr.TypeRef.Primitive != nil && *f.TypeExpr.TypeRef.Primitive == "Void" {
+			return f.SerializedName
+		}
+		return map[string]interface{}{f.SerializedName: g.value(r, f.TypeExpr, depth+1, maxLen)}
+	}
+	return g.value(r, d.te, depth+1, maxLen)
+}
+
+// bounds of the integer primitives, 64 bit integers are within the range a float64 holds exactly
+var bounds = map[string][2]int64{
+	"Int8":   {math.MinInt8, math.MaxInt8},
+	"Int16":  {math.MinInt16, math.MaxInt16},
+	"Int32":  {math.MinInt32, math.MaxInt32},
+	"Int64":  {-(1<<53 - 1), 1<<53 - 1},
+	"Word8":  {0, math.MaxUint8},
+	"Word16": {0, math.MaxUint16},
+	"Word32": {0, math.MaxUint32},
+	"Word64": {0, 1<<53 - 1},
+}
+
+const alphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-.'\"\\/éß日本☃😀"
+
+func (g *Generator) primitive(r *rand.Rand, p string, params []adl.TypeExpr, depth, maxLen int) interface{} {
+	small := depth > g.cfg.MaxDepth
+	length := func() int {
+		if small {
+			return 0
+		}
+		return r.Intn(maxLen + 1)
+	}
+	if b, ok := bounds[p]; ok {
+		switch r.Intn(8) {
+		case 0:
+			return b[0]
+		case 1:
+			return b[1]
+		case 2:
+			if b[0] <= 0 {
+				return int64(0)
+			}
+		}
+		return b[0] + r.Int63n(b[1]-b[0]+1)
+	}
+	switch p {
+	case "Void":
+		return nil
+	case "Bool":
+		return r.Intn(2) == 1
+	case "Float", "Double":
+		f := 0.0
+		if r.Intn(8) != 0 {
+			f = r.NormFloat64() * math.Pow(10, float64(r.Intn(7)))
+		}
+		if p == "Float" {
+			return float64(float32(f))
+		}
+		return f
+	case "String":
+		rs := []rune(alphabet)
+		var buf strings.Builder
+		for i, n := 0, length(); i < n; i++ {
+			buf.WriteRune(rs[r.Intn(len(rs))])
+		}
+		return buf.String()
+	case "Bytes":
+		by := make([]byte, length())
+		r.Read(by)
+		return base64.StdEncoding.EncodeToString(by)
+	case "Json":
+		return g.json(r, depth, maxLen)
+	case "Vector":
+		arr := []interface{}{}
+		for i, n := 0, length(); i < n; i++ {
+			arr = append(arr, g.value(r, params[0], depth, maxLen))
+		}
+		return arr
+	case "StringMap":
+		obj := map[string]interface{}{}
+		for i, n := 0, length(); i < n; i++ {
+			obj[g.primitive(r, "String", nil, depth, maxLen).(string)] = g.value(r, params[0], depth, maxLen)
+		}
+		return obj
+	case "Nullable":
+		if small || r.Intn(2) == 0 {
+			return nil
+		}
+		return g.value(r, params[0], depth, maxLen)
+	}
+	return nil
+}
+
+// json is any json value, nesting to MaxDepth
+func (g *Generator) json(r *rand.Rand, depth, maxLen int) interface{} {
+	n := 6
+	if depth > g.cfg.MaxDepth {
+		n = 4
+	}
+	switch r.Intn(n) {
+	case 0:
+		return nil
+	case 1:
+		return r.Intn(2) == 1
+	case 2:
+		return g.primitive(r, "Double", nil, depth, maxLen)
+	case 3:
+		return g.primitive(r, "String", nil, depth, maxLen)
+	case 4:
+		arr := []interface{}{}
+		for i, n := 0, r.Intn(maxLen+1); i < n; i++ {
+			arr = append(arr, g.json(r, depth+1, maxLen))
+		}
+		return arr
+	}
+	obj := map[string]interface{}{}
+	for i, n := 0, r.Intn(maxLen+1); i < n; i++ {
+		obj[g.primitive(r, "String", nil, depth, maxLen).(string)] = g.json(r, depth+1, maxLen)
+	}
+	return obj
+}
+
+// ParseType parses a type expression, ie Vector<common.Instant>. Names are primitives, scoped names or decl names
+// declared by one module of allmod.
+func ParseType(allmod map[string]adl.Module, s string) (adl.TypeExpr, error) {
+	toks := []string{}
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		tok := ""
+		for _, c := range f {
+			if c == '<' || c == '>' || c == ',' {
+				if tok != "" {
+					toks = append(toks, tok)
+				}
+				toks = append(toks, string(c))
+				tok = ""
+				continue
+			}
+			tok += string(c)
+		}
+		if tok != "" {
+			toks = append(toks, tok)
+		}
+	}
+	te, rest, err := parseType(allmod, toks)
+	if err != nil {
+		return te, err
+	}
+	if len(rest) != 0 {
+		return te, fmt.Errorf("unexpected '%s' in %s", rest[0], s)
+	}
+	return te, nil
+}
+
+func parseType(allmod map[string]adl.Module, toks []string) (adl.TypeExpr, []string, error) {
+	te := adl.TypeExpr{Parameters: []adl.TypeExpr{}}
+	if len(toks) == 0 || strings.ContainsAny(toks[0], "<>,") {
+		return te, nil, fmt.Errorf("expected a type name")
+	}
+	name := toks[0]
+	toks = toks[1:]
+	switch {
+	case adl.IsPrimitive(name):
+		te.TypeRef.Primitive = &name
+	case strings.Contains(name, "."):
+		i := strings.LastIndex(name, ".")
+		te.TypeRef.Reference = &adl.ScopedName{ModuleName: name[:i], Name: name[i+1:]}
+	default:
+		found := []adl.ScopedName{}
+		for _, mn := range adl.ModuleNames(allmod) {
+			if _, ex := allmod[mn].Decls[name]; ex {
+				found = append(found, adl.ScopedName{ModuleName: mn, Name: name})
+			}
+		}
+		switch len(found) {
+		case 0:
+			return te, nil, fmt.Errorf("unknown type %s", name)
+		case 1:
+			te.TypeRef.Reference = &found[0]
+		default:
+			return te, nil, fmt.Errorf("'%s' is ambiguous, it is declared by %v", name, found)
+		}
+	}
+	if len(toks) == 0 || toks[0] != "<" {
+		return te, toks, nil
+	}
+	for {
+		pte, rest, err := parseType(allmod, toks[1:])
+		if err != nil {
+			return te, nil, err
+		}
+		te.Parameters = append(te.Parameters, pte)
+		toks = rest
+		if len(toks) == 0 {
+			return te, nil, fmt.Errorf("expected '>'")
+		}
+		if toks[0] == ">" {
+			return te, toks[1:], nil
+		}
+		if toks[0] != "," {
+			return te, nil, fmt.Errorf("expected ',' or '>' not '%s'", toks[0])
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package sample
+
+import (
+	"encoding/json"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+const testAdl = `module test {
+struct Tree {
+  Int8 value;
+  Vector<Tree> children;
+};
+union List {
+  Void nil;
+  Cons cons;
+};
+struct Cons {
+  Word16 head;
+  List tail;
+};
+struct Inf {
+  Inf inf;
+};
+struct Defaulted {
+  Inf inf = { "inf" : null };
+};
+};`
+
+func modules(t *testing.T) map[string]adl.Module {
+	allmod := adltest.Modules()
+	mod, err := adlbuild.Parse(testAdl, allmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allmod[mod.Name] = mod
+	return allmod
+}
+
+func generator(t *testing.T, allmod map[string]adl.Module, typ string, cfg Config) *Generator {
+	te, err := ParseType(allmod, typ)
+	if err != nil {
+		t.Fatalf("%s: %v", typ, err)
+	}
+	g, err := New(allmod, te, cfg)
+	if err != nil {
+		t.Fatalf("%s: %v", typ, err)
+	}
+	return g
+}
+
+func TestSeed(t *testing.T) {
+	g := generator(t, modules(t), "HelloReq", Config{})
+	sample := func(seed int64) string {
+		r := rand.New(rand.NewSource(seed))
+		by, _ := json.Marshal([]interface{}{g.Value(r), g.Value(r), g.Value(r)})
+		return string(by)
+	}
+	if a, b := sample(1), sample(1); a != b {
+		t.Errorf("same seed, different values\n%s\n%s", a, b)
+	}
+	if a, b := sample(1), sample(2); a == b {
+		t.Errorf("different seeds, same values %s", a)
+	}
+}
+
+func TestValues(t *testing.T) {
+	allmod := modules(t)
+	r := rand.New(rand.NewSource(7))
+	hello := generator(t, allmod, "helix.protoapp.requests.HelloReq", Config{MaxLen: 3})
+	result := generator(t, allmod, "LoginResult", Config{})
+	cons := generator(t, allmod, "Vector<test.Cons>", Config{MaxDepth: 3})
+	seen := map[string]bool{}
+	for i := 0; i < 200; i++ {
+		v := hello.Value(r).(map[string]interface{})
+		for _, f := range []string{"name", "username", "createdAt", "config"} {
+			if _, ex := v[f]; !ex {
+				t.Fatalf("HelloReq without %s %v", f, v)
+			}
+		}
+		if _, ex := v["tags"]; ex {
+			t.Fatalf("HelloReq with tags, not its serialized name labels %v", v)
+		}
+		if s := v["name"].(string); len([]rune(s)) > 3 {
+			t.Errorf("name longer than MaxLen %q", s)
+		}
+		if n := v["createdAt"].(int64); n > 1<<53 || n < -(1<<53) {
+			t.Errorf("createdAt %d not a json number", n)
+		}
+		if c := v["config"].(map[string]interface{}); len(c) != 1 || (c["a"] == nil && c["b"] == nil) {
+			t.Errorf("config isn't a MyConfig branch %v", c)
+		}
+		switch lr := result.Value(r).(type) {
+		case string:
+			if lr != "invalidCredentials" {
+				t.Errorf("unexpected void branch %s", lr)
+			}
+			seen[lr] = true
+		case map[string]interface{}:
+			for k := range lr {
+				seen[k] = true
+			}
+		}
+		for _, c := range cons.Value(r).([]interface{}) {
+			head := c.(map[string]interface{})["head"].(int64)
+			if head < 0 || head > 65535 {
+				t.Errorf("Word16 out of bounds %d", head)
+			}
+			// the list ends after MaxDepth nested decls
+			depth := 0
+			for l := c.(map[string]interface{})["tail"]; l != "nil"; l = l.(map[string]interface{})["cons"].(map[string]interface{})["tail"] {
+				depth++
+			}
+			if depth > 3 {
+				t.Errorf("list of %d conses past MaxDepth", depth)
+			}
+		}
+	}
+	if len(seen) != 3 {
+		t.Errorf("not all LoginResult branches generated %v", seen)
+	}
+}
+
+func TestRecursive(t *testing.T) {
+	allmod := modules(t)
+	for _, typ := range []string{"Tree", "Defaulted", "Nullable<Inf>", "Audit"} {
+		generator(t, allmod, typ, Config{})
+	}
+	for typ, want := range map[string]string{
+		"Inf":                   "test.Inf has no finite value",
+		"StringMap<Inf>":        "",
+		"Vector<Vector<Inf>>":   "",
+		"Pair<Inf,String>":      "sys.types.Pair<test.Inf,String> has no finite value",
+		"Nope":                  "unknown type Nope",
+		"Vector<Int32":          "expected '>'",
+		"Vector<Int32,Int32>":   "Vector takes 1 type params, not 2",
+		"sys.types.Pair<Int32>": "sys.types.Pair takes 2 type params, not 1",
+		"Literal<Bool> String":  "unexpected 'String' in Literal<Bool> String",
+	} {
+		te, err := ParseType(allmod, typ)
+		if err == nil {
+			_, err = New(allmod, te, Config{})
+		}
+		got := ""
+		if err != nil {
+			got = err.Error()
+		}
+		if got != want {
+			t.Errorf("%s: got %q want %q", typ, got, want)
+		}
+	}
+}
+
+func TestQuick(t *testing.T) {
+	g := generator(t, modules(t), "Audit", Config{})
+	n := 0
+	f := func(v interface{}) bool {
+		n++
+		_, err := json.Marshal(v)
+		return err == nil && reflect.TypeOf(v).Kind() == reflect.Map
+	}
+	if err := quick.Check(f, &quick.Config{MaxCount: 50, Values: g.Values, Rand: rand.New(rand.NewSource(1))}); err != nil {
+		t.Error(err)
+	}
+	if n != 50 {
+		t.Errorf("checked %d values", n)
+	}
+}
@@ -0,0 +1,100 @@
+package usage
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlfmt"
+	"github.com/wxio/tron-go/adl/cst"
+)
+
+// OrganizeImports rewrites the imports of src, removing unused and repeated imports and sorting them.
+// With narrow, used .* imports are replaced by imports of the decls used.
+// Comments before an import and at the end of its line move with it, source that adlfmt leaves as is stays
+// formatted. Source with syntax errors isn't changed, the errors are returned.
+func OrganizeImports(src string, allmod map[string]adl.Module, narrow bool) (string, error) {
+	// the imports of source with syntax errors aren't what they seem
+	if _, _, _, _, errs := adl.BuildAdlAST(src); errs.Error() != nil {
+		return "", errs.Error()
+	}
+	u, err := Analyze(src, allmod)
+	if err != nil {
+		return "", err
+	}
+	if len(u.Imports) == 0 {
+		return src, nil
+	}
+	type line struct {
+		text     string
+		comments []string
+		trailing string
+	}
+	lines := []*line{}
+	byText := map[string]*line{}
+	add := func(text string, comments []string, trailing string) {
+		if l, ex := byText[text]; ex {
+			l.comments = append(l.comments, comments...)
+			if l.trailing == "" {
+				l.trailing = trailing
+			}
+			return
+		}
+		l := &line{text: text, comments: comments, trailing: trailing}
+		byText[text] = l
+		lines = append(lines, l)
+	}
+	for i, imp := range u.Imports {
+		ts := imp.Node.Tokens()
+		comments := []string{}
+		// the comments before the first import stay where they are
+		if i != 0 {
+			comments = cst.Comments(ts[0].Leading)
+		}
+		trailing := strings.Join(cst.Comments(ts[len(ts)-1].Trailing), " ")
+		switch {
+		case imp.Known && len(imp.Uses) == 0:
+		case narrow && imp.Wildcard() && imp.Known:
+			for j, sn := range imp.Uses {
+				if j != 0 {
+					comments, trailing = nil, ""
+				}
+				add(sn.String(), comments, trailing)
+			}
+		default:
+			add(imp.Text, comments, trailing)
+		}
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].text < lines[j].text })
+	var buf strings.Builder
+	for _, l := range lines {
+		for _, c := range l.comments {
+			buf.WriteString(c + "\n")
+		}
+		buf.WriteString("import " + l.text + ";")
+		if l.trailing != "" {
+			buf.WriteString(" " + l.trailing)
+		}
+		buf.WriteString("\n")
+	}
+	// replace from the first import keyword to the end of the line of the last import
+	first := u.Imports[0].Node.Tokens()[0]
+	lts := u.Imports[len(u.Imports)-1].Node.Tokens()
+	last := lts[len(lts)-1]
+	end := last.Offset + len([]rune(last.Text))
+	for _, tv := range last.Trailing {
+		end += len([]rune(tv.Text))
+	}
+	rs := []rune(src)
+	out := string(rs[:first.Offset]) + buf.String() + string(rs[end:])
+	if len(lines) == 0 {
+		// drop the blank line left between the module's opening and its decls
+		out = string(rs[:first.Offset]) + strings.TrimLeft(string(rs[end:]), "\r\n")
+	}
+	if formatted, err := adlfmt.Format(src); err == nil && formatted == src {
+		if out2, err := adlfmt.Format(out); err == nil {
+			out = out2
+		}
+	}
+	return out, nil
+}
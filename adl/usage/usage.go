@@ -0,0 +1,323 @@
+// Package usage tracks what a module's decls, imports and annotations are used by.
+//
+// References from type expressions and annotation keys are traced back to the imports that bring them in and to
+// the decls they name. A decl is public when it is used by another module, or by no decl at all, as it is then
+// there for the generated code. The other decls are private, helpers of the module, and they are unreachable when
+// no public decl or module annotation leads to them, ie two helpers only using each other.
+package usage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/cst"
+)
+
+// Import is an import statement of the source
+type Import struct {
+	// Text is the imported name as written, ie common.* or common.db.DbTable
+	Text   string
+	Module string
+	// Name is empty for .* imports
+	Name string
+	// Line and Column are zero based
+	Line, Column int
+	// Node is the import statement
+	Node *cst.Node
+	// Uses are the decls the module uses through the import, in name order
+	Uses []adl.ScopedName
+	// Known is false for a .* import of a module that isn't loaded, its uses can't be told
+	Known bool
+}
+
+// Wildcard is true for .* imports
+func (imp Import) Wildcard() bool {
+	return imp.Name == ""
+}
+
+// Usage of a module
+type Usage struct {
+	File   *cst.File
+	Module adl.Module
+	// AllMod are the modules, Module included
+	AllMod map[string]adl.Module
+	// Imports are the imports of the source, in source order
+	Imports []Import
+	// Refs are the decls the module refers to in type expressions and annotation keys
+	Refs map[adl.ScopedName]bool
+	// Users are the decls of AllMod using each decl, a decl using itself isn't counted and
+	// a module annotation is used by its module, as a decl with an empty name
+	Users map[adl.ScopedName][]adl.ScopedName
+}
+
+// Analyze the module of src.
+// allmod holds the modules it imports, resolved by adlc or adlbuild.Parse, and may hold the module itself, which is
+// otherwise read from src.
+func Analyze(src string, allmod map[string]adl.Module) (*Usage, error) {
+	f := cst.Parse(src)
+	ms := ModuleStatement(f)
+	if ms == nil || len(leaves(ms)) < 2 {
+		return nil, fmt.Errorf("no module")
+	}
+	name := ""
+	for _, t := range leaves(ms)[1:] {
+		if t.Text == "{" {
+			break
+		}
+		name += t.Text
+	}
+	mod, ex := allmod[name]
+	if !ex {
+		var err error
+		if mod, err = adlbuild.Parse(src, allmod); err != nil {
+			return nil, err
+		}
+		all := map[string]adl.Module{name: mod}
+		for k, v := range allmod {
+			all[k] = v
+		}
+		allmod = all
+	}
+	u := &Usage{
+		File:   f,
+		Module: mod,
+		AllMod: allmod,
+		Refs:   Refs(mod),
+		Users:  Users(allmod),
+	}
+	for _, n := range ms.Children {
+		if n.Rule != "ImportModuleName" && n.Rule != "ImportScopedName" {
+			continue
+		}
+		// import a . b . * ;
+		ts := leaves(n)
+		end := len(ts)
+		if end != 0 && ts[end-1].Text == ";" {
+			end--
+		}
+		if end < 4 {
+			// error recovery leaves partial imports
+			continue
+		}
+		imp := Import{
+			Text:   text(ts[1:end]),
+			Module: text(ts[1 : end-2]),
+			Line:   ts[1].Line - 1,
+			Column: ts[1].Column,
+			Node:   n,
+			Uses:   []adl.ScopedName{},
+			Known:  true,
+		}
+		if n.Rule == "ImportScopedName" {
+			imp.Name = ts[end-1].Text
+		} else {
+			_, imp.Known = allmod[imp.Module]
+		}
+		for sn := range u.Refs {
+			if sn.ModuleName == imp.Module && (imp.Name == "" || imp.Name == sn.Name) {
+				imp.Uses = append(imp.Uses, sn)
+			}
+		}
+		sort.Slice(imp.Uses, func(i, j int) bool { return imp.Uses[i].Name < imp.Uses[j].Name })
+		u.Imports = append(u.Imports, imp)
+	}
+	return u, nil
+}
+
+// ModuleStatement is the module of the file, nil when there is none
+func ModuleStatement(f *cst.File) *cst.Node {
+	for _, c := range f.Root.Children {
+		if c.Rule == "ModuleStatement" {
+			return c
+		}
+	}
+	return nil
+}
+
+// leaves are the tokens of the node's direct leaves
+func leaves(n *cst.Node) []*cst.Token {
+	ts := []*cst.Token{}
+	for _, c := range n.Children {
+		if c.Token != nil {
+			ts = append(ts, c.Token)
+		}
+	}
+	return ts
+}
+
+func text(ts []*cst.Token) string {
+	s := ""
+	for _, t := range ts {
+		s += t.Text
+	}
+	return s
+}
+
+// UnusedImports are the imports nothing is used through, .* imports of modules that aren't loaded are kept
+func (u *Usage) UnusedImports() []Import {
+	imps := []Import{}
+	for _, imp := range u.Imports {
+		if imp.Known && len(imp.Uses) == 0 {
+			imps = append(imps, imp)
+		}
+	}
+	return imps
+}
+
+// Narrowable are the .* imports that are used, they could import the decls used by name
+func (u *Usage) Narrowable() []Import {
+	imps := []Import{}
+	for _, imp := range u.Imports {
+		if imp.Wildcard() && len(imp.Uses) != 0 {
+			imps = append(imps, imp)
+		}
+	}
+	return imps
+}
+
+// Public is true for a decl of the module used by another module or by no decl
+func (u *Usage) Public(decl string) bool {
+	us := u.Users[adl.ScopedName{ModuleName: u.Module.Name, Name: decl}]
+	for _, user := range us {
+		if user.ModuleName != u.Module.Name {
+			return true
+		}
+	}
+	return len(us) == 0
+}
+
+// Unreachable are the private decls of the module that no public decl or module annotation leads to, in name order
+func (u *Usage) Unreachable() []string {
+	reached := map[string]bool{}
+	var reach func(name string)
+	reach = func(name string) {
+		if reached[name] {
+			return
+		}
+		reached[name] = true
+		for sn := range DeclRefs(u.Module, name) {
+			if sn.ModuleName == u.Module.Name {
+				reach(sn.Name)
+			}
+		}
+	}
+	for _, an := range u.Module.Annotations {
+		if an.Key.ModuleName == u.Module.Name {
+			reach(an.Key.Name)
+		}
+	}
+	for _, dn := range u.Module.DeclNames() {
+		if u.Public(dn) {
+			reach(dn)
+		}
+	}
+	names := []string{}
+	for _, dn := range u.Module.DeclNames() {
+		if !reached[dn] {
+			names = append(names, dn)
+		}
+	}
+	return names
+}
+
+// ForTypeExprs calls fn with each field's type and the type expression of each type and newtype, in decl order
+func ForTypeExprs(mod adl.Module, fn func(decl, field string, te adl.TypeExpr)) {
+	for _, dn := range mod.DeclNames() {
+		decl := mod.Decls[dn]
+		switch {
+		case decl.Type.Type != nil:
+			fn(dn, "", decl.Type.Type.TypeExpr)
+		case decl.Type.Newtype != nil:
+			fn(dn, "", decl.Type.Newtype.TypeExpr)
+		}
+		for _, f := range decl.Fields() {
+			fn(dn, f.Name, f.TypeExpr)
+		}
+	}
+}
+
+// TypeRefs calls fn with the references of the type expression, qualified by the module from when they aren't
+func TypeRefs(from string, te adl.TypeExpr, fn func(sn adl.ScopedName)) {
+	if sn := te.TypeRef.Reference; sn != nil {
+		if sn.ModuleName == "" {
+			fn(adl.ScopedName{ModuleName: from, Name: sn.Name})
+		} else {
+			fn(*sn)
+		}
+	}
+	for _, pt := range te.Parameters {
+		TypeRefs(from, pt, fn)
+	}
+}
+
+// DeclRefs are the decls the decl refers to in type expressions and annotation keys, itself included
+func DeclRefs(mod adl.Module, name string) map[adl.ScopedName]bool {
+	refs := map[adl.ScopedName]bool{}
+	add := func(sn adl.ScopedName) { refs[sn] = true }
+	decl, ex := mod.Decls[name]
+	if !ex {
+		return refs
+	}
+	annos(mod.Name, decl.Annotations, add)
+	switch {
+	case decl.Type.Type != nil:
+		TypeRefs(mod.Name, decl.Type.Type.TypeExpr, add)
+	case decl.Type.Newtype != nil:
+		TypeRefs(mod.Name, decl.Type.Newtype.TypeExpr, add)
+	}
+	for _, f := range decl.Fields() {
+		annos(mod.Name, f.Annotations, add)
+		TypeRefs(mod.Name, f.TypeExpr, add)
+	}
+	return refs
+}
+
+func annos(from string, ans adl.Annotations, fn func(sn adl.ScopedName)) {
+	for _, an := range ans {
+		if an.Key.ModuleName == "" {
+			fn(adl.ScopedName{ModuleName: from, Name: an.Key.Name})
+		} else {
+			fn(an.Key)
+		}
+	}
+}
+
+// Refs are the decls the module refers to in type expressions and annotation keys
+func Refs(mod adl.Module) map[adl.ScopedName]bool {
+	refs := map[adl.ScopedName]bool{}
+	annos(mod.Name, mod.Annotations, func(sn adl.ScopedName) { refs[sn] = true })
+	for dn := range mod.Decls {
+		for sn := range DeclRefs(mod, dn) {
+			refs[sn] = true
+		}
+	}
+	return refs
+}
+
+// Users are the decls using each decl, see Usage.Users
+func Users(allmod map[string]adl.Module) map[adl.ScopedName][]adl.ScopedName {
+	users := map[adl.ScopedName][]adl.ScopedName{}
+	for _, mn := range adl.ModuleNames(allmod) {
+		mod := allmod[mn]
+		annos(mn, mod.Annotations, func(sn adl.ScopedName) {
+			users[sn] = append(users[sn], adl.ScopedName{ModuleName: mn})
+		})
+		for _, dn := range mod.DeclNames() {
+			user := adl.ScopedName{ModuleName: mn, Name: dn}
+			refs := DeclRefs(mod, dn)
+			sns := []adl.ScopedName{}
+			for sn := range refs {
+				if sn != user {
+					sns = append(sns, sn)
+				}
+			}
+			sort.Slice(sns, func(i, j int) bool { return sns[i].String() < sns[j].String() })
+			for _, sn := range sns {
+				users[sn] = append(users[sn], user)
+			}
+		}
+	}
+	return users
+}
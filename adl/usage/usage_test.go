@@ -0,0 +1,133 @@
+package usage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/internal/adltest"
+)
+
+const demoAdl = `module demo {
+
+// common things
+import common.*;
+import common.db.DbTable; // the table annotation
+import sys.types.*;
+import zz.unknown.*;
+import common.http.Get;
+// again
+import common.*; // twice
+
+/// Public
+struct Order {
+  Instant at;
+  Helper h;
+};
+
+struct Helper {
+  LocalDate day;
+};
+
+struct Loop1 {
+  Nullable<Loop2> next;
+};
+
+struct Loop2 {
+  Loop1 prev;
+};
+};
+`
+
+func TestAnalyze(t *testing.T) {
+	u, err := Analyze(demoAdl, adltest.Modules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := func(imps []Import) string {
+		ss := []string{}
+		for _, imp := range imps {
+			ss = append(ss, fmt.Sprintf("%d:%d %s %v", imp.Line+1, imp.Column+1, imp.Text, imp.Uses))
+		}
+		return strings.Join(ss, ", ")
+	}
+	for _, tc := range []struct {
+		what, got, want string
+	}{
+		{"unused", texts(u.UnusedImports()), "5:8 common.db.DbTable [], 6:8 sys.types.* [], 8:8 common.http.Get []"},
+		{"narrowable", texts(u.Narrowable()), "4:8 common.* [common.Instant common.LocalDate], 10:8 common.* [common.Instant common.LocalDate]"},
+		{"unreachable", strings.Join(u.Unreachable(), ", "), "Loop1, Loop2"},
+		{"public", fmt.Sprint(u.Public("Order"), u.Public("Helper"), u.Public("Loop1")), "true false false"},
+		{"users", fmt.Sprint(u.Users[adl.ScopedName{ModuleName: "demo", Name: "Helper"}]), "[demo.Order]"},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s: expected %s got %s", tc.what, tc.want, tc.got)
+		}
+	}
+}
+
+func TestOrganizeImports(t *testing.T) {
+	decls := demoAdl[strings.Index(demoAdl, "/// Public"):]
+	for _, tc := range []struct {
+		narrow   bool
+		src, out string
+	}{
+		{false, demoAdl, `module demo {
+
+// common things
+// again
+import common.*; // twice
+import zz.unknown.*;
+
+` + decls},
+		{true, demoAdl, `module demo {
+
+// common things
+// again
+import common.Instant; // twice
+import common.LocalDate;
+import zz.unknown.*;
+
+` + decls},
+		{false, "module a {\n\nimport common.*;\n\nstruct A {\n  Int32 x;\n};\n};\n", "module a {\n\nstruct A {\n  Int32 x;\n};\n};\n"},
+	} {
+		out, err := OrganizeImports(tc.src, adltest.Modules(), tc.narrow)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != tc.out {
+			t.Errorf("narrow %v: expected\n%s\ngot\n%s", tc.narrow, tc.out, out)
+		}
+	}
+}
+
+func TestAnalyzePartial(t *testing.T) {
+	for _, src := range []string{"", "module", "module {"} {
+		if _, err := Analyze(src, adltest.Modules()); err == nil {
+			t.Errorf("%q: expected an error", src)
+		}
+	}
+}
+
+func TestIncompleteImport(t *testing.T) {
+	src := "module a {\n\nimport common.*\n\nstruct A {\n  Instant x;\n};\n};\n"
+	// the module is known from an earlier compile, as in the editor
+	allmod := adltest.Modules()
+	mod, err := adlbuild.Parse(strings.Replace(src, "*\n", "*;\n", 1), allmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allmod["a"] = mod
+	if out, err := OrganizeImports(src, allmod, false); err == nil {
+		t.Errorf("expected a syntax error, got\n%s", out)
+	}
+	u, err := Analyze(src, allmod)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Imports) != 1 || u.Imports[0].Text != "common.*" || u.Imports[0].Module != "common" {
+		t.Errorf("got imports %v", u.Imports)
+	}
+}
@@ -111,10 +111,10 @@ export function activate(ctx: ExtensionContext) {
 		// 	code2Protocol: (uri: Uri): string => (uri.scheme ? uri : uri.with({ scheme: 'file' })).toString(),
 		// 	protocol2Code: (uri: string) => Uri.parse(uri),
 		// },
-		// synchronize: {
-		// 	// Notify the server about file changes to '.clientrc files contained in the workspace
-		// 	fileEvents: workspace.createFileSystemWatcher('**/.clientrc')
-		// }
+		synchronize: {
+			// Notify the server about changes to ADL files it hasn't opened, DidChangeWatchedFiles re-indexes them for symbols, references and renames
+			fileEvents: workspace.createFileSystemWatcher('**/*.adl')
+		}
 	};
 
 	// Create the language client and start the client.
@@ -247,3 +247,18 @@ func (v *compileV) VisitModule(ctx adlwi.IModuleContext, delegate antlr.ParseTre
 	v.name = ctx.GetTok().(ctree.TreeNode).Val().(adl.Module).Name
 	return
 }
+
+// moduleName returns the name of the module in a cached file
+func (svr *server) moduleName(uri string) (string, error) {
+	text, err := svr.fileCache.get(uri)
+	if err != nil {
+		return "", err
+	}
+	tr, _, _, _, errs := adl.BuildAdlAST(text)
+	if errs.Error() != nil {
+		return "", errs.Error()
+	}
+	cv := &compileV{}
+	adl.VisitADLWi(tr, cv)
+	return cv.name, nil
+}
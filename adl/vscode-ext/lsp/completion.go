@@ -1,268 +1,88 @@
 package lsp
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 
-	"github.com/wxio/tron-go/internal/ctree"
-
-	"github.com/golangq/q"
-	antlr "github.com/wxio/goantlr"
-	"github.com/wxio/tron-go/adl"
-	"github.com/wxio/tron-go/internal/adlwi"
+	"github.com/wxio/tron-go/adl/nav"
 	"golang.org/x/tools/lsp/protocol"
 )
 
-func (svr *server) collect(ctx context.Context, req *protocol.CompletionParams) (*protocol.CompletionList, error) {
-	defer func() {
-		if r := recover(); r != nil {
-			q.Q(r)
-			qstack()
+// complete are the names, keywords and JSON values that can be written at the position, false inside tokens
+func (svr *server) complete(req *protocol.CompletionParams) ([]protocol.CompletionItem, bool) {
+	ix, ok := svr.index(req.TextDocument.URI)
+	if !ok {
+		return nil, false
+	}
+	cs, ok := ix.Complete(int(req.Position.Line), int(req.Position.Character))
+	if !ok {
+		return nil, false
+	}
+	items := []protocol.CompletionItem{}
+	for i, c := range cs {
+		item := protocol.CompletionItem{
+			Label:         c.Label,
+			Kind:          completionKind(c.Kind, c.Keyword),
+			Detail:        c.Detail,
+			Documentation: c.Doc,
+			// in the order of the completions, type params and local decls first
+			SortText: fmt.Sprintf("%04d", i),
+			TextEdit: &protocol.TextEdit{Range: lspRange(c.Range), NewText: c.Label},
 		}
-	}()
-	if req.Context.TriggerKind != protocol.Invoked {
-		return nil, nil
-	}
-	text, err := svr.fileCache.get(req.TextDocument.URI)
-	if err != nil {
-		q.Q(err)
-		return nil, nil
-	}
-	col := req.TextDocumentPositionParams.Position.Character
-	line := req.TextDocumentPositionParams.Position.Line
-	tr, atr, bl, ts, err1 := adl.BuildAdlAST(text)
-	_, _, _, _ = tr, atr, bl, ts
-	// adl.QTreeToken(ts, bl)
-	if err1.Error() != nil {
-		q.Q(err1)
-	}
-	if tr == nil {
-		q.Q("no tree created")
-		return nil, nil
-	}
-	q.Q(col)
-	q.Q(line + 1)
-	q.Q(req.TextDocumentPositionParams.Position)
-	ccl := &complCollList{
-		col:  int(col),
-		line: int(line) + 1,
-	}
-	pa, errs := adl.WalkADLWo(tr, ccl)
-	if errs.Error() != nil {
-		q.Q(errs.Error())
-	}
-	// antlr.ParseTreeWalkerDefault.Walk(ccl, atr)
-	debug := fmt.Sprintf("%d %v", ccl.size, ccl.treeNode)
-	q.Q(debug)
-
-	for _, x := range ccl.treeNode {
-		tn := pa.GetSymbolicNames()[x.GetTokenType()]
-		q.Q(fmt.Sprintf("%s %T", tn, x.Val()))
-	}
-	//
-	if len(ccl.treeNode) < 2 {
-		return nil, nil
-	}
-	tns := ccl.treeNode
-	// allmod, err := svr.adl2ast(ctx, text, svr.client_log)
-	// if err != nil {
-	// 	q.Q(err)
-	// 	return nil, nil
-	// }
-	if svr.allmod == nil {
-		q.Q("no adl cache")
-		return nil, nil
-	}
-	mod, ex := svr.allmod[tns[1].Val().(adl.Module).Name]
-	_ = ex // adl is valid
-	cl := &protocol.CompletionList{}
-	var curAnn *adl.ScopedName
-	var curAnnMod adl.Module
-	var curDecl *adl.Decl
-	var curDeclType *adl.DeclType
-	for i, tn := range tns[2:] {
-		switch tn.GetTokenType() {
-		case adlwi.AdlWiModuleAnno:
-			// TODO all wild card imports
-			for _, a := range mod.Imports {
-				if a.ScopedName != nil && a.ScopedName.Name == tn.Val() {
-					curAnn = a.ScopedName
-					break
-				}
-			}
-			if curAnn == nil {
-				q.Q("didn't find", tn.Val())
-				q.Q("in", mod.Annotations)
-				q.Q("of", tns[1].Val().(adl.Module).Name, mod)
-				return nil, nil
-			}
-			curAnnMod, ex = svr.allmod[curAnn.ModuleName]
-			_ = ex // adl is valid
-			x := curAnnMod.Decls[curAnn.Name]
-			curDecl = &x
-			curDeclType = &curDecl.Type
-		case adlwi.AdlWiJsonObj:
-			// curDeclType.
-		case adlwi.AdlWiJsonObjKey:
-		case adlwi.AdlWiJsonArray:
-		case adlwi.AdlWiJsonBool:
-		case adlwi.AdlWiJsonFloat:
-		case adlwi.AdlWiJsonInt:
-		case adlwi.AdlWiJsonNull:
-		case adlwi.AdlWiJsonStr:
-		default:
-			q.Q("Unhandled completion", i, tn)
-			svr.client_log(ctx, protocol.Warning, fmt.Sprintf("Unhandled completion %v", tn))
+		if c.Kind == nav.Decl && c.Insert != "" && svr.extConfig.ApplyPTComp {
+			// the struct's value is applied rather than offered, with its placeholders as written
+			svr.applyCompletion(req.TextDocument.URI, protocol.TextEdit{Range: lspRange(c.Range), NewText: c.Label})
+			continue
 		}
-	}
-	if curDeclType == nil {
-		q.Q("keh", tns[2:])
-		return nil, nil
-	}
-	q.Q(curDeclType)
-	ci := declTypeComplete(*curDeclType).Complete(curDecl, svr.allmod, req.TextDocumentPositionParams.Position, req.TextDocument.URI, svr)
-	if ci != nil {
-		cl.Items = append(cl.Items, ci...)
-	}
-	// if edit != nil {
-	// 	changes := map[string][]protocol.TextEdit{
-	// 		req.TextDocument.URI: []protocol.TextEdit{
-	// 			{
-	// 				Range: protocol.Range{
-	// 					Start: req.TextDocumentPositionParams.Position,
-	// 					End:   req.TextDocumentPositionParams.Position,
-	// 				},
-	// 				NewText: *edit,
-	// 			},
-	// 		},
-	// 	}
-	// 	wep := &protocol.ApplyWorkspaceEditParams{
-	// 		Label: "adl-completion",
-	// 		Edit: protocol.WorkspaceEdit{
-	// 			Changes: &changes,
-	// 		},
-	// 	}
-	// 	svr.client.ApplyEdit(ctx, wep)
-	// }
-	return cl, nil
-}
-
-type declTypeComplete adl.DeclType
-
-func (dt declTypeComplete) Complete(curDecl *adl.Decl, allmod map[string]adl.Module, pos protocol.Position, uri string, svr *server) (items []protocol.CompletionItem) {
-	if dt.Union != nil {
-		for _, f := range dt.Union.Field {
-			ci := protocol.CompletionItem{
-				Label:  `"` + f.Name + `" : _`,
-				Kind:   protocol.TextCompletion,
-				Detail: "Union, only one field allowed",
-				// Documentation
-				// Deprecated
-				// Preselect
-				// SortText
-				// FilterText
-				// InsertText
-				// InsertTextFormat
-				// TextEdit
-				// AdditionalTextEdits
-				// CommitCharacters
-				// Command
-				// Data
-			}
-			items = append(items, ci)
+		if c.Insert != "" {
+			item.InsertTextFormat = protocol.SnippetTextFormat
+			item.TextEdit.NewText = c.Insert
 		}
-		return
-	}
-	if dt.Struct != nil {
-		var buf bytes.Buffer
-		for i, f := range dt.Struct.Field {
-			if i != 0 {
-				buf.WriteString(`, `)
-			}
-			buf.WriteString(`"`)
-			buf.WriteString(f.Name)
-			buf.WriteString(fmt.Sprintf(`" : $%d`, i+1))
+		if c.Kind == nav.Decl && c.Insert != "" {
+			item.Documentation = "The setting tron.autoApplyStructCompletions controls whether structs are auto applied, currently false"
 		}
-		te := protocol.TextEdit{
-			Range: protocol.Range{
-				Start: pos,
-				End:   pos,
-			},
-			NewText: buf.String(),
-		}
-		if svr.extConfig.ApplyPTComp {
-			changes := map[string][]protocol.TextEdit{
-				uri: []protocol.TextEdit{te},
-			}
-			wep := &protocol.ApplyWorkspaceEditParams{
-				Label: "adl-completion",
-				Edit: protocol.WorkspaceEdit{
-					Changes: &changes,
-					// DocumentChanges: []protocol.DocumentCh
-				},
-			}
-			svr.client.ApplyEdit(context.Background(), wep)
-		} else {
-			ci := protocol.CompletionItem{
-				Label:         curDecl.Name,
-				Kind:          protocol.SnippetCompletion,
-				Detail:        "Struct, all fields required",
-				Documentation: "The setting tron.autoApplyStructCompletions controls whether structs are auto applied, currently false",
-				// Deprecated
-				// Preselect
-				// SortText
-				// FilterText
-				// InsertText
-				// InsertTextFormat
-				TextEdit: &te,
-				// AdditionalTextEdits
-				// CommitCharacters
-				// Command
-				// Data
-			}
-			items = append(items, ci)
-		}
-		return
+		items = append(items, item)
 	}
-	return nil
+	return items, true
 }
 
-type complCollList struct {
-	line, col int
-	treeNode  []ctree.TreeNode
-	size      []int
+// applyCompletion edits the document, tron.autoApplyStructCompletions applies struct values without offering them
+func (svr *server) applyCompletion(uri string, te protocol.TextEdit) {
+	changes := map[string][]protocol.TextEdit{
+		uri: []protocol.TextEdit{te},
+	}
+	wep := &protocol.ApplyWorkspaceEditParams{
+		Label: "adl-completion",
+		Edit: protocol.WorkspaceEdit{
+			Changes: &changes,
+		},
+	}
+	svr.client.ApplyEdit(context.Background(), wep)
 }
 
-func debug_(x string) {
-	q.Q(x)
-}
-
-func (v *complCollList) collection(tn ctree.TreeNode) {
-	b_line, b_col := tn.StartToken().GetLine(), tn.StartToken().GetColumn()
-	a_line, a_col := tn.StopToken().GetLine(), tn.StopToken().GetColumn()+len(tn.StopToken().GetText())
-	tcount := tn.StopToken().GetTokenIndex() - tn.StartToken().GetTokenIndex()
-	debug := fmt.Sprintf("#%d b=%d:%d a:%d:%d pos=%d:%d start=%v stop=%v",
-		tcount, b_line, b_col, a_line, a_col, v.line, v.col, tn.StartToken(), tn.StopToken())
-	// debug_(debug)
-	if ((b_line == v.line && b_col < v.col) || b_line < v.line) &&
-		((a_line == v.line && a_col >= v.col) || a_line > v.line) {
-		q.Q(debug)
-		v.size = append(v.size, tcount)
-		v.treeNode = append(v.treeNode, tn)
+func completionKind(kind nav.Kind, keyword string) protocol.CompletionItemKind {
+	switch kind {
+	case nav.Keyword, nav.Primitive:
+		return protocol.KeywordCompletion
+	case nav.Module, nav.ModuleRef:
+		return protocol.ModuleCompletion
+	case nav.TypeParam:
+		return protocol.TypeParameterCompletion
+	case nav.Anno:
+		return protocol.PropertyCompletion
+	case nav.Field:
+		if keyword == "union" {
+			return protocol.EnumMemberCompletion
+		}
+		return protocol.FieldCompletion
 	}
-}
-
-func (v *complCollList) VisitTerminal(node antlr.TerminalNode) {
-	if tn, ok := node.GetSymbol().(ctree.TreeNode); ok {
-		v.collection(tn)
+	switch keyword {
+	case "union":
+		return protocol.EnumCompletion
+	case "newtype":
+		return protocol.ClassCompletion
+	case "type":
+		return protocol.TypeParameterCompletion
 	}
+	return protocol.StructCompletion
 }
-
-func (v *complCollList) VisitErrorNode(node antlr.ErrorNode) {}
-func (v *complCollList) EnterEveryRule(ctx antlr.ParserRuleContext) {
-	// if tn, ok := ctx.GetStart().(ctree.TreeNode); ok {
-	// 	v.collection(tn)
-	// }
-}
-func (v *complCollList) ExitEveryRule(ctx antlr.ParserRuleContext) {}
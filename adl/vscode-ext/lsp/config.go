@@ -6,6 +6,7 @@ import (
 	"path"
 
 	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/lint"
 	"golang.org/x/tools/lsp/protocol"
 )
 
@@ -18,11 +19,12 @@ type TronCfg struct {
 }
 
 type TronExtCfg struct {
-	ApplyPTComp  bool     `json:"autoApplyStructCompletions"`
-	Includes     []string `json:"includes"`
-	MaxIssues    float64  `json:"maxIssues"`
-	AdlcPath     string   `json:"adlc.path"`
-	TraceServer  string   `json:"trace.server"`
+	ApplyPTComp  bool        `json:"autoApplyStructCompletions"`
+	Includes     []string    `json:"includes"`
+	MaxIssues    float64     `json:"maxIssues"`
+	AdlcPath     string      `json:"adlc.path"`
+	Lint         lint.Config `json:"lint"`
+	TraceServer  string      `json:"trace.server"`
 	TronLspServe struct {
 		Port       int    `json:"port"`
 		AdlAstPath string `json:"adlast.path"`
@@ -36,6 +38,9 @@ type TronExtCfg struct {
 		Format       bool `json:"format"`
 		AutoComplete bool `json:"autoComplete"`
 	} `json:"devFeatures"`
+	Rename struct {
+		SerializedFields bool `json:"serializedFields"`
+	} `json:"rename"`
 }
 
 type TronLangCfg struct {
@@ -0,0 +1,134 @@
+package lsp
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+func uriPath(uri string) string {
+	return strings.TrimPrefix(uri, "file://")
+}
+
+func pathURI(path string) string {
+	return "file://" + path
+}
+
+// sourceDirs are the directories modules are read from, the workspace folders and the include paths
+func (svr *server) sourceDirs() []string {
+	dirs := []string{}
+	root := ""
+	if svr.initParams != nil {
+		root = uriPath(svr.initParams.RootURI)
+	}
+	for _, wf := range svr.workspaceFolders {
+		dirs = append(dirs, uriPath(wf.URI))
+	}
+	if len(dirs) == 0 && root != "" {
+		dirs = append(dirs, root)
+	}
+	for _, inc := range svr.extConfig.Includes {
+		if abs, err := filepath.Abs(filepath.Join(root, inc)); err == nil {
+			dirs = append(dirs, abs)
+		}
+	}
+	return dirs
+}
+
+// source is the text of the file, from the editor when it is open
+func (svr *server) source(uri string) (string, error) {
+	if text, err := svr.fileCache.get(uri); err == nil {
+		return text, nil
+	}
+	by, err := ioutil.ReadFile(uriPath(uri))
+	return string(by), err
+}
+
+// moduleIndex indexes the source of a module, from the workspace or else module a.b.c is the file a/b/c.adl of a source dir.
+// from is the file being edited, and the module's file when it declares it.
+func (svr *server) moduleIndex(module string, from string, fromIx *nav.Index) (string, *nav.Index, bool) {
+	if fromIx.Module == module {
+		return from, fromIx, true
+	}
+	if uri, ix, ok := svr.workspace.Module(module); ok {
+		return uri, ix, true
+	}
+	rel := filepath.Join(strings.Split(module, ".")...) + ".adl"
+	for _, dir := range svr.sourceDirs() {
+		uri := pathURI(filepath.Join(dir, rel))
+		text, err := svr.source(uri)
+		if err != nil {
+			continue
+		}
+		ix := nav.Parse(text, svr.allmod)
+		if ix.Module == module {
+			return uri, ix, true
+		}
+	}
+	return "", nil, false
+}
+
+// declaration is where the name is declared, the decl, field, type param or module
+func (svr *server) declaration(uri string, ix *nav.Index, n nav.Name) (protocol.Location, bool) {
+	kind, field := nav.Decl, ""
+	switch n.Kind {
+	case nav.Module, nav.ModuleRef:
+		kind = nav.Module
+	case nav.TypeParam:
+		kind, field = nav.TypeParam, n.Field
+	case nav.Field, nav.JsonKey:
+		kind, field = nav.Field, n.Field
+	case nav.AnnoTarget:
+		if n.Field != "" {
+			kind, field = nav.Field, n.Field
+		}
+	case nav.Primitive:
+		return protocol.Location{}, false
+	}
+	if n.Ref.ModuleName == "" {
+		return protocol.Location{}, false
+	}
+	duri, dix, ok := svr.moduleIndex(n.Ref.ModuleName, uri, ix)
+	if !ok {
+		return protocol.Location{}, false
+	}
+	ref := n.Ref
+	if kind == nav.Module {
+		ref = adl.ScopedName{ModuleName: dix.Module}
+	}
+	// type params are declared before they are used
+	d, ok := dix.Find(kind, ref, field)
+	if !ok {
+		return protocol.Location{}, false
+	}
+	return protocol.Location{URI: duri, Range: *nameRange(d)}, true
+}
+
+func (svr *server) Definition(ctx context.Context, req *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
+	q.Q(req)
+	ix, ok := svr.index(req.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	n, ok := ix.At(int(req.Position.Line), int(req.Position.Character))
+	if !ok {
+		return nil, nil
+	}
+	if t, ok := n.Target(); ok {
+		if o, ok := svr.workspace.Declaration(t); ok {
+			return []protocol.Location{{URI: o.URI, Range: *nameRange(o.Name)}}, nil
+		}
+	}
+	// declared outside the workspace
+	loc, ok := svr.declaration(req.TextDocument.URI, ix, n)
+	if !ok {
+		return nil, nil
+	}
+	return []protocol.Location{loc}, nil
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/golangq/q"
 	antlr "github.com/wxio/goantlr"
 	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/lint"
 	"golang.org/x/tools/lsp/protocol"
 )
 
@@ -158,3 +159,34 @@ func collectionDiag(errs []adl.DiagMessage, src string, max int, dss *[]protocol
 		}
 	}
 }
+
+// lintDiag lints the text, allmod holds the modules adlc resolved
+func (svr *server) lintDiag(text string, allmod map[string]adl.Module) []protocol.Diagnostic {
+	dss := []protocol.Diagnostic{}
+	ds, err := lint.Lint(text, allmod, svr.extConfig.Lint)
+	if err != nil {
+		q.Q(err)
+		return dss
+	}
+	for _, er := range ds {
+		dss = append(dss, protocol.Diagnostic{
+			Range: protocol.Range{
+				Start: protocol.Position{
+					Line:      float64(er.Line()),
+					Character: float64(er.Column()),
+				},
+				End: protocol.Position{
+					Line:      float64(er.Line()),
+					Character: float64(er.Column() + er.Len()),
+				},
+			},
+			Severity:           protocol.DiagnosticSeverity(er.Severity),
+			Code:               er.Rule,
+			Source:             "ADL-LINT",
+			Message:            er.Msg,
+			Tags:               []protocol.DiagnosticTag{},
+			RelatedInformation: []protocol.DiagnosticRelatedInformation{},
+		})
+	}
+	return dss
+}
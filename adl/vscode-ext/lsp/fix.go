@@ -0,0 +1,40 @@
+package lsp
+
+import (
+	"github.com/wxio/tron-go/adl/fix"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// fixes are the quick fixes and refactorings of the lines of rng, of the kinds requested
+func (svr *server) fixes(uri string, rng protocol.Range, only []protocol.CodeActionKind) []protocol.CodeAction {
+	actions := []protocol.CodeAction{}
+	ix, ok := svr.index(uri)
+	if !ok {
+		return actions
+	}
+	r := nav.Range{
+		StartLine:   int(rng.Start.Line),
+		StartColumn: int(rng.Start.Character),
+		EndLine:     int(rng.End.Line),
+		EndColumn:   int(rng.End.Character),
+	}
+	for _, f := range fix.Fixes(ix, r) {
+		kind := protocol.CodeActionKind(f.Kind)
+		if !wantsKind(only, kind) {
+			continue
+		}
+		edits := []protocol.TextEdit{}
+		for _, ed := range f.Edits {
+			edits = append(edits, protocol.TextEdit{Range: lspRange(ed.Range), NewText: ed.Text})
+		}
+		actions = append(actions, protocol.CodeAction{
+			Title: f.Title,
+			Kind:  kind,
+			Edit: &protocol.WorkspaceEdit{
+				Changes: &map[string][]protocol.TextEdit{uri: edits},
+			},
+		})
+	}
+	return actions
+}
@@ -0,0 +1,27 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+func (svr *server) FoldingRanges(ctx context.Context, req *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
+	q.Q(req)
+	text, err := svr.fileCache.get(req.TextDocument.URI)
+	if err != nil {
+		q.Q(err)
+		return nil, nil
+	}
+	frs := []protocol.FoldingRange{}
+	for _, f := range nav.Folds(text) {
+		frs = append(frs, protocol.FoldingRange{
+			StartLine: float64(f.StartLine),
+			EndLine:   float64(f.EndLine),
+			Kind:      f.Kind,
+		})
+	}
+	return frs, nil
+}
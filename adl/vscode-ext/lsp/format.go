@@ -0,0 +1,67 @@
+package lsp
+
+import (
+	"context"
+	"strings"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/adlfmt"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+func (svr *server) Formatting(ctx context.Context, req *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
+	edits, err := svr.formatEdits(req.TextDocument.URI)
+	if err != nil {
+		q.Q(err)
+		return nil, nil
+	}
+	return textEdits(edits), nil
+}
+
+// RangeFormatting applies the formatting changes that touch the lines of the range
+func (svr *server) RangeFormatting(ctx context.Context, req *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
+	edits, err := svr.formatEdits(req.TextDocument.URI)
+	if err != nil {
+		q.Q(err)
+		return nil, nil
+	}
+	start, end := int(req.Range.Start.Line), int(req.Range.End.Line)
+	// a selection ending at the start of a line doesn't include it
+	if req.Range.End.Character == 0 && end > start {
+		end--
+	}
+	in := []adlfmt.Edit{}
+	for _, ed := range edits {
+		if ed.Start <= end && ed.End >= start {
+			in = append(in, ed)
+		}
+	}
+	return textEdits(in), nil
+}
+
+func (svr *server) formatEdits(uri string) ([]adlfmt.Edit, error) {
+	text, err := svr.fileCache.get(uri)
+	if err != nil {
+		return nil, err
+	}
+	out, err := adlfmt.Format(text)
+	if err != nil {
+		return nil, err
+	}
+	return adlfmt.Diff(text, out), nil
+}
+
+// textEdits replaces whole lines, the edits don't overlap as required by the protocol
+func textEdits(edits []adlfmt.Edit) []protocol.TextEdit {
+	tes := []protocol.TextEdit{}
+	for _, ed := range edits {
+		tes = append(tes, protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: float64(ed.Start)},
+				End:   protocol.Position{Line: float64(ed.End)},
+			},
+			NewText: strings.Join(ed.Lines, ""),
+		})
+	}
+	return tes
+}
@@ -0,0 +1,37 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+func (svr *server) Hover(ctx context.Context, req *protocol.TextDocumentPositionParams) (*protocol.Hover, error) {
+	q.Q(req)
+	ix, ok := svr.index(req.TextDocument.URI)
+	if !ok {
+		return nil, nil
+	}
+	n, ok := ix.At(int(req.Position.Line), int(req.Position.Character))
+	if !ok {
+		return nil, nil
+	}
+	md := ix.Describe(n)
+	if md == "" {
+		return nil, nil
+	}
+	return &protocol.Hover{
+		Contents: protocol.MarkupContent{Kind: protocol.Markdown, Value: md},
+		Range:    nameRange(n),
+	}, nil
+}
+
+// nameRange is the range of a name in the source
+func nameRange(n nav.Name) *protocol.Range {
+	return &protocol.Range{
+		Start: protocol.Position{Line: float64(n.Line), Character: float64(n.Column)},
+		End:   protocol.Position{Line: float64(n.Line), Character: float64(n.Column + n.Len())},
+	}
+}
@@ -0,0 +1,46 @@
+package lsp
+
+import (
+	"strings"
+
+	"github.com/wxio/tron-go/adl/adlfmt"
+	"github.com/wxio/tron-go/adl/usage"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// organizeImports removes unused imports and sorts them, editor.codeActionsOnSave runs it on save
+func (svr *server) organizeImports(uri string) (*protocol.CodeAction, error) {
+	text, err := svr.fileCache.get(uri)
+	if err != nil {
+		return nil, err
+	}
+	out, err := usage.OrganizeImports(text, svr.allmod, false)
+	if err != nil {
+		return nil, err
+	}
+	if out == text {
+		return nil, nil
+	}
+	return &protocol.CodeAction{
+		Title: "Organize imports",
+		Kind:  protocol.SourceOrganizeImports,
+		Edit: &protocol.WorkspaceEdit{
+			Changes: &map[string][]protocol.TextEdit{
+				uri: textEdits(adlfmt.Diff(text, out)),
+			},
+		},
+	}, nil
+}
+
+// wantsKind is true when the kinds requested are empty or cover kind
+func wantsKind(only []protocol.CodeActionKind, kind protocol.CodeActionKind) bool {
+	if len(only) == 0 {
+		return true
+	}
+	for _, k := range only {
+		if k == kind || strings.HasPrefix(string(kind), string(k)+".") {
+			return true
+		}
+	}
+	return false
+}
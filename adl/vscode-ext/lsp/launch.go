@@ -12,6 +12,8 @@ import (
 	"time"
 
 	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen/htmldoc"
 	"golang.org/x/tools/lsp/protocol"
 )
 
@@ -51,6 +53,10 @@ func (svr *server) serverBrowser() {
 	q.Q(svr.webAddr)
 	mux := &http.ServeMux{}
 	mux.HandleFunc("/", svr.serveJSON)
+	mux.Handle("/doc/", http.StripPrefix("/doc", htmldoc.Handler("Workspace", func() map[string]adl.Module {
+		// served concurrently with the requests that set the modules, the workspace holds them under its lock
+		return svr.workspace.Base()
+	})))
 	wsvr := http.Server{
 		Handler: mux,
 	}
@@ -83,6 +89,24 @@ func (svr *server) openbrowser() (err error) {
 	return
 }
 
+// opendoc opens the documentation site for the last compiled modules
+func (svr *server) opendoc() (err error) {
+	if svr.webAddr == nil {
+		return
+	}
+	url := "http://" + svr.webAddr.String() + "/doc/"
+	if svr.lastFileUri != "" {
+		if mod, err := svr.moduleName(svr.lastFileUri); err == nil {
+			url += htmldoc.ModulePage(mod)
+		}
+	}
+	ok := browserOpen(url)
+	if !ok {
+		err = fmt.Errorf("unsupported platform")
+	}
+	return
+}
+
 // Commands returns a list of possible commands to use to open a url.
 func browserCommands() [][]string {
 	var cmds [][]string
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/nav"
 
 	"github.com/golangq/q"
 	"golang.org/x/tools/jsonrpc2"
@@ -31,6 +32,7 @@ type server struct {
 	// tcpConn    net.Conn
 	fileCache filecache
 	allmod    map[string]adl.Module
+	workspace *nav.Workspace
 	// astCache  astcache
 }
 
@@ -44,6 +46,7 @@ func (svr *server) Initialize(ctx context.Context, req *protocol.InitializeParam
 	svr.tempDir = tempDir
 	q.Q(req)
 	svr.fileCache = newCache()
+	svr.workspace = nav.NewWorkspace()
 	// svr.astCache = newAstCache()
 	svr.initParams = req
 	// type ServerCapabilities struct {
@@ -63,16 +66,19 @@ func (svr *server) Initialize(ctx context.Context, req *protocol.InitializeParam
 			InnerServerCapabilities: protocol.InnerServerCapabilities{
 				CodeActionProvider: true,
 				CompletionProvider: &protocol.CompletionOptions{
-					TriggerCharacters: []string{"."},
+					TriggerCharacters: []string{".", "@", "\""},
 				},
 				DefinitionProvider:              true,
 				DocumentFormattingProvider:      true,
 				DocumentRangeFormattingProvider: true,
 				DocumentSymbolProvider:          true,
 				ExecuteCommandProvider: &protocol.ExecuteCommandOptions{
-					Commands: []string{"tron.compile", "tron.browse", "tron.lsp.readconfig"},
+					Commands: []string{"tron.compile", "tron.browse", "tron.doc", "tron.lsp.readconfig"},
 				},
 				HoverProvider:             true,
+				ReferencesProvider:        true,
+				WorkspaceSymbolProvider:   true,
+				RenameProvider:            true,
 				DocumentHighlightProvider: true,
 				SignatureHelpProvider: &protocol.SignatureHelpOptions{
 					TriggerCharacters: []string{"(", ","},
@@ -107,6 +113,9 @@ func (svr *server) Initialize(ctx context.Context, req *protocol.InitializeParam
 			DeclarationServerCapabilities: protocol.DeclarationServerCapabilities{
 				DeclarationProvider: true,
 			},
+			FoldingRangeServerCapabilities: protocol.FoldingRangeServerCapabilities{
+				FoldingRangeProvider: true,
+			},
 		},
 	}
 
@@ -158,6 +167,7 @@ func (svr *server) Initialized(ctx context.Context, req *protocol.InitializedPar
 	// }
 
 	svr.config()
+	svr.loadWorkspace()
 	svr.serverBrowser()
 
 	return nil
@@ -181,14 +191,6 @@ func (svr *server) DidChangeConfiguration(ctx context.Context, req *protocol.Did
 	q.Q(req)
 	return nil
 }
-func (svr *server) DidChangeWatchedFiles(ctx context.Context, req *protocol.DidChangeWatchedFilesParams) error {
-	q.Q(req)
-	return nil
-}
-func (svr *server) Symbols(ctx context.Context, req *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
-	q.Q(req)
-	return nil, nil
-}
 func (svr *server) ExecuteCommand(ctx context.Context, req *protocol.ExecuteCommandParams) (interface{}, error) {
 	q.Q(req)
 	switch req.Command {
@@ -207,6 +209,13 @@ func (svr *server) ExecuteCommand(ctx context.Context, req *protocol.ExecuteComm
 				Type:    protocol.Info,
 			})
 		}
+	case "tron.doc":
+		if err := svr.opendoc(); err != nil {
+			svr.client.ShowMessage(ctx, &protocol.ShowMessageParams{
+				Message: "Error launching browser",
+				Type:    protocol.Warning,
+			})
+		}
 	case "tron.lsp.readconfig":
 		svr.config()
 	case "tron.compile":
@@ -218,6 +227,7 @@ func (svr *server) ExecuteCommand(ctx context.Context, req *protocol.ExecuteComm
 				return nil, nil
 			}
 			svr.allmod = allmod
+			svr.workspace.SetBase(allmod)
 			svr.compile(ctx, cur, allmod, svr.client_msg_log)
 		}
 	default:
@@ -235,6 +245,7 @@ func (svr *server) DidOpen(ctx context.Context, req *protocol.DidOpenTextDocumen
 		return nil
 	}
 	svr.fileCache.put(req.TextDocument.URI, req.TextDocument.Text)
+	svr.workspace.Update(req.TextDocument.URI, req.TextDocument.Text)
 	dss := svr.diag(ctx, req.TextDocument.Text)
 	svr.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
 		Diagnostics: dss,
@@ -249,7 +260,12 @@ func (svr *server) DidOpen(ctx context.Context, req *protocol.DidOpenTextDocumen
 			return nil
 		}
 		svr.allmod = allmod
+		svr.workspace.SetBase(allmod)
 		svr.compile(ctx, cur, allmod, svr.client_log)
+		svr.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			Diagnostics: svr.lintDiag(req.TextDocument.Text, allmod),
+			URI:         req.TextDocument.URI,
+		})
 	}
 
 	return nil
@@ -265,6 +281,7 @@ func (svr *server) DidChange(ctx context.Context, req *protocol.DidChangeTextDoc
 	}
 	change := req.ContentChanges[0]
 	svr.fileCache.put(req.TextDocument.URI, change.Text)
+	svr.workspace.Update(req.TextDocument.URI, change.Text)
 	dss := svr.diag(ctx, change.Text)
 	svr.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
 		Diagnostics: dss,
@@ -279,7 +296,12 @@ func (svr *server) DidChange(ctx context.Context, req *protocol.DidChangeTextDoc
 			return nil
 		}
 		svr.allmod = allmod
+		svr.workspace.SetBase(allmod)
 		svr.compile(ctx, cur, allmod, svr.client_log)
+		svr.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
+			Diagnostics: svr.lintDiag(change.Text, allmod),
+			URI:         req.TextDocument.URI,
+		})
 	}
 
 	return nil
@@ -303,6 +325,7 @@ func (svr *server) DidClose(ctx context.Context, req *protocol.DidCloseTextDocum
 		return nil
 	}
 	svr.fileCache.delete(req.TextDocument.URI)
+	svr.reindex(req.TextDocument.URI)
 	svr.client.PublishDiagnostics(ctx, &protocol.PublishDiagnosticsParams{
 		Diagnostics: []protocol.Diagnostic{},
 		URI:         req.TextDocument.URI,
@@ -311,25 +334,21 @@ func (svr *server) DidClose(ctx context.Context, req *protocol.DidCloseTextDocum
 }
 func (svr *server) Completion(ctx context.Context, req *protocol.CompletionParams) (*protocol.CompletionList, error) {
 	q.Q(req)
-	return svr.collect(ctx, req)
+	items, ok := svr.complete(req)
+	if !ok {
+		return nil, nil
+	}
+	return &protocol.CompletionList{Items: items}, nil
 }
 
 func (svr *server) CompletionResolve(ctx context.Context, req *protocol.CompletionItem) (*protocol.CompletionItem, error) {
 	q.Q(req)
 	return nil, nil
 }
-func (svr *server) Hover(ctx context.Context, req *protocol.TextDocumentPositionParams) (*protocol.Hover, error) {
-	q.Q(req)
-	return nil, nil
-}
 func (svr *server) SignatureHelp(ctx context.Context, req *protocol.TextDocumentPositionParams) (*protocol.SignatureHelp, error) {
 	q.Q(req)
 	return nil, nil
 }
-func (svr *server) Definition(ctx context.Context, req *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
-	q.Q(req)
-	return nil, nil
-}
 func (svr *server) TypeDefinition(ctx context.Context, req *protocol.TextDocumentPositionParams) ([]protocol.Location, error) {
 	q.Q(req)
 	return nil, nil
@@ -338,10 +357,6 @@ func (svr *server) Implementation(ctx context.Context, req *protocol.TextDocumen
 	q.Q(req)
 	return nil, nil
 }
-func (svr *server) References(ctx context.Context, req *protocol.ReferenceParams) ([]protocol.Location, error) {
-	q.Q(req)
-	return nil, nil
-}
 func (svr *server) DocumentHighlight(ctx context.Context, req *protocol.TextDocumentPositionParams) ([]protocol.DocumentHighlight, error) {
 	q.Q(req)
 	return nil, nil
@@ -372,7 +387,17 @@ func (svr *server) CodeAction(ctx context.Context, req *protocol.CodeActionParam
 		return nil, nil
 	}
 	svr.lastFileUri = req.TextDocument.URI
-	return nil, nil
+	actions := []protocol.CodeAction{}
+	if wantsKind(req.Context.Only, protocol.SourceOrganizeImports) {
+		ca, err := svr.organizeImports(req.TextDocument.URI)
+		if err != nil {
+			q.Q(err)
+		} else if ca != nil {
+			actions = append(actions, *ca)
+		}
+	}
+	actions = append(actions, svr.fixes(req.TextDocument.URI, req.Range, req.Context.Only)...)
+	return actions, nil
 }
 func (svr *server) CodeLens(ctx context.Context, req *protocol.CodeLensParams) ([]protocol.CodeLens, error) {
 	q.Q(req)
@@ -398,23 +423,7 @@ func (svr *server) ColorPresentation(ctx context.Context, req *protocol.ColorPre
 	q.Q(req)
 	return nil, nil
 }
-func (svr *server) Formatting(ctx context.Context, req *protocol.DocumentFormattingParams) ([]protocol.TextEdit, error) {
-	q.Q(req)
-	return nil, nil
-}
-func (svr *server) RangeFormatting(ctx context.Context, req *protocol.DocumentRangeFormattingParams) ([]protocol.TextEdit, error) {
-	q.Q(req)
-	return nil, nil
-}
 func (svr *server) OnTypeFormatting(ctx context.Context, req *protocol.DocumentOnTypeFormattingParams) ([]protocol.TextEdit, error) {
 	q.Q(req)
 	return nil, nil
 }
-func (svr *server) Rename(ctx context.Context, req *protocol.RenameParams) ([]protocol.WorkspaceEdit, error) {
-	q.Q(req)
-	return nil, nil
-}
-func (svr *server) FoldingRanges(ctx context.Context, req *protocol.FoldingRangeParams) ([]protocol.FoldingRange, error) {
-	q.Q(req)
-	return nil, nil
-}
@@ -0,0 +1,51 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// index of the file, from the workspace or indexed from the file cache
+func (svr *server) index(uri string) (*nav.Index, bool) {
+	if ix, ex := svr.workspace.File(uri); ex {
+		return ix, true
+	}
+	text, err := svr.fileCache.get(uri)
+	if err != nil {
+		q.Q(err)
+		return nil, false
+	}
+	return svr.workspace.Update(uri, text), true
+}
+
+// target is what the name at the position declares or refers to
+func (svr *server) target(uri string, pos protocol.Position) (nav.Target, bool) {
+	ix, ok := svr.index(uri)
+	if !ok {
+		return nav.Target{}, false
+	}
+	n, ok := ix.At(int(pos.Line), int(pos.Character))
+	if !ok {
+		return nav.Target{}, false
+	}
+	return n.Target()
+}
+
+func (svr *server) References(ctx context.Context, req *protocol.ReferenceParams) ([]protocol.Location, error) {
+	q.Q(req)
+	t, ok := svr.target(req.TextDocument.URI, req.Position)
+	if !ok {
+		return nil, nil
+	}
+	locs := []protocol.Location{}
+	for _, o := range svr.workspace.Refs(t) {
+		if o.Declares && !req.Context.IncludeDeclaration {
+			continue
+		}
+		locs = append(locs, protocol.Location{URI: o.URI, Range: *nameRange(o.Name)})
+	}
+	return locs, nil
+}
@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/golangq/q"
+	"golang.org/x/tools/jsonrpc2"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// handler replies to textDocument/rename with a WorkspaceEdit, the protocol package's Server replies with a list
+// of them which clients don't accept
+func (svr *server) handler(next jsonrpc2.Handler) jsonrpc2.Handler {
+	return func(ctx context.Context, conn *jsonrpc2.Conn, r *jsonrpc2.Request) {
+		if r.Method != "textDocument/rename" || r.Params == nil {
+			next(ctx, conn, r)
+			return
+		}
+		var params protocol.RenameParams
+		if err := json.Unmarshal(*r.Params, &params); err != nil {
+			conn.Reply(ctx, r, nil, jsonrpc2.NewErrorf(jsonrpc2.CodeParseError, "%v", err))
+			return
+		}
+		edit, err := svr.rename(ctx, &params)
+		if err != nil {
+			conn.Reply(ctx, r, nil, jsonrpc2.NewErrorf(jsonrpc2.CodeInvalidRequest, "%v", err))
+			return
+		}
+		if err := conn.Reply(ctx, r, edit, nil); err != nil {
+			q.Q(err)
+		}
+	}
+}
+
+func (svr *server) Rename(ctx context.Context, req *protocol.RenameParams) ([]protocol.WorkspaceEdit, error) {
+	edit, err := svr.rename(ctx, req)
+	if err != nil || edit == nil {
+		return nil, err
+	}
+	return []protocol.WorkspaceEdit{*edit}, nil
+}
+
+// rename the decl, field or type param at the position across the workspace
+func (svr *server) rename(ctx context.Context, req *protocol.RenameParams) (*protocol.WorkspaceEdit, error) {
+	q.Q(req)
+	t, ok := svr.target(req.TextDocument.URI, req.Position)
+	if !ok {
+		return nil, nil
+	}
+	edits, err := svr.workspace.Rename(t, req.NewName, svr.extConfig.Rename.SerializedFields)
+	if err != nil {
+		return nil, err
+	}
+	changes := map[string][]protocol.TextEdit{}
+	for uri, eds := range edits {
+		for _, ed := range eds {
+			line := float64(ed.Line)
+			changes[uri] = append(changes[uri], protocol.TextEdit{
+				Range: protocol.Range{
+					Start: protocol.Position{Line: line, Character: float64(ed.Column)},
+					End:   protocol.Position{Line: line, Character: float64(ed.Column + ed.Len)},
+				},
+				NewText: ed.Text,
+			})
+		}
+	}
+	return &protocol.WorkspaceEdit{Changes: &changes}, nil
+}
@@ -32,6 +32,7 @@ func (svr *tcpsdtio) Run() error {
 	connLSP, client, _ := protocol.NewServer(stream, srv)
 	srv.client = client
 	srv.conn = connLSP
+	connLSP.Handler = srv.handler(connLSP.Handler)
 	q.Q(connLSP.Run(ctx))
 	q.Q("exiting")
 	return nil
@@ -0,0 +1,57 @@
+package lsp
+
+import (
+	"context"
+
+	"github.com/golangq/q"
+	"github.com/wxio/tron-go/adl/nav"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// maxSymbols is the most symbols a workspace symbol search returns
+const maxSymbols = 200
+
+// symbolKind of a module, import, decl, type param, field or annotation, keyword declares the decl
+func symbolKind(kind nav.Kind, keyword string) protocol.SymbolKind {
+	switch {
+	case kind == nav.Module:
+		return protocol.Module
+	case kind == nav.Import:
+		return protocol.Namespace
+	case kind == nav.Anno:
+		return protocol.Property
+	case kind == nav.TypeParam:
+		return protocol.TypeParameter
+	case kind == nav.Field && keyword == "union":
+		return protocol.EnumMember
+	case kind == nav.Field:
+		return protocol.Field
+	}
+	switch keyword {
+	case "union":
+		return protocol.Enum
+	case "newtype":
+		return protocol.Class
+	case "type":
+		return protocol.TypeParameter
+	}
+	return protocol.Struct
+}
+
+func (svr *server) Symbols(ctx context.Context, req *protocol.WorkspaceSymbolParams) ([]protocol.SymbolInformation, error) {
+	q.Q(req)
+	sis := []protocol.SymbolInformation{}
+	for _, s := range svr.workspace.Symbols(req.Query, maxSymbols) {
+		name := s.Text
+		if s.Kind == nav.Field {
+			name = s.Decl + "::" + s.Text
+		}
+		sis = append(sis, protocol.SymbolInformation{
+			Name:          name,
+			Kind:          symbolKind(s.Kind, s.Keyword),
+			Location:      protocol.Location{URI: s.URI, Range: *nameRange(s.Name)},
+			ContainerName: s.Module,
+		})
+	}
+	return sis, nil
+}
@@ -65,6 +65,7 @@ func (svr *tcpsvr) handle(in io.Reader, out io.Writer) {
 	connLSP, client, _ := protocol.NewServer(stream, srv)
 	srv.client = client
 	srv.conn = connLSP
+	connLSP.Handler = srv.handler(connLSP.Handler)
 	q.Q(connLSP.Run(ctx))
 	q.Q("exiting")
 	// conn.Close()
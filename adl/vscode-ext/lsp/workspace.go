@@ -0,0 +1,69 @@
+package lsp
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golangq/q"
+	"golang.org/x/tools/lsp/protocol"
+)
+
+// loadWorkspace indexes the .adl files of the source dirs, open files are indexed from the editor
+func (svr *server) loadWorkspace() {
+	srcs := map[string]string{}
+	for _, dir := range svr.sourceDirs() {
+		filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info.IsDir() && path != dir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			if info.IsDir() || !strings.HasSuffix(path, ".adl") {
+				return nil
+			}
+			uri := pathURI(path)
+			if text, err := svr.source(uri); err == nil {
+				srcs[uri] = text
+			}
+			return nil
+		})
+	}
+	svr.workspace.SetBase(svr.allmod)
+	svr.workspace.Load(srcs)
+}
+
+// reindex indexes the file from disk, removing it when it can't be read
+func (svr *server) reindex(uri string) {
+	by, err := ioutil.ReadFile(uriPath(uri))
+	if err != nil {
+		svr.workspace.Remove(uri)
+		return
+	}
+	svr.workspace.Update(uri, string(by))
+}
+
+// DidChangeWatchedFiles re-indexes the .adl files changed outside the editor, so references and renames see them.
+// The events come from the extension's synchronize.fileEvents watcher, the server doesn't register one.
+func (svr *server) DidChangeWatchedFiles(ctx context.Context, req *protocol.DidChangeWatchedFilesParams) error {
+	q.Q(req)
+	for _, ch := range req.Changes {
+		if !strings.HasSuffix(ch.URI, ".adl") {
+			continue
+		}
+		if _, err := svr.fileCache.get(ch.URI); err == nil {
+			// the editor's text is indexed
+			continue
+		}
+		switch ch.Type {
+		case protocol.Deleted:
+			svr.workspace.Remove(ch.URI)
+		default:
+			svr.reindex(ch.URI)
+		}
+	}
+	return nil
+}
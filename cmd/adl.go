@@ -3,10 +3,8 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 
 	"github.com/jpillora/opts"
-	"github.com/wxio/tron-go/adl"
 )
 
 func NewLoadAdlAst() opts.Opts {
@@ -18,12 +16,7 @@ type readast struct {
 }
 
 func (et *readast) Run() error {
-	by, err := ioutil.ReadFile(et.File)
-	if err != nil {
-		return err
-	}
-	m := make(map[string]adl.Module)
-	err = json.Unmarshal(by, &m)
+	m, err := loadAst(et.File)
 	if err != nil {
 		return err
 	}
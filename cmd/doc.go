@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen/htmldoc"
+)
+
+func NewAdlDoc() opts.Opts {
+	return opts.New(&adlDoc{Output: "adldoc", Title: "ADL"}).Name("doc")
+}
+
+type adlDoc struct {
+	Ast    string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Output string `help:"output directory" predict:"dirs"`
+	Title  string `help:"title of the index page"`
+	Serve  string `help:"serve the site on this address, ie localhost:8080, instead of writing it"`
+}
+
+func (cm *adlDoc) Run() error {
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	site := htmldoc.New(allmod)
+	site.Title = cm.Title
+	if cm.Serve == "" {
+		return site.Write(cm.Output)
+	}
+	lst, err := net.Listen("tcp", cm.Serve)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("serving http://%s/\n", lst.Addr())
+	return http.Serve(lst, htmldoc.Handler(cm.Title, func() map[string]adl.Module { return allmod }))
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl/adlfmt"
+)
+
+func NewAdlFmt() opts.Opts {
+	return opts.New(&adlFmt{}).Name("fmt")
+}
+
+type adlFmt struct {
+	Files []string `type:"arg" help:"adl files to format" predict:"files"`
+	Write bool     `help:"write the result to the file rather than stdout"`
+	Diff  bool     `help:"print a diff of the formatting changes rather than the result"`
+	List  bool     `help:"list the files whose formatting differs rather than the result"`
+}
+
+func (cm *adlFmt) Run() error {
+	failed := false
+	for _, file := range cm.Files {
+		by, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		out, err := adlfmt.Format(string(by))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%v\n", file, err)
+			failed = true
+			continue
+		}
+		changed := out != string(by)
+		if cm.List && changed {
+			fmt.Println(file)
+		}
+		if cm.Diff && changed {
+			fmt.Print(adlfmt.Unified(file, string(by), out))
+		}
+		if cm.Write && changed {
+			if err := ioutil.WriteFile(file, []byte(out), 0644); err != nil {
+				return err
+			}
+		}
+		if !cm.List && !cm.Diff && !cm.Write {
+			fmt.Print(out)
+		}
+	}
+	if failed {
+		return fmt.Errorf("some files could not be formatted")
+	}
+	return nil
+}
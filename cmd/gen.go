@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/gen/gogen"
+	"github.com/wxio/tron-go/adl/gen/sqlgen"
+	"github.com/wxio/tron-go/adl/gen/tsgen"
+)
+
+// loadAst reads the combined output of adlc ast
+func loadAst(file string) (map[string]adl.Module, error) {
+	by, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	allmod := make(map[string]adl.Module)
+	err = json.Unmarshal(by, &allmod)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return allmod, nil
+}
+
+// writeOut writes to the file or stdout when file is ""
+func writeOut(file string, by []byte) error {
+	if file == "" {
+		_, err := os.Stdout.Write(by)
+		return err
+	}
+	return ioutil.WriteFile(file, by, 0644)
+}
+
+func NewGenGo() opts.Opts {
+	return opts.New(&genGo{}).Name("go")
+}
+
+type genGo struct {
+	Ast     string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Module  string `help:"adl module to generate"`
+	Package string `help:"go package name, defaults to the last component of the module"`
+	Http    bool   `help:"generate a Service interface, router and client for the common.http requests"`
+	Output  string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+func (cm *genGo) Run() error {
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	src, err := gogen.Generate(allmod, gogen.Config{
+		Module:  cm.Module,
+		Package: cm.Package,
+		Http:    cm.Http,
+	})
+	if err != nil {
+		return err
+	}
+	return writeOut(cm.Output, src)
+}
+
+func NewGenSql() opts.Opts {
+	return opts.New(&genSql{Dialect: "postgres"}).Name("sql")
+}
+
+type genSql struct {
+	Ast     string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Module  string `help:"adl module to generate, defaults to all modules"`
+	Dialect string `help:"sql dialect, postgres or sqlite"`
+	Output  string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+func (cm *genSql) Run() error {
+	dialect, err := sqlgen.DialectByName(cm.Dialect)
+	if err != nil {
+		return err
+	}
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	src, err := sqlgen.Generate(allmod, sqlgen.Config{
+		Module:  cm.Module,
+		Dialect: dialect,
+	})
+	if err != nil {
+		return err
+	}
+	return writeOut(cm.Output, src)
+}
+
+func NewGenTs() opts.Opts {
+	return opts.New(&genTs{}).Name("ts")
+}
+
+type genTs struct {
+	Ast    string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Module string `help:"adl module to generate"`
+	Http   bool   `help:"generate a Client for the common.http requests"`
+	Output string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+func (cm *genTs) Run() error {
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	src, err := tsgen.Generate(allmod, tsgen.Config{
+		Module: cm.Module,
+		Http:   cm.Http,
+	})
+	if err != nil {
+		return err
+	}
+	return writeOut(cm.Output, src)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl/gen/graph"
+)
+
+func NewAdlGraph() opts.Opts {
+	return opts.New(&adlGraph{Format: "dot"}).Name("graph")
+}
+
+type adlGraph struct {
+	Ast     string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Format  string `help:"output format, dot or mermaid"`
+	Modules bool   `help:"graph the dependencies between modules rather than decls"`
+	Root    string `help:"only graph the decls reachable from this decl, ie common.http.Post"`
+	Output  string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+func (cm *adlGraph) Run() error {
+	if cm.Format != "dot" && cm.Format != "mermaid" {
+		return fmt.Errorf("unknown format '%s', expected dot or mermaid", cm.Format)
+	}
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	g, err := graph.Build(allmod, graph.Options{
+		Modules: cm.Modules,
+		Root:    cm.Root,
+	})
+	if err != nil {
+		return err
+	}
+	out := g.Dot()
+	if cm.Format == "mermaid" {
+		out = g.Mermaid()
+	}
+	return writeOut(cm.Output, []byte(out))
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/importer/goimport"
+	"github.com/wxio/tron-go/adl/importer/jsonschema"
+	"github.com/wxio/tron-go/adl/importer/protoimport"
+)
+
+// writeModules writes each module to dir/a/b/c.adl, as found by adlc's search path, or all of them to stdout when dir is ""
+func writeModules(dir string, mods []adl.Module) error {
+	out := []string{}
+	for _, mod := range mods {
+		src, err := adlbuild.Source(mod)
+		if err != nil {
+			return err
+		}
+		if dir == "" {
+			out = append(out, src)
+			continue
+		}
+		file := filepath.Join(dir, filepath.FromSlash(strings.Replace(mod.Name, ".", "/", -1))+".adl")
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+		if err := writeOut(file, []byte(src)); err != nil {
+			return err
+		}
+	}
+	if dir == "" {
+		return writeOut("", []byte(strings.Join(out, "\n")))
+	}
+	return nil
+}
+
+func NewImportGo() opts.Opts {
+	return opts.New(&importGo{}).Name("go")
+}
+
+type importGo struct {
+	Packages []string `type:"arg" help:"go packages, ie ./... or github.com/a/b"`
+	Module   string   `help:"adl module name of a single package, defaults to the import path with / as ."`
+	Dir      string   `help:"write each module to <dir>/<module path>.adl, defaults to stdout"`
+}
+
+func (cm *importGo) Run() error {
+	mods, err := goimport.Load(goimport.Config{Module: cm.Module}, cm.Packages...)
+	if err != nil {
+		return err
+	}
+	return writeModules(cm.Dir, mods)
+}
+
+func NewImportJsonSchema() opts.Opts {
+	return opts.New(&importJsonSchema{}).Name("jsonschema")
+}
+
+type importJsonSchema struct {
+	Schema string `type:"arg" help:"json schema file" predict:"files"`
+	Module string `help:"adl module name"`
+	Name   string `help:"decl name of the root schema, defaults to its title or Root"`
+	Output string `help:"adl output file, defaults to stdout" predict:"files"`
+	Ast    string `help:"also write the module as a combined adl ast file, as adlc ast --combined-output" predict:"files"`
+}
+
+func (cm *importJsonSchema) Run() error {
+	by, err := ioutil.ReadFile(cm.Schema)
+	if err != nil {
+		return err
+	}
+	mod, lossy, err := jsonschema.Import(by, jsonschema.Config{Module: cm.Module, Name: cm.Name})
+	if err != nil {
+		return fmt.Errorf("%s: %v", cm.Schema, err)
+	}
+	for _, l := range lossy {
+		fmt.Fprintf(os.Stderr, "%s: lossy: %s\n", cm.Schema, l)
+	}
+	src, err := adlbuild.Source(mod)
+	if err != nil {
+		return err
+	}
+	if cm.Ast != "" {
+		ast, err := json.MarshalIndent(map[string]adl.Module{mod.Name: mod}, "", "    ")
+		if err != nil {
+			return err
+		}
+		if err := writeOut(cm.Ast, ast); err != nil {
+			return err
+		}
+	}
+	return writeOut(cm.Output, []byte(src))
+}
+
+func NewImportProto() opts.Opts {
+	return opts.New(&importProto{}).Name("proto")
+}
+
+type importProto struct {
+	Files   []string `type:"arg" help:".proto files, the files they import are read for their definitions" predict:"files"`
+	Include []string `help:"directory imports are searched for in, defaults to the working directory"`
+	Dir     string   `help:"write each module to <dir>/<module path>.adl, defaults to stdout"`
+}
+
+func (cm *importProto) Run() error {
+	mods, err := protoimport.Load(protoimport.Config{Include: cm.Include}, cm.Files...)
+	if err != nil {
+		return err
+	}
+	return writeModules(cm.Dir, mods)
+}
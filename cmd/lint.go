@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl"
+	"github.com/wxio/tron-go/adl/adlbuild"
+	"github.com/wxio/tron-go/adl/lint"
+)
+
+func NewAdlLint() opts.Opts {
+	return opts.New(&adlLint{}).Name("lint")
+}
+
+type adlLint struct {
+	Files  []string `type:"arg" help:"adl files to lint" predict:"files"`
+	Ast    string   `help:"combined adl ast file of the files' imports, see adlc ast --combined-output, otherwise the files may only import each other" predict:"files"`
+	Config string   `help:"lint config json file, ie {\"rules\": {\"missing-doc\": {\"severity\": \"off\"}}}" predict:"files"`
+	Rules  bool     `help:"list the rules and their default severity"`
+}
+
+func (cm *adlLint) Run() error {
+	if cm.Rules {
+		for _, r := range lint.Rules() {
+			fmt.Printf("%-18s %-8s %s\n", r.Name, r.Severity, r.Doc)
+		}
+		return nil
+	}
+	cfg := lint.Config{}
+	if cm.Config != "" {
+		by, err := ioutil.ReadFile(cm.Config)
+		if err != nil {
+			return err
+		}
+		if cfg, err = lint.ReadConfig(by); err != nil {
+			return fmt.Errorf("%s: %v", cm.Config, err)
+		}
+	}
+	allmod := map[string]adl.Module{}
+	if cm.Ast != "" {
+		var err error
+		if allmod, err = loadAst(cm.Ast); err != nil {
+			return err
+		}
+	}
+	srcs := map[string]string{}
+	for _, file := range cm.Files {
+		by, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		srcs[file] = string(by)
+	}
+	// read the files not in the ast, until those left fail, they may import each other in any order
+	pending := append([]string{}, cm.Files...)
+	for len(pending) != 0 {
+		left := []string{}
+		for _, file := range pending {
+			mod, err := adlbuild.Parse(srcs[file], allmod)
+			if err != nil {
+				left = append(left, file)
+				continue
+			}
+			if _, ex := allmod[mod.Name]; !ex {
+				allmod[mod.Name] = mod
+			}
+		}
+		if len(left) == len(pending) {
+			break
+		}
+		pending = left
+	}
+	failed := false
+	for _, file := range cm.Files {
+		ds, err := lint.Lint(srcs[file], allmod, cfg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%v\n", file, err)
+			failed = true
+			continue
+		}
+		for _, d := range ds {
+			fmt.Printf("%s:%s\n", file, d)
+			failed = failed || d.Severity == lint.Error || d.Severity == lint.Warning
+		}
+	}
+	if failed {
+		return fmt.Errorf("lint found problems")
+	}
+	return nil
+}
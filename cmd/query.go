@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl/query"
+)
+
+func NewAdlQuery() opts.Opts {
+	return opts.New(&adlQuery{Format: "text"}).Name("query")
+}
+
+type adlQuery struct {
+	Expr   text   `type:"arg" help:"query, ie 'kind:struct @DbTable', 'refs:common.Instant' or 'fields type:Nullable<_>'"`
+	Ast    string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Format string `help:"output format, text or json"`
+	Output string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+// text is a string arg kept whole, opts scans plain strings up to the first space
+type text string
+
+func (t *text) Set(s string) error {
+	*t = text(s)
+	return nil
+}
+
+func (t text) String() string { return string(t) }
+
+func (cm *adlQuery) Run() error {
+	if cm.Format != "text" && cm.Format != "json" {
+		return fmt.Errorf("unknown format '%s', expected text or json", cm.Format)
+	}
+	q, err := query.Parse(string(cm.Expr))
+	if err != nil {
+		return fmt.Errorf("query: %v", err)
+	}
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	rs := q.Run(allmod)
+	if cm.Format == "json" {
+		buf := &bytes.Buffer{}
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "    ")
+		if err := enc.Encode(rs); err != nil {
+			return err
+		}
+		return writeOut(cm.Output, buf.Bytes())
+	}
+	lines := make([]string, len(rs))
+	for i, r := range rs {
+		lines[i] = r.String() + "\n"
+	}
+	return writeOut(cm.Output, []byte(strings.Join(lines, "")))
+}
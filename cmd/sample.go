@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"time"
+
+	"github.com/jpillora/opts"
+	"github.com/wxio/tron-go/adl/sample"
+)
+
+func NewAdlSample() opts.Opts {
+	return opts.New(&adlSample{Count: 1, Seed: time.Now().UnixNano()}).Name("sample")
+}
+
+type adlSample struct {
+	Type     text   `type:"arg" help:"type, ie helix.protoapp.requests.HelloReq, HelloReq when one module declares it, or 'Vector<common.Instant>'"`
+	Ast      string `type:"arg" help:"combined adl ast file, see adlc ast --combined-output" predict:"files"`
+	Count    int    `help:"number of values, more than one are printed as a json array"`
+	Seed     int64  `help:"random seed, the same seed gives the same values, defaults to the time"`
+	MaxLen   int    `help:"maximum length of strings, bytes, vectors and string maps, defaults to 5"`
+	MaxDepth int    `help:"number of nested decls after which values are as small as their types allow, defaults to 4"`
+	Output   string `help:"output file, defaults to stdout" predict:"files"`
+}
+
+func (cm *adlSample) Run() error {
+	allmod, err := loadAst(cm.Ast)
+	if err != nil {
+		return err
+	}
+	te, err := sample.ParseType(allmod, string(cm.Type))
+	if err != nil {
+		return err
+	}
+	g, err := sample.New(allmod, te, sample.Config{MaxLen: cm.MaxLen, MaxDepth: cm.MaxDepth})
+	if err != nil {
+		return err
+	}
+	r := rand.New(rand.NewSource(cm.Seed))
+	var out interface{}
+	if cm.Count == 1 {
+		out = g.Value(r)
+	} else {
+		vs := []interface{}{}
+		for i := 0; i < cm.Count; i++ {
+			vs = append(vs, g.Value(r))
+		}
+		out = vs
+	}
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	return writeOut(cm.Output, buf.Bytes())
+}
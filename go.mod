@@ -12,6 +12,7 @@ require (
 	github.com/golang/protobuf v1.3.1
 	github.com/golangq/q v1.0.7
 	github.com/jpillora/opts v1.0.5
+	github.com/mattn/go-sqlite3 v1.14.0
 	github.com/wxio/goantlr v1.0.3
 	golang.org/x/tools v0.0.0-00010101000000-000000000000
 )
@@ -0,0 +1,1820 @@
+// Package adltest holds ADL fixtures shared by the tests of the adl tools.
+package adltest
+
+import (
+	"encoding/json"
+
+	"github.com/wxio/tron-go/adl"
+)
+
+// Modules returns a fresh copy of the OneOfEachAst modules
+func Modules() map[string]adl.Module {
+	allmod := map[string]adl.Module{}
+	if err := json.Unmarshal([]byte(OneOfEachAst), &allmod); err != nil {
+		panic(err)
+	}
+	return allmod
+}
+
+// OneOfEachAdl is the source of the helix.protoapp.requests module
+const OneOfEachAdl = `module helix.protoapp.requests {
+
+import common.http.*;
+import common.*;
+import common.db.DbTable;
+import sys.types.*;
+
+// comment
+/// doccmt
+@Path "localanno"
+type Hello<A> = Post<HelloReq, HelloResp<Vector<Vector<Vector<A>>>,Int32,Float>>;
+
+type Literal<T> = StringMap<T>;
+type StrLiteral = Literal<String>;
+annotation StrLiteral { "a" : "b" };
+
+struct A {
+  String a;
+};
+struct B {
+  Int32 b;
+};
+union MyConfig {
+  A a;
+  B b;
+};
+type MyConfigMap = StringMap<MyConfig>;
+annotation MyConfig { "a" : { "a" : "mystring" } };
+annotation MyConfig { "b" : { "b" : 10 } };
+annotation MyConfigMap {
+  "a b" : { "b" : { "b" : 10 } },
+  "a a" : { "a" : { "a" : "hw" } }
+};
+
+/// doconstr
+@SA { "a" : "b" }
+struct HelloReq {
+  String name;
+  /// Unique login name
+  String username;
+  Nullable<String> email = null;
+  Int32 age = 0;
+  Instant createdAt;
+  MyConfig config;
+  @SerializedName "labels"
+  Vector<String> tags = [];
+};
+
+struct SA {
+  String a;
+};
+
+struct HelloResp<A,B,C> {
+};
+
+/// docontype
+@Path "/debug/time"
+type CurrentTime = Get<Instant>;
+
+/// doconnewtype
+@Path "/debug/dummy-exception"
+newtype DummyException = Post<String, Unit>;
+
+union LoginResp<T> {
+  @SA { "a" : "b" }
+  T accessToken;
+};
+
+struct LoginReq {
+  String username;
+  String password;
+  Nullable<String> otp = null;
+};
+
+/// The outcome of a login attempt
+union LoginResult {
+  String accessToken;
+  Void invalidCredentials;
+  LoginResp<LocalDate> locked;
+};
+
+/// Authenticate a user
+@Path "/login"
+type Login = Post<LoginReq, LoginResult>;
+
+struct Greeting {
+  String message;
+  Int32 count = 1;
+  StringMap<Double> extras = {};
+};
+
+@Path "/hello"
+type SayHello = Post<HelloReq, Greeting>;
+
+/// An audit log entry
+@DbTable {
+  "tableName" : "audit_log",
+  "indexes" : [["who"], ["when", "who"]],
+  "uniquenessConstraints" : [["who", "when"]]
+}
+struct Audit {
+  String who;
+  Instant when;
+  LocalDate day;
+  Json detail;
+  Nullable<Audit> previous = null;
+  Map<String, Int64> pairs = [];
+};
+
+annotation Path "mod anno str";
+
+annotation HelloReq::name Path "field anno";
+
+annotation HelloReq DbTable {
+  "withIdPrimaryKey" : true,
+  "indexes" : [["username"]]
+};
+
+};
+`
+
+// OneOfEachAst is the combined adlc ast output for OneOfEachAdl and the modules it imports
+const OneOfEachAst = `{
+  "common": {
+    "annotations": [],
+    "decls": {
+      "Instant": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Milliseconds since the epoch\n"
+          }
+        ],
+        "name": "Instant",
+        "type_": {
+          "newtype_": {
+            "default": "nothing",
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "Int64"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "LocalDate": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "A date in ISO 8601 format\n"
+          }
+        ],
+        "name": "LocalDate",
+        "type_": {
+          "newtype_": {
+            "default": {
+              "just": "1970-01-01"
+            },
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "Unit": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "A type with a single value\n"
+          }
+        ],
+        "name": "Unit",
+        "type_": {
+          "struct_": {
+            "fields": [],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [],
+    "name": "common"
+  },
+  "common.db": {
+    "annotations": [],
+    "decls": {
+      "DbColumnName": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Field annotation overriding the column name\n"
+          }
+        ],
+        "name": "DbColumnName",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "DbColumnType": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Field annotation overriding the column type\n"
+          }
+        ],
+        "name": "DbColumnType",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "DbTable": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Annotation to map a struct to a database table\n"
+          }
+        ],
+        "name": "DbTable",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": {
+                  "just": ""
+                },
+                "name": "tableName",
+                "serializedName": "tableName",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": false
+                },
+                "name": "withIdPrimaryKey",
+                "serializedName": "withIdPrimaryKey",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Bool"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": []
+                },
+                "name": "indexes",
+                "serializedName": "indexes",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [
+                        {
+                          "parameters": [],
+                          "typeRef": {
+                            "primitive": "String"
+                          }
+                        }
+                      ],
+                      "typeRef": {
+                        "primitive": "Vector"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Vector"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": []
+                },
+                "name": "uniquenessConstraints",
+                "serializedName": "uniquenessConstraints",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [
+                        {
+                          "parameters": [],
+                          "typeRef": {
+                            "primitive": "String"
+                          }
+                        }
+                      ],
+                      "typeRef": {
+                        "primitive": "Vector"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Vector"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [],
+    "name": "common.db"
+  },
+  "common.http": {
+    "annotations": [],
+    "decls": {
+      "Get": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "A GET request returning a value of type O\n"
+          }
+        ],
+        "name": "Get",
+        "type_": {
+          "struct_": {
+            "fields": [],
+            "typeParams": [
+              "O"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Path": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "The url path of a request\n"
+          }
+        ],
+        "name": "Path",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "Post": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "A POST request with a body of type I returning a value of type O\n"
+          }
+        ],
+        "name": "Post",
+        "type_": {
+          "struct_": {
+            "fields": [],
+            "typeParams": [
+              "I",
+              "O"
+            ]
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [],
+    "name": "common.http"
+  },
+  "helix.protoapp.requests": {
+    "annotations": [
+      {
+        "v1": {
+          "moduleName": "common.http",
+          "name": "Path"
+        },
+        "v2": "mod anno str"
+      },
+      {
+        "v1": {
+          "moduleName": "helix.protoapp.requests",
+          "name": "StrLiteral"
+        },
+        "v2": {
+          "a": "b"
+        }
+      },
+      {
+        "v1": {
+          "moduleName": "helix.protoapp.requests",
+          "name": "MyConfig"
+        },
+        "v2": {
+          "a": {
+            "a": "mystring"
+          }
+        }
+      },
+      {
+        "v1": {
+          "moduleName": "helix.protoapp.requests",
+          "name": "MyConfig"
+        },
+        "v2": {
+          "b": {
+            "b": 10
+          }
+        }
+      },
+      {
+        "v1": {
+          "moduleName": "helix.protoapp.requests",
+          "name": "MyConfigMap"
+        },
+        "v2": {
+          "a a": {
+            "a": {
+              "a": "hw"
+            }
+          },
+          "a b": {
+            "b": {
+              "b": 10
+            }
+          }
+        }
+      }
+    ],
+    "decls": {
+      "A": {
+        "annotations": [],
+        "name": "A",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "a",
+                "serializedName": "a",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "Audit": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.db",
+              "name": "DbTable"
+            },
+            "v2": {
+              "indexes": [
+                [
+                  "who"
+                ],
+                [
+                  "when",
+                  "who"
+                ]
+              ],
+              "tableName": "audit_log",
+              "uniquenessConstraints": [
+                [
+                  "who",
+                  "when"
+                ]
+              ]
+            }
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "An audit log entry\n"
+          }
+        ],
+        "name": "Audit",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "who",
+                "serializedName": "who",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "when",
+                "serializedName": "when",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "common",
+                      "name": "Instant"
+                    }
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "day",
+                "serializedName": "day",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "common",
+                      "name": "LocalDate"
+                    }
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "detail",
+                "serializedName": "detail",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Json"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": null
+                },
+                "name": "previous",
+                "serializedName": "previous",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "reference": {
+                          "moduleName": "helix.protoapp.requests",
+                          "name": "Audit"
+                        }
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Nullable"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": []
+                },
+                "name": "pairs",
+                "serializedName": "pairs",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "String"
+                      }
+                    },
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "Int64"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "sys.types",
+                      "name": "Map"
+                    }
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "B": {
+        "annotations": [],
+        "name": "B",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "b",
+                "serializedName": "b",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Int32"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "CurrentTime": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.http",
+              "name": "Path"
+            },
+            "v2": "/debug/time"
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "docontype\n"
+          }
+        ],
+        "name": "CurrentTime",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "common",
+                      "name": "Instant"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "common.http",
+                  "name": "Get"
+                }
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "DummyException": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.http",
+              "name": "Path"
+            },
+            "v2": "/debug/dummy-exception"
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "doconnewtype\n"
+          }
+        ],
+        "name": "DummyException",
+        "type_": {
+          "newtype_": {
+            "default": "nothing",
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                },
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "common",
+                      "name": "Unit"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "common.http",
+                  "name": "Post"
+                }
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "Greeting": {
+        "annotations": [],
+        "name": "Greeting",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "message",
+                "serializedName": "message",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": 1
+                },
+                "name": "count",
+                "serializedName": "count",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Int32"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": {}
+                },
+                "name": "extras",
+                "serializedName": "extras",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "Double"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "StringMap"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "Hello": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.http",
+              "name": "Path"
+            },
+            "v2": "localanno"
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "doccmt\n"
+          }
+        ],
+        "name": "Hello",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "HelloReq"
+                    }
+                  }
+                },
+                {
+                  "parameters": [
+                    {
+                      "parameters": [
+                        {
+                          "parameters": [
+                            {
+                              "parameters": [
+                                {
+                                  "parameters": [],
+                                  "typeRef": {
+                                    "typeParam": "A"
+                                  }
+                                }
+                              ],
+                              "typeRef": {
+                                "primitive": "Vector"
+                              }
+                            }
+                          ],
+                          "typeRef": {
+                            "primitive": "Vector"
+                          }
+                        }
+                      ],
+                      "typeRef": {
+                        "primitive": "Vector"
+                      }
+                    },
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "Int32"
+                      }
+                    },
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "Float"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "HelloResp"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "common.http",
+                  "name": "Post"
+                }
+              }
+            },
+            "typeParams": [
+              "A"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "HelloReq": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "helix.protoapp.requests",
+              "name": "SA"
+            },
+            "v2": {
+              "a": "b"
+            }
+          },
+          {
+            "v1": {
+              "moduleName": "common.db",
+              "name": "DbTable"
+            },
+            "v2": {
+              "indexes": [
+                [
+                  "username"
+                ]
+              ],
+              "withIdPrimaryKey": true
+            }
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "doconstr\n"
+          }
+        ],
+        "name": "HelloReq",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [
+                  {
+                    "v1": {
+                      "moduleName": "common.http",
+                      "name": "Path"
+                    },
+                    "v2": "field anno"
+                  }
+                ],
+                "default": "nothing",
+                "name": "name",
+                "serializedName": "name",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [
+                  {
+                    "v1": {
+                      "moduleName": "sys.annotations",
+                      "name": "Doc"
+                    },
+                    "v2": "Unique login name\n"
+                  }
+                ],
+                "default": "nothing",
+                "name": "username",
+                "serializedName": "username",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": null
+                },
+                "name": "email",
+                "serializedName": "email",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "String"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Nullable"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": 0
+                },
+                "name": "age",
+                "serializedName": "age",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Int32"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "createdAt",
+                "serializedName": "createdAt",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "common",
+                      "name": "Instant"
+                    }
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "config",
+                "serializedName": "config",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "MyConfig"
+                    }
+                  }
+                }
+              },
+              {
+                "annotations": [
+                  {
+                    "v1": {
+                      "moduleName": "sys.annotations",
+                      "name": "SerializedName"
+                    },
+                    "v2": "labels"
+                  }
+                ],
+                "default": {
+                  "just": []
+                },
+                "name": "tags",
+                "serializedName": "labels",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "String"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Vector"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "HelloResp": {
+        "annotations": [],
+        "name": "HelloResp",
+        "type_": {
+          "struct_": {
+            "fields": [],
+            "typeParams": [
+              "A",
+              "B",
+              "C"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Literal": {
+        "annotations": [],
+        "name": "Literal",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T"
+                  }
+                }
+              ],
+              "typeRef": {
+                "primitive": "StringMap"
+              }
+            },
+            "typeParams": [
+              "T"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Login": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.http",
+              "name": "Path"
+            },
+            "v2": "/login"
+          },
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Authenticate a user\n"
+          }
+        ],
+        "name": "Login",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "LoginReq"
+                    }
+                  }
+                },
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "LoginResult"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "common.http",
+                  "name": "Post"
+                }
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "LoginReq": {
+        "annotations": [],
+        "name": "LoginReq",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "username",
+                "serializedName": "username",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "password",
+                "serializedName": "password",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": {
+                  "just": null
+                },
+                "name": "otp",
+                "serializedName": "otp",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "primitive": "String"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "primitive": "Nullable"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "LoginResp": {
+        "annotations": [],
+        "name": "LoginResp",
+        "type_": {
+          "union_": {
+            "fields": [
+              {
+                "annotations": [
+                  {
+                    "v1": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "SA"
+                    },
+                    "v2": {
+                      "a": "b"
+                    }
+                  }
+                ],
+                "default": "nothing",
+                "name": "accessToken",
+                "serializedName": "accessToken",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T"
+                  }
+                }
+              }
+            ],
+            "typeParams": [
+              "T"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "LoginResult": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "The outcome of a login attempt\n"
+          }
+        ],
+        "name": "LoginResult",
+        "type_": {
+          "union_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "accessToken",
+                "serializedName": "accessToken",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "invalidCredentials",
+                "serializedName": "invalidCredentials",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Void"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "locked",
+                "serializedName": "locked",
+                "typeExpr": {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "reference": {
+                          "moduleName": "common",
+                          "name": "LocalDate"
+                        }
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "LoginResp"
+                    }
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "MyConfig": {
+        "annotations": [],
+        "name": "MyConfig",
+        "type_": {
+          "union_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "a",
+                "serializedName": "a",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "A"
+                    }
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "b",
+                "serializedName": "b",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "B"
+                    }
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "MyConfigMap": {
+        "annotations": [],
+        "name": "MyConfigMap",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "MyConfig"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "primitive": "StringMap"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "SA": {
+        "annotations": [],
+        "name": "SA",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "a",
+                "serializedName": "a",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              }
+            ],
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "SayHello": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "common.http",
+              "name": "Path"
+            },
+            "v2": "/hello"
+          }
+        ],
+        "name": "SayHello",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "HelloReq"
+                    }
+                  }
+                },
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "helix.protoapp.requests",
+                      "name": "Greeting"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "common.http",
+                  "name": "Post"
+                }
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "StrLiteral": {
+        "annotations": [],
+        "name": "StrLiteral",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "String"
+                  }
+                }
+              ],
+              "typeRef": {
+                "reference": {
+                  "moduleName": "helix.protoapp.requests",
+                  "name": "Literal"
+                }
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [
+      {
+        "moduleName": "sys.annotations"
+      },
+      {
+        "moduleName": "common.http"
+      },
+      {
+        "moduleName": "common"
+      },
+      {
+        "scopedName": {
+          "moduleName": "common.db",
+          "name": "DbTable"
+        }
+      },
+      {
+        "moduleName": "sys.types"
+      }
+    ],
+    "name": "helix.protoapp.requests"
+  },
+  "sys.annotations": {
+    "annotations": [],
+    "decls": {
+      "Doc": {
+        "annotations": [
+          {
+            "v1": {
+              "moduleName": "sys.annotations",
+              "name": "Doc"
+            },
+            "v2": "Documentation for a decl or field\n"
+          }
+        ],
+        "name": "Doc",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      },
+      "SerializedName": {
+        "annotations": [],
+        "name": "SerializedName",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [],
+              "typeRef": {
+                "primitive": "String"
+              }
+            },
+            "typeParams": []
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [],
+    "name": "sys.annotations"
+  },
+  "sys.types": {
+    "annotations": [],
+    "decls": {
+      "Either": {
+        "annotations": [],
+        "name": "Either",
+        "type_": {
+          "union_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "left",
+                "serializedName": "left",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T1"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "right",
+                "serializedName": "right",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T2"
+                  }
+                }
+              }
+            ],
+            "typeParams": [
+              "T1",
+              "T2"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Map": {
+        "annotations": [],
+        "name": "Map",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "typeParam": "K"
+                      }
+                    },
+                    {
+                      "parameters": [],
+                      "typeRef": {
+                        "typeParam": "V"
+                      }
+                    }
+                  ],
+                  "typeRef": {
+                    "reference": {
+                      "moduleName": "sys.types",
+                      "name": "Pair"
+                    }
+                  }
+                }
+              ],
+              "typeRef": {
+                "primitive": "Vector"
+              }
+            },
+            "typeParams": [
+              "K",
+              "V"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Maybe": {
+        "annotations": [],
+        "name": "Maybe",
+        "type_": {
+          "union_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "nothing",
+                "serializedName": "nothing",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "primitive": "Void"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "just",
+                "serializedName": "just",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T"
+                  }
+                }
+              }
+            ],
+            "typeParams": [
+              "T"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Pair": {
+        "annotations": [],
+        "name": "Pair",
+        "type_": {
+          "struct_": {
+            "fields": [
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "v1",
+                "serializedName": "v1",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T1"
+                  }
+                }
+              },
+              {
+                "annotations": [],
+                "default": "nothing",
+                "name": "v2",
+                "serializedName": "v2",
+                "typeExpr": {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T2"
+                  }
+                }
+              }
+            ],
+            "typeParams": [
+              "T1",
+              "T2"
+            ]
+          }
+        },
+        "version": "nothing"
+      },
+      "Set": {
+        "annotations": [],
+        "name": "Set",
+        "type_": {
+          "type_": {
+            "typeExpr": {
+              "parameters": [
+                {
+                  "parameters": [],
+                  "typeRef": {
+                    "typeParam": "T"
+                  }
+                }
+              ],
+              "typeRef": {
+                "primitive": "Vector"
+              }
+            },
+            "typeParams": [
+              "T"
+            ]
+          }
+        },
+        "version": "nothing"
+      }
+    },
+    "imports": [],
+    "name": "sys.types"
+  }
+}
+`
@@ -15,6 +15,8 @@ var (
 type root struct{}
 type build struct{}
 type adl struct{}
+type gen struct{}
+type imports struct{}
 
 func main() {
 	r := root{}
@@ -28,7 +30,21 @@ func main() {
 				ConfigPath(".antlr.build.json"))).
 		AddCommand(opts.New(&adl{}).
 			AddCommand(cmd.NewLoadAdlAst()).
-			AddCommand(cmd.BuildAdlAst())).
+			AddCommand(cmd.BuildAdlAst()).
+			AddCommand(cmd.NewAdlDoc()).
+			AddCommand(cmd.NewAdlGraph()).
+			AddCommand(cmd.NewAdlQuery()).
+			AddCommand(cmd.NewAdlLint()).
+			AddCommand(cmd.NewAdlSample()).
+			AddCommand(cmd.NewAdlFmt()).
+			AddCommand(opts.New(&gen{}).
+				AddCommand(cmd.NewGenGo()).
+				AddCommand(cmd.NewGenSql()).
+				AddCommand(cmd.NewGenTs())).
+			AddCommand(opts.New(&imports{}).Name("import").
+				AddCommand(cmd.NewImportGo()).
+				AddCommand(cmd.NewImportJsonSchema()).
+				AddCommand(cmd.NewImportProto()))).
 		Parse().
 		RunFatal()
 }